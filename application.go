@@ -0,0 +1,93 @@
+package shipyard
+
+import (
+	"time"
+
+	"github.com/samalba/dockerclient"
+)
+
+const (
+	// ApplicationLabel is set on the containers of an application to the
+	// application name
+	ApplicationLabel = "com.shipyard.application"
+	// ServiceLabel is set on the containers of an application to the
+	// service the container was created for
+	ServiceLabel = "com.shipyard.service"
+	// GroupLabel is set on the replicas of a scaled container to the name
+	// of the group they belong to
+	GroupLabel = "com.shipyard.group"
+)
+
+type (
+	// Application is a set of containers labeled with the application
+	// name.  Applications deployed from a docker-compose file are stored
+	// with their definition and Services lists the compose services in
+	// dependency order.  Stored applications are kept in the state of their
+	// latest revision unless ReconcilePaused is set.
+	Application struct {
+		ID              string                   `json:"id,omitempty" gorethink:"id,omitempty"`
+		Name            string                   `json:"name,omitempty" gorethink:"name,omitempty"`
+		Definition      string                   `json:"definition,omitempty" gorethink:"definition,omitempty"`
+		Services        []string                 `json:"services,omitempty" gorethink:"services,omitempty"`
+		Username        string                   `json:"username,omitempty" gorethink:"username,omitempty"`
+		Created         time.Time                `json:"created,omitempty" gorethink:"created,omitempty"`
+		Updated         time.Time                `json:"updated,omitempty" gorethink:"updated,omitempty"`
+		ReconcilePaused bool                     `json:"reconcile_paused" gorethink:"reconcile_paused"`
+		Containers      []dockerclient.Container `json:"containers,omitempty" gorethink:"-"`
+		Status          *ApplicationStatus       `json:"status,omitempty" gorethink:"-"`
+	}
+
+	ApplicationStatus struct {
+		Running   int `json:"running"`
+		Total     int `json:"total"`
+		Unhealthy int `json:"unhealthy"`
+	}
+
+	// ApplicationLog is a log line of a container of an application
+	ApplicationLog struct {
+		Container string    `json:"container,omitempty"`
+		Service   string    `json:"service,omitempty"`
+		Time      time.Time `json:"time,omitempty"`
+		Stream    string    `json:"stream,omitempty"`
+		Message   string    `json:"message"`
+	}
+
+	// ApplicationDrift compares the containers of an application with its
+	// latest revision.  Missing containers are recreated and extra
+	// containers removed.  Containers on unreachable nodes are left alone
+	// until the node is back or removed from the cluster.
+	ApplicationDrift struct {
+		Application string    `json:"application,omitempty"`
+		Revision    int       `json:"revision"`
+		Missing     []string  `json:"missing"`
+		Extra       []string  `json:"extra"`
+		Unreachable []string  `json:"unreachable"`
+		Created     []string  `json:"created"`
+		Removed     []string  `json:"removed"`
+		Errors      []string  `json:"errors"`
+		Time        time.Time `json:"time,omitempty"`
+	}
+
+	ContainerStats struct {
+		Container   string  `json:"container,omitempty"`
+		Service     string  `json:"service,omitempty"`
+		CPUPercent  float64 `json:"cpu_percent"`
+		MemoryUsage uint64  `json:"memory_usage"`
+		MemoryLimit uint64  `json:"memory_limit"`
+		NetworkRx   uint64  `json:"network_rx"`
+		NetworkTx   uint64  `json:"network_tx"`
+	}
+
+	// ApplicationStats is the combined resource usage of the running
+	// containers of an application
+	ApplicationStats struct {
+		Application string            `json:"application,omitempty"`
+		CPUPercent  float64           `json:"cpu_percent"`
+		MemoryUsage uint64            `json:"memory_usage"`
+		MemoryLimit uint64            `json:"memory_limit"`
+		NetworkRx   uint64            `json:"network_rx"`
+		NetworkTx   uint64            `json:"network_tx"`
+		Containers  []*ContainerStats `json:"containers"`
+		Errors      []string          `json:"errors"`
+	}
+)
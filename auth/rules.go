@@ -27,6 +27,30 @@ func DefaultACLs() []*ACL {
 	}
 	acls = append(acls, adminACL)
 
+	applicationsACLRO := &ACL{
+		RoleName:    "applications:ro",
+		Description: "Applications Read Only",
+		Rules: []*AccessRule{
+			{
+				Path:    "/api/applications",
+				Methods: []string{"GET"},
+			},
+		},
+	}
+	acls = append(acls, applicationsACLRO)
+
+	applicationsACLRW := &ACL{
+		RoleName:    "applications:rw",
+		Description: "Applications",
+		Rules: []*AccessRule{
+			{
+				Path:    "/api/applications",
+				Methods: []string{"GET", "POST", "DELETE"},
+			},
+		},
+	}
+	acls = append(acls, applicationsACLRW)
+
 	containersACLRO := &ACL{
 		RoleName:    "containers:ro",
 		Description: "Containers Read Only",
@@ -35,6 +59,14 @@ func DefaultACLs() []*ACL {
 				Path:    "/containers",
 				Methods: []string{"GET"},
 			},
+			{
+				Path:    "/api/groups",
+				Methods: []string{"GET"},
+			},
+			{
+				Path:    "/api/containers",
+				Methods: []string{"GET"},
+			},
 		},
 	}
 	acls = append(acls, containersACLRO)
@@ -47,6 +79,14 @@ func DefaultACLs() []*ACL {
 				Path:    "/containers",
 				Methods: []string{"GET", "POST", "DELETE"},
 			},
+			{
+				Path:    "/api/groups",
+				Methods: []string{"GET", "POST"},
+			},
+			{
+				Path:    "/api/containers",
+				Methods: []string{"GET"},
+			},
 		},
 	}
 	acls = append(acls, containersACLRW)
@@ -83,6 +123,10 @@ func DefaultACLs() []*ACL {
 				Path:    "/images",
 				Methods: []string{"GET"},
 			},
+			{
+				Path:    "/api/images/search",
+				Methods: []string{"GET"},
+			},
 		},
 	}
 	acls = append(acls, imagesACLRO)
@@ -95,10 +139,46 @@ func DefaultACLs() []*ACL {
 				Path:    "/images",
 				Methods: []string{"GET", "POST", "DELETE"},
 			},
+			{
+				Path:    "/api/images/search",
+				Methods: []string{"GET"},
+			},
 		},
 	}
 	acls = append(acls, imagesACLRW)
 
+	networksACLRO := &ACL{
+		RoleName:    "networks:ro",
+		Description: "Networks Read Only",
+		Rules: []*AccessRule{
+			{
+				Path:    "/networks",
+				Methods: []string{"GET"},
+			},
+			{
+				Path:    "/api/networks",
+				Methods: []string{"GET"},
+			},
+		},
+	}
+	acls = append(acls, networksACLRO)
+
+	networksACLRW := &ACL{
+		RoleName:    "networks:rw",
+		Description: "Networks",
+		Rules: []*AccessRule{
+			{
+				Path:    "/networks",
+				Methods: []string{"GET", "POST", "DELETE"},
+			},
+			{
+				Path:    "/api/networks",
+				Methods: []string{"GET", "POST", "DELETE"},
+			},
+		},
+	}
+	acls = append(acls, networksACLRW)
+
 	nodesACLRO := &ACL{
 		RoleName:    "nodes:ro",
 		Description: "Nodes Read Only",
@@ -107,6 +187,14 @@ func DefaultACLs() []*ACL {
 				Path:    "/api/nodes",
 				Methods: []string{"GET"},
 			},
+			{
+				Path:    "/nodes",
+				Methods: []string{"GET"},
+			},
+			{
+				Path:    "/api/cluster",
+				Methods: []string{"GET"},
+			},
 		},
 	}
 	acls = append(acls, nodesACLRO)
@@ -119,6 +207,14 @@ func DefaultACLs() []*ACL {
 				Path:    "/api/nodes",
 				Methods: []string{"GET", "POST", "DELETE"},
 			},
+			{
+				Path:    "/nodes",
+				Methods: []string{"GET", "POST", "DELETE"},
+			},
+			{
+				Path:    "/api/cluster",
+				Methods: []string{"GET"},
+			},
 		},
 	}
 	acls = append(acls, nodesACLRW)
@@ -131,6 +227,14 @@ func DefaultACLs() []*ACL {
 				Path:    "/api/registry",
 				Methods: []string{"GET"},
 			},
+			{
+				Path:    "/api/retentionpolicies",
+				Methods: []string{"GET"},
+			},
+			{
+				Path:    "/api/images/search",
+				Methods: []string{"GET"},
+			},
 		},
 	}
 	acls = append(acls, registriesACLRO)
@@ -143,9 +247,165 @@ func DefaultACLs() []*ACL {
 				Path:    "/api/registry",
 				Methods: []string{"GET", "POST", "DELETE"},
 			},
+			{
+				Path:    "/api/retentionpolicies",
+				Methods: []string{"GET", "POST", "PUT", "DELETE"},
+			},
+			{
+				Path:    "/api/images/search",
+				Methods: []string{"GET"},
+			},
 		},
 	}
 	acls = append(acls, registriesACLRW)
 
+	registriesACLPromote := &ACL{
+		RoleName:    "registries:promote",
+		Description: "Registries Promote Images",
+		Rules: []*AccessRule{
+			{
+				Path:    "/api/promotions",
+				Methods: []string{"POST"},
+			},
+		},
+	}
+	acls = append(acls, registriesACLPromote)
+
+	secretsACLRO := &ACL{
+		RoleName:    "secrets:ro",
+		Description: "Secrets Read Only",
+		Rules: []*AccessRule{
+			{
+				Path:    "/secrets",
+				Methods: []string{"GET"},
+			},
+			{
+				Path:    "/api/secrets",
+				Methods: []string{"GET"},
+			},
+		},
+	}
+	acls = append(acls, secretsACLRO)
+
+	secretsACLRW := &ACL{
+		RoleName:    "secrets:rw",
+		Description: "Secrets",
+		Rules: []*AccessRule{
+			{
+				Path:    "/secrets",
+				Methods: []string{"GET", "POST", "DELETE"},
+			},
+			{
+				Path:    "/api/secrets",
+				Methods: []string{"GET", "POST", "DELETE"},
+			},
+		},
+	}
+	acls = append(acls, secretsACLRW)
+
+	servicesACLRO := &ACL{
+		RoleName:    "services:ro",
+		Description: "Services Read Only",
+		Rules: []*AccessRule{
+			{
+				Path:    "/services",
+				Methods: []string{"GET"},
+			},
+			{
+				Path:    "/api/services",
+				Methods: []string{"GET"},
+			},
+		},
+	}
+	acls = append(acls, servicesACLRO)
+
+	servicesACLRW := &ACL{
+		RoleName:    "services:rw",
+		Description: "Services",
+		Rules: []*AccessRule{
+			{
+				Path:    "/services",
+				Methods: []string{"GET", "POST", "DELETE"},
+			},
+			{
+				Path:    "/api/services",
+				Methods: []string{"GET", "POST", "DELETE"},
+			},
+		},
+	}
+	acls = append(acls, servicesACLRW)
+
+	tasksACLRO := &ACL{
+		RoleName:    "tasks:ro",
+		Description: "Tasks Read Only",
+		Rules: []*AccessRule{
+			{
+				Path:    "/tasks",
+				Methods: []string{"GET"},
+			},
+			{
+				Path:    "/api/tasks",
+				Methods: []string{"GET"},
+			},
+		},
+	}
+	acls = append(acls, tasksACLRO)
+
+	templatesACLRO := &ACL{
+		RoleName:    "templates:ro",
+		Description: "Templates Read Only",
+		Rules: []*AccessRule{
+			{
+				Path:    "/api/templates",
+				Methods: []string{"GET"},
+			},
+		},
+	}
+	acls = append(acls, templatesACLRO)
+
+	templatesACLRW := &ACL{
+		RoleName:    "templates:rw",
+		Description: "Templates",
+		Rules: []*AccessRule{
+			{
+				Path:    "/api/templates",
+				Methods: []string{"GET", "POST", "DELETE"},
+			},
+		},
+	}
+	acls = append(acls, templatesACLRW)
+
+	volumesACLRO := &ACL{
+		RoleName:    "volumes:ro",
+		Description: "Volumes Read Only",
+		Rules: []*AccessRule{
+			{
+				Path:    "/volumes",
+				Methods: []string{"GET"},
+			},
+			{
+				Path:    "/api/volumes",
+				Methods: []string{"GET"},
+			},
+		},
+	}
+	acls = append(acls, volumesACLRO)
+
+	volumesACLRW := &ACL{
+		RoleName:    "volumes:rw",
+		Description: "Volumes",
+		Rules: []*AccessRule{
+			{
+				Path:    "/volumes",
+				Methods: []string{"GET", "POST", "DELETE"},
+			},
+			{
+				Path:    "/api/volumes",
+				Methods: []string{"GET", "POST", "DELETE"},
+			},
+		},
+	}
+	acls = append(acls, volumesACLRW)
+
 	return acls
 }
@@ -0,0 +1,268 @@
+package compose
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/samalba/dockerclient"
+)
+
+// ContainerName returns the name of the container created for the service
+func (p *Project) ContainerName(project, service string) string {
+	if svc, ok := p.Services[service]; ok && svc.ContainerName != "" {
+		return svc.ContainerName
+	}
+
+	return fmt.Sprintf("%s_%s_1", project, service)
+}
+
+// VolumeName returns the name of a named volume of the project.  Volumes
+// declared in the compose file are prefixed with the project name unless
+// they are external.
+func (p *Project) VolumeName(project, volume string) string {
+	v, ok := p.Volumes[volume]
+	if !ok {
+		return volume
+	}
+
+	switch ext := v.External.(type) {
+	case bool:
+		if ext {
+			return volume
+		}
+	case map[interface{}]interface{}:
+		if name, ok := ext["name"].(string); ok && name != "" {
+			return name
+		}
+		return volume
+	}
+
+	return project + "_" + volume
+}
+
+// ContainerConfig returns the configuration used to create the container of
+// the service.  References to other services are resolved to the names of
+// their containers in the project.
+func (p *Project) ContainerConfig(project, service string) (*dockerclient.ContainerConfig, error) {
+	svc, ok := p.Services[service]
+	if !ok {
+		return nil, fmt.Errorf("unknown service: %s", service)
+	}
+
+	config := &dockerclient.ContainerConfig{
+		Image:        svc.Image,
+		Cmd:          []string(svc.Command),
+		Entrypoint:   []string(svc.Entrypoint),
+		Env:          svc.Environment.List("="),
+		Hostname:     svc.Hostname,
+		Domainname:   svc.DomainName,
+		User:         svc.User,
+		WorkingDir:   svc.WorkingDir,
+		Tty:          svc.Tty,
+		OpenStdin:    svc.StdinOpen,
+		StopSignal:   svc.StopSignal,
+		Labels:       map[string]string{},
+		ExposedPorts: map[string]struct{}{},
+		Volumes:      map[string]struct{}{},
+	}
+
+	for k, v := range svc.Labels {
+		config.Labels[k] = v
+	}
+
+	hostConfig := dockerclient.HostConfig{
+		Privileged:     svc.Privileged,
+		ReadonlyRootfs: svc.ReadOnly,
+		Memory:         int64(svc.MemLimit),
+		CpuShares:      svc.CPUShares,
+		CpusetCpus:     svc.CPUSet,
+		Dns:            []string(svc.DNS),
+		DnsSearch:      []string(svc.DNSSearch),
+		ExtraHosts:     MappingList(svc.ExtraHosts).List(":"),
+		CapAdd:         []string(svc.CapAdd),
+		CapDrop:        []string(svc.CapDrop),
+		PortBindings:   map[string][]dockerclient.PortBinding{},
+		Links:          []string{},
+		Binds:          []string{},
+		VolumesFrom:    []string{},
+	}
+
+	for _, port := range svc.Expose {
+		config.ExposedPorts[portKey(port)] = struct{}{}
+	}
+
+	for _, port := range svc.Ports {
+		key, binding, err := ParsePort(port)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %s", service, err)
+		}
+
+		config.ExposedPorts[key] = struct{}{}
+		hostConfig.PortBindings[key] = append(hostConfig.PortBindings[key], binding)
+	}
+
+	for _, volume := range svc.Volumes {
+		bind, err := p.parseVolume(project, volume)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %s", service, err)
+		}
+
+		if bind == "" {
+			config.Volumes[volume] = struct{}{}
+			continue
+		}
+
+		hostConfig.Binds = append(hostConfig.Binds, bind)
+	}
+
+	for _, from := range svc.VolumesFrom {
+		hostConfig.VolumesFrom = append(hostConfig.VolumesFrom, p.volumesFrom(project, from))
+	}
+
+	for _, link := range svc.Links {
+		parts := strings.SplitN(link, ":", 2)
+		alias := parts[0]
+		if len(parts) == 2 {
+			alias = parts[1]
+		}
+
+		hostConfig.Links = append(hostConfig.Links, p.ContainerName(project, parts[0])+":"+alias)
+	}
+
+	hostConfig.Links = append(hostConfig.Links, svc.ExternalLinks...)
+
+	networkMode := svc.NetworkMode
+	if networkMode == "" {
+		networkMode = svc.Net
+	}
+	if strings.HasPrefix(networkMode, "service:") {
+		networkMode = "container:" + p.ContainerName(project, strings.TrimPrefix(networkMode, "service:"))
+	}
+	hostConfig.NetworkMode = networkMode
+
+	restart, err := parseRestartPolicy(svc.Restart)
+	if err != nil {
+		return nil, fmt.Errorf("service %s: %s", service, err)
+	}
+	hostConfig.RestartPolicy = restart
+
+	config.HostConfig = hostConfig
+
+	return config, nil
+}
+
+// portKey returns the port with the protocol (i.e. "80/tcp")
+func portKey(port string) string {
+	if !strings.Contains(port, "/") {
+		return port + "/tcp"
+	}
+
+	return port
+}
+
+// ParsePort parses a port mapping of the form
+// [[ip:]host_port:]container_port[/protocol]
+func ParsePort(port string) (string, dockerclient.PortBinding, error) {
+	binding := dockerclient.PortBinding{}
+	if strings.Contains(port, "-") {
+		return "", binding, fmt.Errorf("port ranges are not supported: %s", port)
+	}
+
+	proto := "tcp"
+	if i := strings.Index(port, "/"); i != -1 {
+		proto = port[i+1:]
+		port = port[:i]
+	}
+
+	parts := strings.Split(port, ":")
+	var containerPort string
+	switch len(parts) {
+	case 1:
+		containerPort = parts[0]
+	case 2:
+		binding.HostPort = parts[0]
+		containerPort = parts[1]
+	case 3:
+		binding.HostIp = parts[0]
+		binding.HostPort = parts[1]
+		containerPort = parts[2]
+	default:
+		return "", binding, fmt.Errorf("invalid port: %s", port)
+	}
+
+	for _, p := range []string{containerPort, binding.HostPort} {
+		if p == "" {
+			continue
+		}
+		if _, err := strconv.ParseUint(p, 10, 16); err != nil {
+			return "", binding, fmt.Errorf("invalid port: %s", port)
+		}
+	}
+
+	if containerPort == "" {
+		return "", binding, fmt.Errorf("invalid port: %s", port)
+	}
+
+	return containerPort + "/" + proto, binding, nil
+}
+
+// parseVolume returns the bind for a volume mapping or an empty string for
+// an anonymous volume
+func (p *Project) parseVolume(project, volume string) (string, error) {
+	parts := strings.SplitN(volume, ":", 2)
+	if len(parts) == 1 {
+		return "", nil
+	}
+
+	source, target := parts[0], parts[1]
+	switch {
+	case strings.HasPrefix(source, "/"):
+	case strings.HasPrefix(source, "."), strings.HasPrefix(source, "~"):
+		return "", fmt.Errorf("relative host paths are not supported: %s", volume)
+	default:
+		source = p.VolumeName(project, source)
+	}
+
+	return source + ":" + target, nil
+}
+
+func (p *Project) volumesFrom(project, from string) string {
+	if strings.HasPrefix(from, "container:") {
+		return strings.TrimPrefix(from, "container:")
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(from, "service:"), ":", 2)
+	name := p.ContainerName(project, parts[0])
+	if len(parts) == 2 {
+		name += ":" + parts[1]
+	}
+
+	return name
+}
+
+func parseRestartPolicy(restart string) (dockerclient.RestartPolicy, error) {
+	policy := dockerclient.RestartPolicy{}
+	if restart == "" || restart == "no" {
+		return policy, nil
+	}
+
+	parts := strings.SplitN(restart, ":", 2)
+	switch parts[0] {
+	case "always", "unless-stopped":
+		policy.Name = parts[0]
+	case "on-failure":
+		policy.Name = parts[0]
+		if len(parts) == 2 {
+			n, err := strconv.ParseInt(parts[1], 10, 64)
+			if err != nil {
+				return policy, fmt.Errorf("invalid restart policy: %s", restart)
+			}
+			policy.MaximumRetryCount = n
+		}
+	default:
+		return policy, fmt.Errorf("invalid restart policy: %s", restart)
+	}
+
+	return policy, nil
+}
@@ -0,0 +1,92 @@
+package compose
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContainerConfigV1(t *testing.T) {
+	p, err := Load([]byte(testComposeV1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := p.ContainerConfig("blog", "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(config.HostConfig.Links, []string{"blog_db_1:database"}) {
+		t.Fatalf("unexpected links: %v", config.HostConfig.Links)
+	}
+
+	if !reflect.DeepEqual(config.HostConfig.VolumesFrom, []string{"blog_data_1"}) {
+		t.Fatalf("unexpected volumes from: %v", config.HostConfig.VolumesFrom)
+	}
+
+	if !reflect.DeepEqual(config.Env, []string{"RAILS_ENV=production"}) {
+		t.Fatalf("unexpected environment: %v", config.Env)
+	}
+
+	web, err := p.ContainerConfig("blog", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bindings := web.HostConfig.PortBindings["80/tcp"]
+	if len(bindings) != 2 || bindings[0].HostPort != "" || bindings[1].HostPort != "8080" {
+		t.Fatalf("unexpected port bindings: %v", bindings)
+	}
+
+	if _, ok := web.ExposedPorts["80/tcp"]; !ok {
+		t.Fatal("expected port 80 to be exposed")
+	}
+
+	data, err := p.ContainerConfig("blog", "data")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := data.Volumes["/var/lib/postgresql"]; !ok {
+		t.Fatalf("expected anonymous volume; received %v", data.Volumes)
+	}
+}
+
+func TestContainerConfigV2(t *testing.T) {
+	p, err := Load([]byte(testComposeV2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app, err := p.ContainerConfig("blog", "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"blog_data:/data", "/etc/app:/etc/app:ro", "shared:/shared"}
+	if !reflect.DeepEqual(app.HostConfig.Binds, expected) {
+		t.Fatalf("expected binds %v; received %v", expected, app.HostConfig.Binds)
+	}
+
+	if app.HostConfig.Memory != 512*1024*1024 {
+		t.Fatalf("expected memory limit; received %d", app.HostConfig.Memory)
+	}
+
+	if app.Labels["tier"] != "backend" {
+		t.Fatalf("expected tier label; received %v", app.Labels)
+	}
+
+	web, err := p.ContainerConfig("blog", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bindings := web.HostConfig.PortBindings["80/tcp"]
+	if len(bindings) != 1 || bindings[0].HostIp != "127.0.0.1" || bindings[0].HostPort != "8080" {
+		t.Fatalf("unexpected port bindings: %v", bindings)
+	}
+
+	if web.HostConfig.RestartPolicy.Name != "on-failure" || web.HostConfig.RestartPolicy.MaximumRetryCount != 3 {
+		t.Fatalf("unexpected restart policy: %v", web.HostConfig.RestartPolicy)
+	}
+}
@@ -0,0 +1,273 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	ErrNoServices = errors.New("compose file does not define any services")
+)
+
+type (
+	// Project is a parsed docker-compose file (version 1 or 2)
+	Project struct {
+		Version  string
+		Services map[string]*Service
+		Volumes  map[string]*Volume
+	}
+
+	// Volume is a named volume declared in a version 2 compose file
+	Volume struct {
+		Driver     string            `yaml:"driver"`
+		DriverOpts map[string]string `yaml:"driver_opts"`
+		External   interface{}       `yaml:"external"`
+	}
+
+	Service struct {
+		Name          string      `yaml:"-"`
+		Image         string      `yaml:"image"`
+		Command       Command     `yaml:"command"`
+		Entrypoint    Command     `yaml:"entrypoint"`
+		Environment   MappingList `yaml:"environment"`
+		Labels        MappingList `yaml:"labels"`
+		Ports         StringList  `yaml:"ports"`
+		Expose        StringList  `yaml:"expose"`
+		Volumes       StringList  `yaml:"volumes"`
+		VolumesFrom   StringList  `yaml:"volumes_from"`
+		Links         StringList  `yaml:"links"`
+		DependsOn     StringList  `yaml:"depends_on"`
+		ExternalLinks StringList  `yaml:"external_links"`
+		ContainerName string      `yaml:"container_name"`
+		Hostname      string      `yaml:"hostname"`
+		DomainName    string      `yaml:"domainname"`
+		User          string      `yaml:"user"`
+		WorkingDir    string      `yaml:"working_dir"`
+		Restart       string      `yaml:"restart"`
+		NetworkMode   string      `yaml:"network_mode"`
+		Net           string      `yaml:"net"`
+		Privileged    bool        `yaml:"privileged"`
+		ReadOnly      bool        `yaml:"read_only"`
+		Tty           bool        `yaml:"tty"`
+		StdinOpen     bool        `yaml:"stdin_open"`
+		MemLimit      MemorySize  `yaml:"mem_limit"`
+		CPUShares     int64       `yaml:"cpu_shares"`
+		CPUSet        string      `yaml:"cpuset"`
+		DNS           StringList  `yaml:"dns"`
+		DNSSearch     StringList  `yaml:"dns_search"`
+		ExtraHosts    HostList    `yaml:"extra_hosts"`
+		CapAdd        StringList  `yaml:"cap_add"`
+		CapDrop       StringList  `yaml:"cap_drop"`
+		StopSignal    string      `yaml:"stop_signal"`
+
+		// not supported by the controller
+		Build    interface{} `yaml:"build"`
+		EnvFile  interface{} `yaml:"env_file"`
+		Extends  interface{} `yaml:"extends"`
+		Networks interface{} `yaml:"networks"`
+	}
+)
+
+// Load parses a version 1 or version 2 compose file
+func Load(data []byte) (*Project, error) {
+	var header struct {
+		Version interface{} `yaml:"version"`
+	}
+
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	project := &Project{
+		Version:  "1",
+		Volumes:  map[string]*Volume{},
+		Services: map[string]*Service{},
+	}
+
+	if header.Version == nil {
+		if err := yaml.Unmarshal(data, &project.Services); err != nil {
+			return nil, err
+		}
+	} else {
+		version, err := stringValue(header.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		if version != "2" && !strings.HasPrefix(version, "2.") {
+			return nil, fmt.Errorf("unsupported compose file version: %s", version)
+		}
+
+		var v2 struct {
+			Services map[string]*Service    `yaml:"services"`
+			Volumes  map[string]*Volume     `yaml:"volumes"`
+			Networks map[string]interface{} `yaml:"networks"`
+		}
+
+		if err := yaml.Unmarshal(data, &v2); err != nil {
+			return nil, err
+		}
+
+		if len(v2.Networks) > 0 {
+			return nil, errors.New("networks are not supported; use links instead")
+		}
+
+		project.Version = version
+		project.Services = v2.Services
+		for name, v := range v2.Volumes {
+			if v == nil {
+				v = &Volume{}
+			}
+			project.Volumes[name] = v
+		}
+	}
+
+	if len(project.Services) == 0 {
+		return nil, ErrNoServices
+	}
+
+	for name, svc := range project.Services {
+		if svc == nil {
+			return nil, fmt.Errorf("service %s is empty", name)
+		}
+		svc.Name = name
+	}
+
+	if err := project.validate(); err != nil {
+		return nil, err
+	}
+
+	if _, err := project.Order(); err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (p *Project) validate() error {
+	for _, name := range p.ServiceNames() {
+		svc := p.Services[name]
+
+		if svc.Build != nil {
+			return fmt.Errorf("service %s: build is not supported; push the image to a registry", name)
+		}
+
+		if svc.Image == "" {
+			return fmt.Errorf("service %s: image is required", name)
+		}
+
+		for key, v := range map[string]interface{}{"env_file": svc.EnvFile, "extends": svc.Extends, "networks": svc.Networks} {
+			if v != nil {
+				return fmt.Errorf("service %s: %s is not supported", name, key)
+			}
+		}
+
+		for _, dep := range svc.Dependencies() {
+			if _, ok := p.Services[dep]; !ok {
+				return fmt.Errorf("service %s: depends on undefined service %s", name, dep)
+			}
+		}
+
+		// ports, volumes and restart policies are checked by the conversion
+		if _, err := p.ContainerConfig("project", name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ServiceNames returns the names of all services sorted by name
+func (p *Project) ServiceNames() []string {
+	names := []string{}
+	for name := range p.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Dependencies returns the services which must be created before the
+// service because it links to them, uses their volumes or shares their
+// network stack
+func (s *Service) Dependencies() []string {
+	deps := []string{}
+	seen := map[string]bool{}
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			deps = append(deps, name)
+		}
+	}
+
+	for _, link := range s.Links {
+		add(strings.SplitN(link, ":", 2)[0])
+	}
+
+	for _, dep := range s.DependsOn {
+		add(dep)
+	}
+
+	for _, from := range s.VolumesFrom {
+		// version 2 uses "container:name" to refer to other containers
+		if strings.HasPrefix(from, "container:") {
+			continue
+		}
+		add(strings.SplitN(strings.TrimPrefix(from, "service:"), ":", 2)[0])
+	}
+
+	for _, mode := range []string{s.NetworkMode, s.Net} {
+		if strings.HasPrefix(mode, "service:") {
+			add(strings.TrimPrefix(mode, "service:"))
+		}
+	}
+
+	sort.Strings(deps)
+
+	return deps
+}
+
+// Order returns the service names sorted so that every service comes after
+// its dependencies.  Independent services are sorted by name.
+func (p *Project) Order() ([]string, error) {
+	var (
+		order   = []string{}
+		done    = map[string]bool{}
+		visited = map[string]bool{}
+		visit   func(name string, path []string) error
+	)
+
+	visit = func(name string, path []string) error {
+		if done[name] {
+			return nil
+		}
+
+		if visited[name] {
+			return fmt.Errorf("circular dependency: %s", strings.Join(append(path, name), " -> "))
+		}
+		visited[name] = true
+
+		for _, dep := range p.Services[name].Dependencies() {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+
+		done[name] = true
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range p.ServiceNames() {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
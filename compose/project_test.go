@@ -0,0 +1,154 @@
+package compose
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testComposeV1 = `
+web:
+  image: nginx:latest
+  links:
+    - app
+  ports:
+    - 80
+    - "8080:80"
+app:
+  image: example/app:1.0
+  command: bundle exec "rails server"
+  environment:
+    - RAILS_ENV=production
+  links:
+    - db:database
+  volumes_from:
+    - data
+db:
+  image: postgres
+data:
+  image: busybox
+  volumes:
+    - /var/lib/postgresql
+`
+
+const testComposeV2 = `
+version: "2"
+services:
+  web:
+    image: nginx
+    depends_on:
+      - app
+    ports:
+      - "127.0.0.1:8080:80/tcp"
+    restart: on-failure:3
+  app:
+    image: example/app
+    environment:
+      RAILS_ENV: production
+      WORKERS: 4
+    volumes:
+      - data:/data
+      - /etc/app:/etc/app:ro
+      - shared:/shared
+    mem_limit: 512m
+    labels:
+      tier: backend
+volumes:
+  data: {}
+  shared:
+    external: true
+`
+
+func TestLoadV1(t *testing.T) {
+	p, err := Load([]byte(testComposeV1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Version != "1" {
+		t.Fatalf("expected version 1; received %s", p.Version)
+	}
+
+	if len(p.Services) != 4 {
+		t.Fatalf("expected 4 services; received %d", len(p.Services))
+	}
+
+	cmd := []string(p.Services["app"].Command)
+	if !reflect.DeepEqual(cmd, []string{"bundle", "exec", "rails server"}) {
+		t.Fatalf("unexpected command: %v", cmd)
+	}
+
+	order, err := p.Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"data", "db", "app", "web"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Fatalf("expected order %v; received %v", expected, order)
+	}
+}
+
+func TestLoadV2(t *testing.T) {
+	p, err := Load([]byte(testComposeV2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Version != "2" {
+		t.Fatalf("expected version 2; received %s", p.Version)
+	}
+
+	if p.Services["app"].Environment["WORKERS"] != "4" {
+		t.Fatalf("expected numeric environment value; received %q", p.Services["app"].Environment["WORKERS"])
+	}
+
+	if p.Services["app"].MemLimit != 512*1024*1024 {
+		t.Fatalf("expected 512m memory limit; received %d", p.Services["app"].MemLimit)
+	}
+
+	order, err := p.Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(order, []string{"app", "web"}) {
+		t.Fatalf("unexpected order: %v", order)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := map[string]string{
+		"unsupported compose file version": "version: \"3\"\nservices:\n  web:\n    image: nginx\n",
+		"build is not supported":           "web:\n  build: .\n",
+		"image is required":                "web:\n  command: true\n",
+		"undefined service":                "web:\n  image: nginx\n  links:\n    - db\n",
+		"circular dependency":              "a:\n  image: a\n  links:\n    - b\nb:\n  image: b\n  links:\n    - a\n",
+		"relative host paths":              "web:\n  image: nginx\n  volumes:\n    - ./html:/usr/share/nginx/html\n",
+		"port ranges":                      "web:\n  image: nginx\n  ports:\n    - \"8000-8010:80\"\n",
+		"does not define any services":     "version: \"2\"\n",
+	}
+
+	for expected, data := range tests {
+		_, err := Load([]byte(data))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error containing %q; received %v", expected, err)
+		}
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	args, err := splitCommand(`sh -c 'echo "hello world"' a\ b`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"sh", "-c", `echo "hello world"`, "a b"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("expected %v; received %v", expected, args)
+	}
+
+	if _, err := splitCommand(`echo "unterminated`); err == nil {
+		t.Fatal("expected error for unterminated quote")
+	}
+}
@@ -0,0 +1,279 @@
+package compose
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// stringValue converts a yaml scalar to a string.  Ports and other values
+// are often written without quotes and decoded as numbers.
+func stringValue(v interface{}) (string, error) {
+	switch s := v.(type) {
+	case string:
+		return s, nil
+	case int:
+		return strconv.Itoa(s), nil
+	case int64:
+		return strconv.FormatInt(s, 10), nil
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(s), nil
+	case nil:
+		return "", nil
+	}
+
+	return "", fmt.Errorf("expected a string; received %v", v)
+}
+
+// StringList is a list of strings which may be written as a single string
+type StringList []string
+
+func (l *StringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+
+	switch items := v.(type) {
+	case []interface{}:
+		list := StringList{}
+		for _, item := range items {
+			s, err := stringValue(item)
+			if err != nil {
+				return err
+			}
+
+			list = append(list, s)
+		}
+		*l = list
+	default:
+		s, err := stringValue(items)
+		if err != nil {
+			return err
+		}
+		*l = StringList{s}
+	}
+
+	return nil
+}
+
+// Command is a command written either as a list or as a shell string
+type Command []string
+
+func (c *Command) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+
+	if s, ok := v.(string); ok {
+		args, err := splitCommand(s)
+		if err != nil {
+			return err
+		}
+
+		*c = Command(args)
+		return nil
+	}
+
+	var list StringList
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+
+	*c = Command(list)
+	return nil
+}
+
+// splitCommand splits a command string into arguments honouring single and
+// double quotes and backslash escapes
+func splitCommand(s string) ([]string, error) {
+	var (
+		args    = []string{}
+		current = []rune{}
+		inArg   = false
+		quote   rune
+		escaped = false
+	)
+
+	for _, c := range s {
+		switch {
+		case escaped:
+			current = append(current, c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				current = append(current, c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inArg = true
+		case unicode.IsSpace(c):
+			if inArg {
+				args = append(args, string(current))
+				current = []rune{}
+				inArg = false
+			}
+		default:
+			current = append(current, c)
+			inArg = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote in command: %s", s)
+	}
+
+	if inArg {
+		args = append(args, string(current))
+	}
+
+	return args, nil
+}
+
+// MappingList is a set of key value pairs written either as a map or as a
+// list of "key=value" strings
+type MappingList map[string]string
+
+func (m *MappingList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return m.unmarshal(unmarshal, "=")
+}
+
+func (m *MappingList) unmarshal(unmarshal func(interface{}) error, sep string) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+
+	mapping := MappingList{}
+	switch items := v.(type) {
+	case map[interface{}]interface{}:
+		for k, val := range items {
+			key, err := stringValue(k)
+			if err != nil {
+				return err
+			}
+
+			s, err := stringValue(val)
+			if err != nil {
+				return err
+			}
+
+			mapping[key] = s
+		}
+	case []interface{}:
+		for _, item := range items {
+			s, err := stringValue(item)
+			if err != nil {
+				return err
+			}
+
+			parts := strings.SplitN(s, sep, 2)
+			if len(parts) == 1 {
+				mapping[parts[0]] = ""
+			} else {
+				mapping[parts[0]] = parts[1]
+			}
+		}
+	case nil:
+	default:
+		return fmt.Errorf("expected a map or a list; received %v", v)
+	}
+
+	*m = mapping
+	return nil
+}
+
+// List returns the pairs joined by sep sorted by key
+func (m MappingList) List(sep string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	list := []string{}
+	for _, k := range keys {
+		list = append(list, k+sep+m[k])
+	}
+
+	return list
+}
+
+// HostList is a list of extra hosts written either as a map or as a list of
+// "host:ip" strings
+type HostList map[string]string
+
+func (h *HostList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	m := MappingList{}
+	if err := m.unmarshal(unmarshal, ":"); err != nil {
+		return err
+	}
+
+	*h = HostList(m)
+	return nil
+}
+
+// MemorySize is a number of bytes optionally written with a unit suffix
+// (i.e. "512m")
+type MemorySize int64
+
+func (s *MemorySize) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+
+	str, err := stringValue(v)
+	if err != nil {
+		return err
+	}
+
+	size, err := parseMemorySize(str)
+	if err != nil {
+		return err
+	}
+
+	*s = MemorySize(size)
+	return nil
+}
+
+func parseMemorySize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	units := map[string]int64{
+		"b": 1,
+		"k": 1 << 10,
+		"m": 1 << 20,
+		"g": 1 << 30,
+	}
+
+	orig := s
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimSuffix(s, "b")
+	multiplier := int64(1)
+	if len(s) > 0 {
+		if m, ok := units[s[len(s)-1:]]; ok {
+			multiplier = m
+			s = s[:len(s)-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory size: %s", orig)
+	}
+
+	return int64(n * float64(multiplier)), nil
+}
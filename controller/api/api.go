@@ -10,6 +10,7 @@ import (
 	"github.com/gorilla/context"
 	"github.com/gorilla/mux"
 	"github.com/mailgun/oxy/forward"
+	"github.com/shipyard/shipyard"
 	"github.com/shipyard/shipyard/auth"
 	"github.com/shipyard/shipyard/controller/manager"
 	"github.com/shipyard/shipyard/controller/middleware/access"
@@ -30,6 +31,7 @@ type (
 		tlsCACertPath      string
 		tlsCertPath        string
 		tlsKeyPath         string
+		proxyPolicy        string
 		dUrl               string
 		fwd                *forward.Forwarder
 	}
@@ -43,6 +45,7 @@ type (
 		TLSCACertPath      string
 		TLSCertPath        string
 		TLSKeyPath         string
+		ProxyPolicy        string
 	}
 
 	Credentials struct {
@@ -57,7 +60,29 @@ func writeCorsHeaders(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Access-Control-Allow-Methods", "GET, POST, DELETE, PUT, OPTIONS")
 }
 
+// currentUsername returns the username from the access token of the request.
+// Requests authenticated with a service key have no username.
+func currentUsername(r *http.Request) string {
+	tk, err := auth.GetAccessToken(r.Header.Get("X-Access-Token"))
+	if err != nil {
+		return ""
+	}
+
+	return tk.Username
+}
+
+// NewApi returns the api for the config.  Docker api endpoints of unknown
+// resources are denied unless the proxy policy allows them.
 func NewApi(config ApiConfig) (*Api, error) {
+	policy := config.ProxyPolicy
+	switch policy {
+	case "":
+		policy = shipyard.ProxyPolicyDeny
+	case shipyard.ProxyPolicyAllow, shipyard.ProxyPolicyDeny:
+	default:
+		return nil, fmt.Errorf("invalid proxy policy: %s", policy)
+	}
+
 	return &Api{
 		listenAddr:         config.ListenAddr,
 		manager:            config.Manager,
@@ -67,6 +92,7 @@ func NewApi(config ApiConfig) (*Api, error) {
 		tlsCertPath:        config.TLSCertPath,
 		tlsKeyPath:         config.TLSKeyPath,
 		tlsCACertPath:      config.TLSCACertPath,
+		proxyPolicy:        policy,
 	}, nil
 }
 
@@ -108,8 +134,6 @@ func (a *Api) Run() error {
 
 	log.Debugf("configured docker proxy target: %s", a.dUrl)
 
-	swarmRedirect := http.HandlerFunc(a.swarmRedirect)
-
 	swarmHijack := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		a.swarmHijack(client.TLSConfig, a.dUrl, w, req)
 	})
@@ -121,18 +145,93 @@ func (a *Api) Run() error {
 	apiRouter.HandleFunc("/api/accounts/{username}", a.deleteAccount).Methods("DELETE")
 	apiRouter.HandleFunc("/api/roles", a.roles).Methods("GET")
 	apiRouter.HandleFunc("/api/roles/{name}", a.role).Methods("GET")
+	apiRouter.HandleFunc("/api/cluster/info", a.clusterInfo).Methods("GET")
 	apiRouter.HandleFunc("/api/nodes", a.nodes).Methods("GET")
+	apiRouter.HandleFunc("/api/nodes/maintenance", a.maintenanceNodes).Methods("GET")
 	apiRouter.HandleFunc("/api/nodes/{name}", a.node).Methods("GET")
+	apiRouter.HandleFunc("/api/nodes/{name}/maintenance", a.setNodeMaintenance).Methods("POST")
+	apiRouter.HandleFunc("/api/nodes/{name}/maintenance", a.clearNodeMaintenance).Methods("DELETE")
+	apiRouter.HandleFunc("/api/nodes/{name}/drain", a.drainNode).Methods("POST")
+	apiRouter.HandleFunc("/api/services", a.services).Methods("GET")
+	apiRouter.HandleFunc("/api/services", a.createService).Methods("POST")
+	apiRouter.HandleFunc("/api/services/{id}", a.service).Methods("GET")
+	apiRouter.HandleFunc("/api/services/{id}", a.removeService).Methods("DELETE")
+	apiRouter.HandleFunc("/api/services/{id}/update", a.updateService).Methods("POST")
+	apiRouter.HandleFunc("/api/services/{id}/tasks", a.serviceTasks).Methods("GET")
+	apiRouter.HandleFunc("/api/tasks", a.tasks).Methods("GET")
+	apiRouter.HandleFunc("/api/tasks/{id}", a.task).Methods("GET")
+	apiRouter.HandleFunc("/api/secrets", a.secrets).Methods("GET")
+	apiRouter.HandleFunc("/api/secrets", a.createSecret).Methods("POST")
+	apiRouter.HandleFunc("/api/secrets/{id}", a.secret).Methods("GET")
+	apiRouter.HandleFunc("/api/secrets/{id}", a.removeSecret).Methods("DELETE")
+	apiRouter.HandleFunc("/api/networks", a.networks).Methods("GET")
+	apiRouter.HandleFunc("/api/networks", a.createNetwork).Methods("POST")
+	apiRouter.HandleFunc("/api/networks/{id}", a.network).Methods("GET")
+	apiRouter.HandleFunc("/api/networks/{id}", a.removeNetwork).Methods("DELETE")
+	apiRouter.HandleFunc("/api/networks/{id}/connect", a.connectNetwork).Methods("POST")
+	apiRouter.HandleFunc("/api/networks/{id}/disconnect", a.disconnectNetwork).Methods("POST")
+	apiRouter.HandleFunc("/api/volumes", a.volumes).Methods("GET")
+	apiRouter.HandleFunc("/api/volumes", a.createVolume).Methods("POST")
+	apiRouter.HandleFunc("/api/volumes/cleanup", a.cleanupVolumes).Methods("POST")
+	apiRouter.HandleFunc("/api/volumes/{name:.*}", a.volume).Methods("GET")
+	apiRouter.HandleFunc("/api/volumes/{name:.*}", a.removeVolume).Methods("DELETE")
+	apiRouter.HandleFunc("/api/containers/health", a.healthStatuses).Methods("GET")
+	apiRouter.HandleFunc("/api/containers/{id}/health", a.containerHealth).Methods("GET")
 	apiRouter.HandleFunc("/api/containers/{id}/scale", a.scaleContainer).Methods("POST")
+	apiRouter.HandleFunc("/api/groups/{name}/scale", a.scaleGroup).Methods("POST")
+	apiRouter.HandleFunc("/api/templates", a.templates).Methods("GET")
+	apiRouter.HandleFunc("/api/templates", a.saveTemplate).Methods("POST")
+	apiRouter.HandleFunc("/api/templates/{name}", a.template).Methods("GET")
+	apiRouter.HandleFunc("/api/templates/{name}", a.removeTemplate).Methods("DELETE")
+	apiRouter.HandleFunc("/api/templates/{name}/versions", a.templateVersions).Methods("GET")
+	apiRouter.HandleFunc("/api/templates/{name}/render", a.renderTemplate).Methods("POST")
+	apiRouter.HandleFunc("/api/templates/{name}/deploy", a.deployTemplate).Methods("POST")
+	apiRouter.HandleFunc("/api/groups/{name}/update", a.updateGroup).Methods("POST")
+	apiRouter.HandleFunc("/api/groups/{group}/revisions", a.revisions).Methods("GET")
+	apiRouter.HandleFunc("/api/groups/{group}/revisions/diff", a.diffRevisions).Methods("GET")
+	apiRouter.HandleFunc("/api/groups/{group}/revisions/{number}", a.revision).Methods("GET")
 	apiRouter.HandleFunc("/api/events", a.events).Methods("GET")
 	apiRouter.HandleFunc("/api/events", a.purgeEvents).Methods("DELETE")
 	apiRouter.HandleFunc("/api/registries", a.registries).Methods("GET")
 	apiRouter.HandleFunc("/api/registries", a.addRegistry).Methods("POST")
 	apiRouter.HandleFunc("/api/registries/{name}", a.registry).Methods("GET")
 	apiRouter.HandleFunc("/api/registries/{name}", a.removeRegistry).Methods("DELETE")
+	apiRouter.HandleFunc("/api/registries/{name}/storage", a.registryStorage).Methods("GET")
+	apiRouter.HandleFunc("/api/registries/{name}/storage/history", a.registryStorageHistory).Methods("GET")
 	apiRouter.HandleFunc("/api/registries/{name}/repositories", a.repositories).Methods("GET")
+	apiRouter.HandleFunc("/api/registries/{name}/repositories/{repo:.+}/tags", a.repositoryTags).Methods("GET")
+	apiRouter.HandleFunc("/api/registries/{name}/repositories/{repo:.+}/usage", a.repositoryUsage).Methods("GET")
+	apiRouter.HandleFunc("/api/registries/{name}/repositories/{repo:.+}/storage", a.repositoryStorage).Methods("GET")
+	apiRouter.HandleFunc("/api/registries/{name}/repositories/{repo:.+}/tags/{tag}", a.deleteRepositoryTag).Methods("DELETE")
+	apiRouter.HandleFunc("/api/registries/{name}/repositories/{repo:.+}/deploy", a.deployRepository).Methods("POST")
 	apiRouter.HandleFunc("/api/registries/{name}/repositories/{repo:.*}", a.repository).Methods("GET")
 	apiRouter.HandleFunc("/api/registries/{name}/repositories/{repo:.*}", a.deleteRepository).Methods("DELETE")
+	apiRouter.HandleFunc("/api/retentionpolicies", a.retentionPolicies).Methods("GET")
+	apiRouter.HandleFunc("/api/retentionpolicies", a.saveRetentionPolicy).Methods("POST")
+	apiRouter.HandleFunc("/api/retentionpolicies/{id}", a.retentionPolicy).Methods("GET")
+	apiRouter.HandleFunc("/api/retentionpolicies/{id}", a.saveRetentionPolicy).Methods("PUT")
+	apiRouter.HandleFunc("/api/retentionpolicies/{id}", a.removeRetentionPolicy).Methods("DELETE")
+	apiRouter.HandleFunc("/api/retentionpolicies/{id}/apply", a.applyRetentionPolicy).Methods("POST")
+	apiRouter.HandleFunc("/api/promotions", a.promoteImage).Methods("POST")
+	apiRouter.HandleFunc("/api/images/search", a.searchImages).Methods("GET")
+	apiRouter.HandleFunc("/api/applications", a.applications).Methods("GET")
+	apiRouter.HandleFunc("/api/applications", a.createApplication).Methods("POST")
+	apiRouter.HandleFunc("/api/applications/{name}", a.inspectApplication).Methods("GET")
+	apiRouter.HandleFunc("/api/applications/{name}", a.removeApplication).Methods("DELETE")
+	apiRouter.HandleFunc("/api/applications/{name}/start", a.startApplication).Methods("POST")
+	apiRouter.HandleFunc("/api/applications/{name}/stop", a.stopApplication).Methods("POST")
+	apiRouter.HandleFunc("/api/applications/{name}/restart", a.restartApplication).Methods("POST")
+	apiRouter.HandleFunc("/api/applications/{name}/redeploy", a.redeployApplication).Methods("POST")
+	apiRouter.HandleFunc("/api/applications/{name}/update", a.updateApplication).Methods("POST")
+	apiRouter.HandleFunc("/api/applications/{name}/reconcile", a.reconcileApplication).Methods("POST")
+	apiRouter.HandleFunc("/api/applications/{name}/reconcile/pause", a.pauseReconcile).Methods("POST")
+	apiRouter.HandleFunc("/api/applications/{name}/reconcile/resume", a.resumeReconcile).Methods("POST")
+	apiRouter.HandleFunc("/api/applications/{name}/revisions", a.revisions).Methods("GET")
+	apiRouter.HandleFunc("/api/applications/{name}/revisions/diff", a.diffRevisions).Methods("GET")
+	apiRouter.HandleFunc("/api/applications/{name}/revisions/{number}", a.revision).Methods("GET")
+	apiRouter.HandleFunc("/api/applications/{name}/revisions/{number}/rollback", a.rollbackApplication).Methods("POST")
+	apiRouter.HandleFunc("/api/applications/{name}/logs", a.applicationLogs).Methods("GET")
+	apiRouter.HandleFunc("/api/applications/{name}/stats", a.applicationStats).Methods("GET")
 	apiRouter.HandleFunc("/api/servicekeys", a.serviceKeys).Methods("GET")
 	apiRouter.HandleFunc("/api/servicekeys", a.addServiceKey).Methods("POST")
 	apiRouter.HandleFunc("/api/servicekeys", a.removeServiceKey).Methods("DELETE")
@@ -144,9 +243,6 @@ func (a *Api) Run() error {
 	apiRouter.HandleFunc("/api/consolesession/{token}", a.consoleSession).Methods("GET")
 	apiRouter.HandleFunc("/api/consolesession/{token}", a.removeConsoleSession).Methods("DELETE")
 
-	// global handler
-	globalMux.Handle("/", http.FileServer(http.Dir("static")))
-
 	auditExcludes := []string{
 		"^/containers/json",
 		"^/images/json",
@@ -187,83 +283,28 @@ func (a *Api) Run() error {
 
 	// swarm
 	swarmRouter := mux.NewRouter()
-	// these are pulled from the swarm api code to proxy and allow
-	// usage with the standard Docker cli
-	m := map[string]map[string]http.HandlerFunc{
-		"GET": {
-			"/_ping":                          swarmRedirect,
-			"/events":                         swarmRedirect,
-			"/info":                           swarmRedirect,
-			"/version":                        swarmRedirect,
-			"/images/json":                    swarmRedirect,
-			"/images/viz":                     swarmRedirect,
-			"/images/search":                  swarmRedirect,
-			"/images/get":                     swarmRedirect,
-			"/images/{name:.*}/get":           swarmRedirect,
-			"/images/{name:.*}/history":       swarmRedirect,
-			"/images/{name:.*}/json":          swarmRedirect,
-			"/containers/ps":                  swarmRedirect,
-			"/containers/json":                swarmRedirect,
-			"/containers/{name:.*}/export":    swarmRedirect,
-			"/containers/{name:.*}/changes":   swarmRedirect,
-			"/containers/{name:.*}/json":      swarmRedirect,
-			"/containers/{name:.*}/top":       swarmRedirect,
-			"/containers/{name:.*}/logs":      swarmRedirect,
-			"/containers/{name:.*}/stats":     swarmRedirect,
-			"/containers/{name:.*}/attach/ws": swarmHijack,
-			"/exec/{execid:.*}/json":          swarmRedirect,
-		},
-		"POST": {
-			"/auth":                         swarmRedirect,
-			"/commit":                       swarmRedirect,
-			"/build":                        swarmRedirect,
-			"/images/create":                swarmRedirect,
-			"/images/load":                  swarmRedirect,
-			"/images/{name:.*}/push":        swarmRedirect,
-			"/images/{name:.*}/tag":         swarmRedirect,
-			"/containers/create":            swarmRedirect,
-			"/containers/{name:.*}/kill":    swarmRedirect,
-			"/containers/{name:.*}/pause":   swarmRedirect,
-			"/containers/{name:.*}/unpause": swarmRedirect,
-			"/containers/{name:.*}/rename":  swarmRedirect,
-			"/containers/{name:.*}/restart": swarmRedirect,
-			"/containers/{name:.*}/start":   swarmRedirect,
-			"/containers/{name:.*}/stop":    swarmRedirect,
-			"/containers/{name:.*}/wait":    swarmRedirect,
-			"/containers/{name:.*}/resize":  swarmRedirect,
-			"/containers/{name:.*}/attach":  swarmHijack,
-			"/containers/{name:.*}/copy":    swarmRedirect,
-			"/containers/{name:.*}/exec":    swarmRedirect,
-			"/exec/{execid:.*}/start":       swarmHijack,
-			"/exec/{execid:.*}/resize":      swarmRedirect,
-		},
-		"DELETE": {
-			"/containers/{name:.*}": swarmRedirect,
-			"/images/{name:.*}":     swarmRedirect,
-		},
-		"OPTIONS": {
-			"": swarmRedirect,
-		},
-	}
-
-	for method, routes := range m {
-		for route, fct := range routes {
-			localRoute := route
-			localFct := fct
-			wrap := func(w http.ResponseWriter, r *http.Request) {
-				if a.enableCors {
-					writeCorsHeaders(w, r)
-				}
-				localFct(w, r)
+	cors := func(fct http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if a.enableCors {
+				writeCorsHeaders(w, r)
 			}
-			localMethod := method
-
-			// add the new route
-			swarmRouter.Path("/v{version:[0-9.]+}" + localRoute).Methods(localMethod).HandlerFunc(wrap)
-			swarmRouter.Path(localRoute).Methods(localMethod).HandlerFunc(wrap)
+			fct(w, r)
 		}
 	}
 
+	// streams are hijacked; all other docker api requests are forwarded
+	// to swarm with any api version
+	hijacked := map[string]string{
+		"/containers/{name:.*}/attach/ws": "GET",
+		"/containers/{name:.*}/attach":    "POST",
+		"/exec/{execid:.*}/start":         "POST",
+	}
+	for route, method := range hijacked {
+		swarmRouter.Path("/v{version:[0-9.]+}" + route).Methods(method).HandlerFunc(cors(swarmHijack))
+		swarmRouter.Path(route).Methods(method).HandlerFunc(cors(swarmHijack))
+	}
+	swarmRouter.PathPrefix("/").HandlerFunc(cors(a.proxy))
+
 	swarmAuthRouter := negroni.New()
 	swarmAuthRequired := mAuth.NewAuthRequired(controllerManager, a.authWhitelistCIDRs)
 	swarmAccessRequired := access.NewAccessRequired(controllerManager)
@@ -271,21 +312,17 @@ func (a *Api) Run() error {
 	swarmAuthRouter.Use(negroni.HandlerFunc(swarmAccessRequired.HandlerFuncWithNext))
 	swarmAuthRouter.Use(negroni.HandlerFunc(apiAuditor.HandlerFuncWithNext))
 	swarmAuthRouter.UseHandler(swarmRouter)
-	globalMux.Handle("/containers/", swarmAuthRouter)
-	globalMux.Handle("/_ping", swarmAuthRouter)
-	globalMux.Handle("/commit", swarmAuthRouter)
-	globalMux.Handle("/build", swarmAuthRouter)
-	globalMux.Handle("/events", swarmAuthRouter)
-	globalMux.Handle("/version", swarmAuthRouter)
-	globalMux.Handle("/images/", swarmAuthRouter)
-	globalMux.Handle("/exec/", swarmAuthRouter)
-	globalMux.Handle("/v1.14/", swarmAuthRouter)
-	globalMux.Handle("/v1.15/", swarmAuthRouter)
-	globalMux.Handle("/v1.16/", swarmAuthRouter)
-	globalMux.Handle("/v1.17/", swarmAuthRouter)
-	globalMux.Handle("/v1.18/", swarmAuthRouter)
-	globalMux.Handle("/v1.19/", swarmAuthRouter)
-	globalMux.Handle("/v1.20/", swarmAuthRouter)
+
+	// global handler; docker api requests go to swarm and everything else
+	// to the static files
+	static := http.FileServer(http.Dir("static"))
+	globalMux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if shipyard.IsProxyPath(r.URL.Path) {
+			swarmAuthRouter.ServeHTTP(w, r)
+			return
+		}
+		static.ServeHTTP(w, r)
+	}))
 
 	// check for admin user
 	if _, err := controllerManager.Account("admin"); err == manager.ErrAccountDoesNotExist {
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/compose"
+	"github.com/shipyard/shipyard/controller/manager"
+)
+
+// readApplication reads an application from the request.  JSON requests
+// contain the name and definition; other requests contain the compose file
+// with the name in the name parameter.
+func readApplication(r *http.Request) (*shipyard.Application, error) {
+	app := &shipyard.Application{}
+	if strings.Contains(r.Header.Get("Content-Type"), "json") {
+		if err := json.NewDecoder(r.Body).Decode(app); err != nil {
+			return nil, err
+		}
+
+		return app, nil
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	app.Name = r.URL.Query().Get("name")
+	app.Definition = string(data)
+
+	return app, nil
+}
+
+func (a *Api) application(w http.ResponseWriter, r *http.Request) (*shipyard.Application, bool) {
+	name := mux.Vars(r)["name"]
+
+	app, err := a.manager.Application(name)
+	if err != nil {
+		if err == manager.ErrApplicationDoesNotExist {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return app, true
+}
+
+func (a *Api) applications(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	apps, err := a.manager.Applications()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(apps); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) inspectApplication(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	app, ok := a.application(w, r)
+	if !ok {
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(app); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) createApplication(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	app, err := readApplication(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if app.Name == "" {
+		http.Error(w, "application name is required", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := compose.Load([]byte(app.Definition)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := a.manager.CreateApplication(app, currentUsername(r)); err != nil {
+		if err == manager.ErrApplicationExists {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(app); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// writeApplicationResult writes the per container results of a bulk
+// operation.  Partial failures are reported with a 500 like scaling.
+func writeApplicationResult(w http.ResponseWriter, result manager.ApplicationResult) {
+	w.Header().Set("content-type", "application/json")
+
+	if len(result.Errors) > 0 {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) startApplication(w http.ResponseWriter, r *http.Request) {
+	app, ok := a.application(w, r)
+	if !ok {
+		return
+	}
+
+	writeApplicationResult(w, a.manager.StartApplication(app, currentUsername(r)))
+}
+
+func (a *Api) stopApplication(w http.ResponseWriter, r *http.Request) {
+	app, ok := a.application(w, r)
+	if !ok {
+		return
+	}
+
+	writeApplicationResult(w, a.manager.StopApplication(app, currentUsername(r)))
+}
+
+func (a *Api) restartApplication(w http.ResponseWriter, r *http.Request) {
+	app, ok := a.application(w, r)
+	if !ok {
+		return
+	}
+
+	writeApplicationResult(w, a.manager.RestartApplication(app, currentUsername(r)))
+}
+
+func (a *Api) applicationLogs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	app, ok := a.application(w, r)
+	if !ok {
+		return
+	}
+
+	tail, err := intParam(r, "tail", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logs, err := a.manager.ApplicationLogs(app, tail)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(logs); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) applicationStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	app, ok := a.application(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := a.manager.ApplicationStats(app)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// redeployApplication recreates the containers of the application.  The
+// request may contain a new compose definition.
+func (a *Api) redeployApplication(w http.ResponseWriter, r *http.Request) {
+	app, ok := a.application(w, r)
+	if !ok {
+		return
+	}
+
+	update, err := readApplication(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if update.Definition != "" {
+		if _, err := compose.Load([]byte(update.Definition)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := a.manager.RedeployApplication(app, update.Definition, currentUsername(r)); err != nil {
+		if err == manager.ErrApplicationNotDeployed {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Api) removeApplication(w http.ResponseWriter, r *http.Request) {
+	app, ok := a.application(w, r)
+	if !ok {
+		return
+	}
+
+	writeApplicationResult(w, a.manager.RemoveApplication(app, currentUsername(r)))
+}
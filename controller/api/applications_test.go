@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/manager"
+	"github.com/shipyard/shipyard/controller/mock_test"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestApplicationsServer(t *testing.T) *httptest.Server {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/applications", api.createApplication).Methods("POST")
+	router.HandleFunc("/api/applications/{name}", api.inspectApplication).Methods("GET")
+	router.HandleFunc("/api/applications/{name}/redeploy", api.redeployApplication).Methods("POST")
+	router.HandleFunc("/api/applications/{name}/start", api.startApplication).Methods("POST")
+	router.HandleFunc("/api/applications/{name}/restart", api.restartApplication).Methods("POST")
+	router.HandleFunc("/api/applications/{name}/logs", api.applicationLogs).Methods("GET")
+
+	return httptest.NewServer(router)
+}
+
+func TestApiCreateApplication(t *testing.T) {
+	ts := getTestApplicationsServer(t)
+	defer ts.Close()
+
+	definition := "web:\n  image: nginx\n  links:\n    - app\napp:\n  image: example/app\n"
+	res, err := http.Post(ts.URL+"/api/applications?name=blog", "application/x-yaml", strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 201, "expected response code 201")
+}
+
+func TestApiCreateApplicationInvalidDefinition(t *testing.T) {
+	ts := getTestApplicationsServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/applications?name=blog", "application/x-yaml", strings.NewReader("web:\n  build: .\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 400, "expected response code 400")
+}
+
+func TestApiCreateApplicationExists(t *testing.T) {
+	ts := getTestApplicationsServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/applications", "application/json", strings.NewReader(`{"name": "`+mock_test.TestApplication.Name+`", "definition": "web:\n  image: nginx\n"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 409, "expected response code 409")
+}
+
+func TestApiInspectApplicationNotFound(t *testing.T) {
+	ts := getTestApplicationsServer(t)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/applications/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 404, "expected response code 404")
+}
+
+func TestApiRedeployApplication(t *testing.T) {
+	ts := getTestApplicationsServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/applications/"+mock_test.TestApplication.Name+"/redeploy", "application/x-yaml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 204, "expected response code 204")
+}
+
+func TestApiStartApplication(t *testing.T) {
+	ts := getTestApplicationsServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/applications/"+mock_test.TestApplication.Name+"/start", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+	result := manager.ApplicationResult{}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, result.Containers, []string{mock_test.TestContainerId}, "expected started container")
+}
+
+func TestApiRestartApplicationPartialFailure(t *testing.T) {
+	ts := getTestApplicationsServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/applications/"+mock_test.TestApplication.Name+"/restart", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 500, "expected response code 500")
+	result := manager.ApplicationResult{}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(result.Containers), 1, "expected 1 restarted container")
+	assert.Equal(t, len(result.Errors), 1, "expected 1 error")
+}
+
+func TestApiApplicationLogs(t *testing.T) {
+	ts := getTestApplicationsServer(t)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/applications/" + mock_test.TestApplication.Name + "/logs?tail=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+	logs := []*shipyard.ApplicationLog{}
+	if err := json.NewDecoder(res.Body).Decode(&logs); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(logs), 1, "expected 1 log line")
+	assert.Equal(t, logs[0].Service, "web", "expected service of log line")
+}
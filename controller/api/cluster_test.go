@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestClusterServer(t *testing.T) *httptest.Server {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/cluster/info", api.clusterInfo).Methods("GET")
+
+	return httptest.NewServer(router)
+}
+
+func TestApiGetClusterInfo(t *testing.T) {
+	ts := getTestClusterServer(t)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/cluster/info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+	info := &shipyard.ClusterInfo{}
+	if err := json.NewDecoder(res.Body).Decode(info); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, info.Cpus, 4.0)
+	assert.Equal(t, info.EngineCount, 1)
+	if assert.Len(t, info.Nodes, 1) {
+		assert.Equal(t, info.Nodes[0].Name, "testnode")
+		assert.Equal(t, info.Nodes[0].ReservedMemory, 1073741824.0)
+	}
+}
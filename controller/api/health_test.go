@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/mock_test"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestHealthServer(t *testing.T) *httptest.Server {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/containers/health", api.healthStatuses).Methods("GET")
+	router.HandleFunc("/api/containers/{id}/health", api.containerHealth).Methods("GET")
+
+	return httptest.NewServer(router)
+}
+
+func TestApiHealthStatuses(t *testing.T) {
+	ts := getTestHealthServer(t)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/containers/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+
+	statuses := []*shipyard.ContainerHealth{}
+	if err := json.NewDecoder(res.Body).Decode(&statuses); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(statuses), 1, "expected 1 monitored container")
+	assert.Equal(t, statuses[0].Status, shipyard.HealthHealthy)
+}
+
+func TestApiContainerHealth(t *testing.T) {
+	ts := getTestHealthServer(t)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/containers/" + mock_test.TestContainerId + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+
+	health := &shipyard.ContainerHealth{}
+	if err := json.NewDecoder(res.Body).Decode(health); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, health.Name, mock_test.TestContainerName)
+}
+
+func TestApiContainerHealthNotMonitored(t *testing.T) {
+	ts := getTestHealthServer(t)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/containers/unknown/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 404, "expected response code 404")
+}
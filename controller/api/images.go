@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultSearchTimeout = 10 * time.Second
+)
+
+// searchImages searches the repositories of all registries the caller may
+// use.  The per registry timeout can be set with the timeout parameter
+// (i.e. "5s").
+func (a *Api) searchImages(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		http.Error(w, "search query is required", http.StatusBadRequest)
+		return
+	}
+
+	timeout := defaultSearchTimeout
+	if t := r.URL.Query().Get("timeout"); t != "" {
+		d, err := time.ParseDuration(t)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid timeout", http.StatusBadRequest)
+			return
+		}
+
+		timeout = d
+	}
+
+	search, err := a.manager.SearchImages(query, currentUsername(r), timeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(search); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
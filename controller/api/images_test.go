@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shipyard/shipyard"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApiSearchImages(t *testing.T) {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(api.searchImages))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "?q=redis&timeout=2s")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+	search := &shipyard.ImageSearch{}
+	if err := json.NewDecoder(res.Body).Decode(search); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(search.Results), 1, "expected 1 result")
+	assert.Equal(t, search.Results[0].Repository, "library/redis", "expected redis repository")
+	assert.Equal(t, search.Errors["offline-registry"], "timed out after 2s", "expected error for offline registry")
+}
+
+func TestApiSearchImagesRequiresQuery(t *testing.T) {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(api.searchImages))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 400, "expected response code 400")
+}
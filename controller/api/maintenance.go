@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard/controller/manager"
+)
+
+func (a *Api) maintenanceNodes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	nodes, err := a.manager.MaintenanceNodes()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(nodes); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func writeNodeError(w http.ResponseWriter, err error) {
+	if err == manager.ErrNodeDoesNotExist {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// setNodeMaintenance stops new containers from being placed on the node
+func (a *Api) setNodeMaintenance(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := a.manager.SetNodeMaintenance(name, req.Reason, currentUsername(r)); err != nil {
+		writeNodeError(w, err)
+		return
+	}
+
+	log.Infof("node maintenance started: node=%s", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Api) clearNodeMaintenance(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	if err := a.manager.ClearNodeMaintenance(name, currentUsername(r)); err != nil {
+		writeNodeError(w, err)
+		return
+	}
+
+	log.Infof("node maintenance ended: node=%s", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// drainNode streams the progress of moving the containers off the node
+func (a *Api) drainNode(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	progress := newProgressWriter(w)
+	res, err := a.manager.DrainNode(name, currentUsername(r), progress.writeDrain)
+	if err != nil {
+		writeNodeError(w, err)
+		return
+	}
+
+	log.Infof("drained node: node=%s moved=%d failed=%d", name, len(res.Moved), len(res.Failed))
+}
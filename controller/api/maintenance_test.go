@@ -0,0 +1,122 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/mock_test"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestMaintenanceServer(t *testing.T) *httptest.Server {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/nodes/maintenance", api.maintenanceNodes).Methods("GET")
+	router.HandleFunc("/api/nodes/{name}/maintenance", api.setNodeMaintenance).Methods("POST")
+	router.HandleFunc("/api/nodes/{name}/maintenance", api.clearNodeMaintenance).Methods("DELETE")
+	router.HandleFunc("/api/nodes/{name}/drain", api.drainNode).Methods("POST")
+
+	return httptest.NewServer(router)
+}
+
+func TestApiGetMaintenanceNodes(t *testing.T) {
+	ts := getTestMaintenanceServer(t)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/nodes/maintenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+	nodes := []*shipyard.NodeMaintenance{}
+	if err := json.NewDecoder(res.Body).Decode(&nodes); err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, nodes, 1) {
+		assert.Equal(t, nodes[0].Node, mock_test.TestNode.Name)
+	}
+}
+
+func TestApiSetNodeMaintenance(t *testing.T) {
+	ts := getTestMaintenanceServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/nodes/"+mock_test.TestNode.Name+"/maintenance", "application/json", bytes.NewBufferString(`{"reason": "patching"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, res.StatusCode, 204, "expected response code 204")
+
+	res, err = http.Post(ts.URL+"/api/nodes/unknown/maintenance", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, res.StatusCode, 404, "expected response code 404")
+}
+
+func TestApiClearNodeMaintenance(t *testing.T) {
+	ts := getTestMaintenanceServer(t)
+	defer ts.Close()
+
+	req, err := http.NewRequest("DELETE", ts.URL+"/api/nodes/"+mock_test.TestNode.Name+"/maintenance", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, res.StatusCode, 204, "expected response code 204")
+}
+
+func TestApiDrainNode(t *testing.T) {
+	ts := getTestMaintenanceServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/nodes/"+mock_test.TestNode.Name+"/drain", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+
+	stages := []string{}
+	var last shipyard.DrainProgress
+	dec := json.NewDecoder(res.Body)
+	for dec.More() {
+		if err := dec.Decode(&last); err != nil {
+			t.Fatal(err)
+		}
+		stages = append(stages, last.Stage)
+	}
+
+	assert.Equal(t, stages, []string{"moved", "complete"})
+	if assert.NotNil(t, last.Result) {
+		assert.Equal(t, last.Result.Moved, []string{mock_test.TestContainerName})
+	}
+}
+
+func TestApiDrainNodeUnknown(t *testing.T) {
+	ts := getTestMaintenanceServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/nodes/unknown/drain", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 404, "expected response code 404")
+}
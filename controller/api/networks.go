@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard/controller/manager"
+)
+
+func writeNetworkError(w http.ResponseWriter, err error) {
+	switch err {
+	case manager.ErrNetworkDoesNotExist:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case manager.ErrNetworkNameRequired, manager.ErrNetworkContainerRequired, manager.ErrNetworkInvalid:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case manager.ErrNetworkPredefined, manager.ErrNetworkNotAdmitted:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case manager.ErrNetworkInUse:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (a *Api) networks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	networks, err := a.manager.Networks()
+	if err != nil {
+		writeNetworkError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(networks); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) network(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	network, err := a.manager.Network(mux.Vars(r)["id"])
+	if err != nil {
+		writeNetworkError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(network); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) createNetwork(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	config := &dockerclient.NetworkCreate{}
+	if err := json.NewDecoder(r.Body).Decode(config); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	network, err := a.manager.CreateNetwork(config, currentUsername(r))
+	if err != nil {
+		writeNetworkError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(network); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) removeNetwork(w http.ResponseWriter, r *http.Request) {
+	if err := a.manager.RemoveNetwork(mux.Vars(r)["id"], currentUsername(r)); err != nil {
+		writeNetworkError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Api) connectNetwork(w http.ResponseWriter, r *http.Request) {
+	connect := &dockerclient.NetworkConnect{}
+	if err := json.NewDecoder(r.Body).Decode(connect); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := a.manager.ConnectNetwork(mux.Vars(r)["id"], connect.Container, currentUsername(r)); err != nil {
+		writeNetworkError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Api) disconnectNetwork(w http.ResponseWriter, r *http.Request) {
+	disconnect := &dockerclient.NetworkDisconnect{}
+	if err := json.NewDecoder(r.Body).Decode(disconnect); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := a.manager.DisconnectNetwork(mux.Vars(r)["id"], disconnect.Container, disconnect.Force, currentUsername(r)); err != nil {
+		writeNetworkError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard/controller/mock_test"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestNetworksServer(t *testing.T) *httptest.Server {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/networks", api.networks).Methods("GET")
+	router.HandleFunc("/api/networks", api.createNetwork).Methods("POST")
+	router.HandleFunc("/api/networks/{id}", api.network).Methods("GET")
+	router.HandleFunc("/api/networks/{id}", api.removeNetwork).Methods("DELETE")
+	router.HandleFunc("/api/networks/{id}/connect", api.connectNetwork).Methods("POST")
+	router.HandleFunc("/api/networks/{id}/disconnect", api.disconnectNetwork).Methods("POST")
+
+	return httptest.NewServer(router)
+}
+
+func TestApiGetNetworks(t *testing.T) {
+	ts := getTestNetworksServer(t)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/networks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+	networks := []*dockerclient.NetworkResource{}
+	if err := json.NewDecoder(res.Body).Decode(&networks); err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, networks, 1) {
+		assert.Equal(t, networks[0].Name, mock_test.TestNetwork.Name)
+		assert.Equal(t, networks[0].IPAM.Config[0].Subnet, "10.0.0.0/24")
+		assert.Equal(t, networks[0].Containers[mock_test.TestContainerId].Name, mock_test.TestContainerName)
+	}
+}
+
+func TestApiGetNetworkUnknown(t *testing.T) {
+	ts := getTestNetworksServer(t)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/networks/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 404, "expected response code 404")
+}
+
+func TestApiCreateNetwork(t *testing.T) {
+	ts := getTestNetworksServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/networks", "application/json", bytes.NewBufferString(`{"Name": "backend", "Driver": "overlay", "Internal": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, http.StatusCreated, "expected response code 201")
+	network := &dockerclient.NetworkResource{}
+	if err := json.NewDecoder(res.Body).Decode(network); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, network.Name, "backend")
+}
+
+func TestApiCreateNetworkNotAdmitted(t *testing.T) {
+	ts := getTestNetworksServer(t)
+	defer ts.Close()
+
+	for _, body := range []string{`{"Name": "public", "Driver": "overlay"}`, `{"Name": "public"}`} {
+		res, err := http.Post(ts.URL+"/api/networks", "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, res.StatusCode, http.StatusForbidden, "expected response code 403 for "+body)
+	}
+}
+
+func TestApiRemoveNetwork(t *testing.T) {
+	ts := getTestNetworksServer(t)
+	defer ts.Close()
+
+	for id, status := range map[string]int{mock_test.TestNetwork.ID: 409, "unknown": 404} {
+		req, err := http.NewRequest("DELETE", ts.URL+"/api/networks/"+id, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, res.StatusCode, status, "unexpected response code for "+id)
+	}
+}
+
+func TestApiConnectNetwork(t *testing.T) {
+	ts := getTestNetworksServer(t)
+	defer ts.Close()
+
+	tests := []struct {
+		path   string
+		body   string
+		status int
+	}{
+		{"/api/networks/" + mock_test.TestNetwork.ID + "/connect", `{"Container": "web"}`, http.StatusNoContent},
+		{"/api/networks/" + mock_test.TestNetwork.ID + "/disconnect", `{"Container": "web", "Force": true}`, http.StatusNoContent},
+		{"/api/networks/" + mock_test.TestNetwork.ID + "/connect", `{}`, http.StatusBadRequest},
+		{"/api/networks/unknown/connect", `{"Container": "web"}`, http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		res, err := http.Post(ts.URL+test.path, "application/json", bytes.NewBufferString(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, res.StatusCode, test.status, "unexpected response code for "+test.path+" "+test.body)
+	}
+}
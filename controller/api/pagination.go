@@ -0,0 +1,60 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// intParam returns the integer form value for key or def if not present
+func intParam(r *http.Request, key string, def int) (int, error) {
+	v := r.FormValue(key)
+	if v == "" {
+		return def, nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s: %s", key, v)
+	}
+
+	if i < 1 {
+		return 0, fmt.Errorf("%s must be a positive value", key)
+	}
+
+	return i, nil
+}
+
+// writePaginationHeaders sets the X-Total-Count and Link headers for a
+// paginated response
+func writePaginationHeaders(w http.ResponseWriter, r *http.Request, total, page, perPage int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	pageLink := func(p int, rel string) string {
+		u := *r.URL
+		q := u.Query()
+		q.Set("page", strconv.Itoa(p))
+		q.Set("per_page", strconv.Itoa(perPage))
+		u.RawQuery = q.Encode()
+		return fmt.Sprintf("<%s>; rel=\"%s\"", u.RequestURI(), rel)
+	}
+
+	lastPage := (total + perPage - 1) / perPage
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	links := []string{
+		pageLink(1, "first"),
+	}
+	if page > 1 {
+		links = append(links, pageLink(page-1, "prev"))
+	}
+	if page < lastPage {
+		links = append(links, pageLink(page+1, "next"))
+	}
+	links = append(links, pageLink(lastPage, "last"))
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
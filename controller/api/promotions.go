@@ -0,0 +1,41 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/manager"
+)
+
+func (a *Api) promoteImage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	var promotion *shipyard.Promotion
+	if err := json.NewDecoder(r.Body).Decode(&promotion); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res, err := a.manager.PromoteImage(promotion, currentUsername(r))
+	if err != nil {
+		switch err {
+		case manager.ErrRegistryDoesNotExist:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case manager.ErrRegistryAccessDenied:
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	log.Infof("promoted image: source=%s/%s:%s target=%s/%s:%s", res.SourceRegistry, res.SourceRepository, res.SourceTag, res.TargetRegistry, res.TargetRepository, res.TargetTag)
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
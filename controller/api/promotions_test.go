@@ -0,0 +1,74 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/mock_test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApiPromoteImage(t *testing.T) {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(api.promoteImage))
+	defer ts.Close()
+
+	data, err := json.Marshal(&shipyard.Promotion{
+		SourceRegistry:   mock_test.TestRegistry.Name,
+		SourceRepository: "app",
+		SourceTag:        "1.0",
+		TargetRegistry:   mock_test.TestRegistry.Name,
+		TargetRepository: "prod/app",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := http.Post(ts.URL, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 201, "expected response code 201")
+	promotion := &shipyard.Promotion{}
+	if err := json.NewDecoder(res.Body).Decode(promotion); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEqual(t, promotion.ImageID, "", "expected image id")
+	assert.Equal(t, len(promotion.CopiedLayers), 1, "expected 1 copied layer")
+}
+
+func TestApiPromoteImageUnknownRegistry(t *testing.T) {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(api.promoteImage))
+	defer ts.Close()
+
+	data, err := json.Marshal(&shipyard.Promotion{
+		SourceRegistry:   "unknown",
+		SourceRepository: "app",
+		TargetRegistry:   mock_test.TestRegistry.Name,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := http.Post(ts.URL, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 404, "expected response code 404")
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/shipyard/shipyard/controller/manager"
+)
+
+func (a *Api) reconcileApplication(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	app, ok := a.application(w, r)
+	if !ok {
+		return
+	}
+
+	drift, err := a.manager.ReconcileApplication(app, currentUsername(r))
+	if err != nil {
+		switch err {
+		case manager.ErrApplicationNotDeployed:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case manager.ErrReconcilePaused:
+			http.Error(w, err.Error(), http.StatusConflict)
+		case manager.ErrRevisionDoesNotExist:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if len(drift.Errors) > 0 {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	if err := json.NewEncoder(w).Encode(drift); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) pauseReconcile(w http.ResponseWriter, r *http.Request) {
+	a.setReconcilePaused(w, r, true)
+}
+
+func (a *Api) resumeReconcile(w http.ResponseWriter, r *http.Request) {
+	a.setReconcilePaused(w, r, false)
+}
+
+func (a *Api) setReconcilePaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	app, ok := a.application(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.manager.SetReconcilePaused(app, paused, currentUsername(r)); err != nil {
+		if err == manager.ErrApplicationNotDeployed {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/mock_test"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestReconcileServer(t *testing.T) *httptest.Server {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/applications/{name}/reconcile", api.reconcileApplication).Methods("POST")
+	router.HandleFunc("/api/applications/{name}/reconcile/pause", api.pauseReconcile).Methods("POST")
+
+	return httptest.NewServer(router)
+}
+
+func TestApiReconcileApplication(t *testing.T) {
+	ts := getTestReconcileServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/applications/"+mock_test.TestApplication.Name+"/reconcile", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+
+	var drift shipyard.ApplicationDrift
+	if err := json.NewDecoder(res.Body).Decode(&drift); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, drift.Created, []string{"test-app_web_1"})
+}
+
+func TestApiPauseReconcile(t *testing.T) {
+	ts := getTestReconcileServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/applications/"+mock_test.TestApplication.Name+"/reconcile/pause", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 204, "expected response code 204")
+}
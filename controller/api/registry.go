@@ -7,6 +7,12 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/gorilla/mux"
 	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/manager"
+	registry "github.com/shipyard/shipyard/registry/v1"
+)
+
+const (
+	defaultRepositoriesPerPage = 100
 )
 
 func (a *Api) registries(w http.ResponseWriter, r *http.Request) {
@@ -80,18 +86,41 @@ func (a *Api) repositories(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	name := vars["name"]
 
+	page, err := intParam(r, "page", 1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	perPage, err := intParam(r, "per_page", defaultRepositoriesPerPage)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// layers are only expanded on request as it fetches every tag
+	expand := r.FormValue("expand") == "true" || r.FormValue("expand") == "1"
+
 	if name != "" {
-		registry, err := a.manager.Registry(name)
+		reg, err := a.manager.Registry(name)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		repos, err := registry.Repositories()
+		res, err := reg.Repositories(r.FormValue("q"), page, perPage, expand)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+
+		writePaginationHeaders(w, r, res.NumberOfResults, page, perPage)
+
+		repos := res.Results
+		if repos == nil {
+			repos = []*registry.Repository{}
+		}
+
 		if err := json.NewEncoder(w).Encode(repos); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -123,10 +152,15 @@ func (a *Api) repository(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// deleteRepository removes the repository unless it is used by running
+// containers.  Pass force=true to delete it anyway.
 func (a *Api) deleteRepository(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
 	vars := mux.Vars(r)
 	name := vars["name"]
 	repoName := vars["repo"]
+	force := r.FormValue("force") == "true" || r.FormValue("force") == "1"
 
 	registry, err := a.manager.Registry(name)
 	if err != nil {
@@ -134,12 +168,113 @@ func (a *Api) deleteRepository(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := registry.DeleteRepository(repoName); err != nil {
+	deletion, err := a.manager.DeleteRepository(registry, repoName, currentUsername(r), force)
+	if err != nil {
+		if err == manager.ErrRepositoryInUse {
+			usage, uErr := a.manager.RepositoryUsage(registry, repoName)
+			if uErr != nil {
+				http.Error(w, uErr.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			w.WriteHeader(http.StatusConflict)
+			if err := json.NewEncoder(w).Encode(usage); err != nil {
+				log.Error(err)
+			}
+			return
+		}
+
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	log.Infof("deleted repository: registry=%s repository=%s tags=%d force=%v", name, repoName, len(deletion.Tags), force)
+
+	if err := json.NewEncoder(w).Encode(deletion); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// repositoryUsage previews what would break if the repository was deleted
+func (a *Api) repositoryUsage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	vars := mux.Vars(r)
+	name := vars["name"]
+	repoName := vars["repo"]
+
+	registry, err := a.manager.Registry(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	usage, err := a.manager.RepositoryUsage(registry, repoName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(usage); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// deployRepository starts a container from a tagged image of the repository
+// and returns the container id
+func (a *Api) deployRepository(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	vars := mux.Vars(r)
+	name := vars["name"]
+	repoName := vars["repo"]
+
+	var deployment *shipyard.RepositoryDeployment
+	if err := json.NewDecoder(r.Body).Decode(&deployment); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if deployment == nil {
+		deployment = &shipyard.RepositoryDeployment{}
+	}
+
+	registry, err := a.manager.Registry(name)
+	if err != nil {
+		if err == manager.ErrRegistryDoesNotExist {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res, err := a.manager.DeployRepository(registry, repoName, deployment, currentUsername(r))
+	if err != nil {
+		if _, ok := err.(*manager.DeploymentError); ok {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err == manager.ErrRegistryAccessDenied {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Infof("deployed repository: registry=%s image=%s container=%s", name, res.Image, res.ContainerID)
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 }
 
 func (a *Api) inspectRepository(w http.ResponseWriter, r *http.Request) {
@@ -163,3 +298,50 @@ func (a *Api) inspectRepository(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func (a *Api) repositoryTags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	vars := mux.Vars(r)
+	name := vars["name"]
+	repoName := vars["repo"]
+
+	registry, err := a.manager.Registry(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := registry.Tags(repoName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(tags); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) deleteRepositoryTag(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	repoName := vars["repo"]
+	tag := vars["tag"]
+
+	registry, err := a.manager.Registry(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := a.manager.DeleteTag(registry, repoName, tag); err != nil {
+		log.Errorf("error deleting tag: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Infof("deleted tag: registry=%s repository=%s tag=%s", name, repoName, tag)
+	w.WriteHeader(http.StatusNoContent)
+}
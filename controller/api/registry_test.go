@@ -0,0 +1,124 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/mock_test"
+	"github.com/stretchr/testify/assert"
+)
+
+func getRepositoryTestServer(t *testing.T) *httptest.Server {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/registries/{name}/repositories/{repo:.+}/usage", api.repositoryUsage).Methods("GET")
+	router.HandleFunc("/api/registries/{name}/repositories/{repo:.+}/deploy", api.deployRepository).Methods("POST")
+	router.HandleFunc("/api/registries/{name}/repositories/{repo:.*}", api.deleteRepository).Methods("DELETE")
+
+	return httptest.NewServer(router)
+}
+
+func deleteRepository(t *testing.T, url string) *http.Response {
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func TestApiDeleteRepositoryInUse(t *testing.T) {
+	ts := getRepositoryTestServer(t)
+	defer ts.Close()
+
+	res := deleteRepository(t, ts.URL+"/api/registries/test-registry/repositories/"+mock_test.TestContainerImage)
+
+	assert.Equal(t, res.StatusCode, http.StatusConflict, "expected response code 409")
+	usage := &shipyard.RepositoryUsage{}
+	if err := json.NewDecoder(res.Body).Decode(usage); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(usage.Containers), 1, "expected container using the repository")
+}
+
+func TestApiDeleteRepositoryForce(t *testing.T) {
+	ts := getRepositoryTestServer(t)
+	defer ts.Close()
+
+	res := deleteRepository(t, ts.URL+"/api/registries/test-registry/repositories/"+mock_test.TestContainerImage+"?force=true")
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+	deletion := &shipyard.RepositoryDeletion{}
+	if err := json.NewDecoder(res.Body).Decode(deletion); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, deletion.Forced, "expected forced deletion")
+}
+
+func TestApiRepositoryUsage(t *testing.T) {
+	ts := getRepositoryTestServer(t)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/registries/test-registry/repositories/team/unused/usage")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+	usage := &shipyard.RepositoryUsage{}
+	if err := json.NewDecoder(res.Body).Decode(usage); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, usage.Repository, "team/unused", "expected repository name")
+	assert.Equal(t, len(usage.Containers), 0, "expected no containers")
+}
+
+func TestApiDeployRepository(t *testing.T) {
+	ts := getRepositoryTestServer(t)
+	defer ts.Close()
+
+	body := bytes.NewBufferString(`{"tag": "1.0", "ports": ["8080:80"]}`)
+	res, err := http.Post(ts.URL+"/api/registries/test-registry/repositories/library/app/deploy", "application/json", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, http.StatusCreated, "expected response code 201")
+	deployment := &shipyard.RepositoryDeployment{}
+	if err := json.NewDecoder(res.Body).Decode(deployment); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, deployment.ContainerID, mock_test.TestContainerId)
+	assert.Equal(t, deployment.Image, "localhost:5000/library/app:1.0")
+}
+
+func TestApiDeployRepositoryInvalidTag(t *testing.T) {
+	ts := getRepositoryTestServer(t)
+	defer ts.Close()
+
+	body := bytes.NewBufferString(`{"tag": "bad tag"}`)
+	res, err := http.Post(ts.URL+"/api/registries/test-registry/repositories/library/app/deploy", "application/json", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, http.StatusBadRequest, "expected response code 400")
+}
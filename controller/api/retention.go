@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/manager"
+)
+
+func (a *Api) retentionPolicies(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	policies, err := a.manager.RetentionPolicies()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(policies); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) retentionPolicy(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	policy, err := a.manager.RetentionPolicy(id)
+	if err != nil {
+		if err == manager.ErrRetentionPolicyDoesNotExist {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(policy); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) saveRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	var policy *shipyard.RetentionPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// updates keep the time of the last enforcement
+	if id := mux.Vars(r)["id"]; id != "" {
+		existing, err := a.manager.RetentionPolicy(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		policy.ID = existing.ID
+		policy.LastRun = existing.LastRun
+	}
+
+	if err := a.manager.SaveRetentionPolicy(policy); err != nil {
+		log.Errorf("error saving retention policy: %s", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Infof("saved retention policy: name=%s registry=%s", policy.Name, policy.Registry)
+
+	if err := json.NewEncoder(w).Encode(policy); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) removeRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	policy, err := a.manager.RetentionPolicy(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := a.manager.RemoveRetentionPolicy(policy); err != nil {
+		log.Errorf("error removing retention policy: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Infof("removed retention policy: name=%s", policy.Name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyRetentionPolicy enforces the policy.  Unless dry_run=false is passed
+// only a report of the tags that would be deleted is returned.
+func (a *Api) applyRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	policy, err := a.manager.RetentionPolicy(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	dryRun := r.FormValue("dry_run") != "false" && r.FormValue("dry_run") != "0"
+
+	report, err := a.manager.ApplyRetentionPolicy(policy, dryRun)
+	if err != nil {
+		log.Errorf("error applying retention policy: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
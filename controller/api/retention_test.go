@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/mock_test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApiGetRetentionPolicies(t *testing.T) {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(api.retentionPolicies))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+	policies := []*shipyard.RetentionPolicy{}
+	if err := json.NewDecoder(res.Body).Decode(&policies); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEqual(t, len(policies), 0, "expected policies; received none")
+	assert.Equal(t, policies[0].Name, mock_test.TestRetentionPolicy.Name, "expected test policy")
+}
+
+func TestApiApplyRetentionPolicyDefaultsToDryRun(t *testing.T) {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(api.applyRetentionPolicy))
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL, "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+	report := &shipyard.RetentionReport{}
+	if err := json.NewDecoder(res.Body).Decode(report); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, report.DryRun, "expected dry run report")
+	assert.Equal(t, len(report.Deleted), 1, "expected 1 deleted tag in report")
+}
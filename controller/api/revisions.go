@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/manager"
+)
+
+// revisionKind returns the kind of revisions served by the route
+func revisionKind(r *http.Request) string {
+	if _, ok := mux.Vars(r)["group"]; ok {
+		return shipyard.RevisionGroup
+	}
+
+	return shipyard.RevisionApplication
+}
+
+func revisionTarget(r *http.Request) string {
+	vars := mux.Vars(r)
+	if group, ok := vars["group"]; ok {
+		return group
+	}
+
+	return vars["name"]
+}
+
+func revisionNumber(w http.ResponseWriter, r *http.Request) (int, bool) {
+	number, err := strconv.Atoi(mux.Vars(r)["number"])
+	if err != nil || number < 1 {
+		http.Error(w, "invalid revision number", http.StatusBadRequest)
+		return 0, false
+	}
+
+	return number, true
+}
+
+func writeRevisionError(w http.ResponseWriter, err error) {
+	if err == manager.ErrRevisionDoesNotExist {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func (a *Api) revisions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	revisions, err := a.manager.Revisions(revisionKind(r), revisionTarget(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(revisions); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) revision(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	number, ok := revisionNumber(w, r)
+	if !ok {
+		return
+	}
+
+	rev, err := a.manager.Revision(revisionKind(r), revisionTarget(r), number)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(rev); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// diffRevisions compares two revisions (params: from, to).  Without
+// parameters the latest revision is compared to the one before.
+func (a *Api) diffRevisions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	from, err := intParam(r, "from", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	to, err := intParam(r, "to", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	diff, err := a.manager.DiffRevisions(revisionKind(r), revisionTarget(r), from, to)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(diff); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) rollbackApplication(w http.ResponseWriter, r *http.Request) {
+	app, ok := a.application(w, r)
+	if !ok {
+		return
+	}
+
+	number, ok := revisionNumber(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := a.manager.RollbackApplication(app, number, currentUsername(r))
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+
+	writeApplicationResult(w, result)
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/manager"
+	"github.com/shipyard/shipyard/controller/mock_test"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestRevisionsServer(t *testing.T) *httptest.Server {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/applications/{name}/revisions", api.revisions).Methods("GET")
+	router.HandleFunc("/api/applications/{name}/revisions/diff", api.diffRevisions).Methods("GET")
+	router.HandleFunc("/api/applications/{name}/revisions/{number}", api.revision).Methods("GET")
+	router.HandleFunc("/api/applications/{name}/revisions/{number}/rollback", api.rollbackApplication).Methods("POST")
+	router.HandleFunc("/api/groups/{group}/revisions", api.revisions).Methods("GET")
+
+	return httptest.NewServer(router)
+}
+
+func TestApiRevisions(t *testing.T) {
+	ts := getTestRevisionsServer(t)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/applications/" + mock_test.TestApplication.Name + "/revisions")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+
+	var revisions []*shipyard.Revision
+	if err := json.NewDecoder(res.Body).Decode(&revisions); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(revisions), 1)
+}
+
+func TestApiGroupRevisions(t *testing.T) {
+	ts := getTestRevisionsServer(t)
+	defer ts.Close()
+
+	// the application revisions are not returned for a group of the same name
+	res, err := http.Get(ts.URL + "/api/groups/" + mock_test.TestApplication.Name + "/revisions")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var revisions []*shipyard.Revision
+	if err := json.NewDecoder(res.Body).Decode(&revisions); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(revisions), 0)
+}
+
+func TestApiRevision(t *testing.T) {
+	ts := getTestRevisionsServer(t)
+	defer ts.Close()
+
+	for path, code := range map[string]int{
+		"/revisions/1":            200,
+		"/revisions/2":            404,
+		"/revisions/x":            400,
+		"/revisions/diff?to=1":    404,
+		"/revisions/diff?from=-1": 400,
+	} {
+		res, err := http.Get(ts.URL + "/api/applications/" + mock_test.TestApplication.Name + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, res.StatusCode, code, "unexpected response code for "+path)
+	}
+}
+
+func TestApiRollbackApplication(t *testing.T) {
+	ts := getTestRevisionsServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/applications/"+mock_test.TestApplication.Name+"/revisions/1/rollback", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+
+	var result manager.ApplicationResult
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, result.Containers, []string{"test-app_web_1"})
+}
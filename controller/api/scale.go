@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard/controller/manager"
 )
 
 func (a *Api) scaleContainer(w http.ResponseWriter, r *http.Request) {
@@ -13,10 +14,27 @@ func (a *Api) scaleContainer(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
 	containerId := vars["id"]
+
+	ports, ok := portStrategyParam(w, r)
+	if !ok {
+		return
+	}
+
+	// scale the group of the container to a number of replicas
+	if r.URL.Query().Get("replicas") != "" {
+		replicas, ok := replicasParam(w, r)
+		if !ok {
+			return
+		}
+
+		writeGroupScaleResult(w, a.manager.ScaleContainerReplicas(containerId, replicas, ports, currentUsername(r)))
+		return
+	}
+
 	n := r.URL.Query()["n"]
 
 	if len(n) == 0 {
-		http.Error(w, "you must enter a number of instances (param: n)", http.StatusBadRequest)
+		http.Error(w, "you must enter a number of instances (param: n) or replicas (param: replicas)", http.StatusBadRequest)
 		return
 	}
 
@@ -31,7 +49,7 @@ func (a *Api) scaleContainer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result := a.manager.ScaleContainer(containerId, numInstances)
+	result := a.manager.ScaleContainer(containerId, numInstances, ports, currentUsername(r))
 	// If we received any errors, continue to write result to the writer, but return a 500
 	if len(result.Errors) > 0 {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -42,3 +60,69 @@ func (a *Api) scaleContainer(w http.ResponseWriter, r *http.Request) {
 	}
 
 }
+
+func (a *Api) scaleGroup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	vars := mux.Vars(r)
+	group := vars["name"]
+
+	replicas, ok := replicasParam(w, r)
+	if !ok {
+		return
+	}
+
+	ports, ok := portStrategyParam(w, r)
+	if !ok {
+		return
+	}
+
+	writeGroupScaleResult(w, a.manager.ScaleGroup(group, replicas, ports, currentUsername(r)))
+}
+
+// replicasParam parses the requested number of replicas and writes an error
+// if it is missing or invalid
+func replicasParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	v := r.URL.Query().Get("replicas")
+	if v == "" {
+		http.Error(w, "you must enter a number of replicas (param: replicas)", http.StatusBadRequest)
+		return 0, false
+	}
+
+	replicas, err := strconv.Atoi(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return 0, false
+	}
+
+	if replicas < 0 {
+		http.Error(w, "replicas must not be negative", http.StatusBadRequest)
+		return 0, false
+	}
+
+	return replicas, true
+}
+
+// portStrategyParam parses how fixed host ports are handled when scaling
+// (param: ports) and writes an error if it is invalid
+func portStrategyParam(w http.ResponseWriter, r *http.Request) (manager.PortStrategy, bool) {
+	ports, err := manager.ParsePortStrategy(r.URL.Query().Get("ports"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+
+	return ports, true
+}
+
+func writeGroupScaleResult(w http.ResponseWriter, result manager.GroupScaleResult) {
+	// the state of the group is written even if scaling failed partially
+	if len(result.Errors) > 0 {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
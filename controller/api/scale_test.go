@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard/controller/manager"
+	"github.com/shipyard/shipyard/controller/mock_test"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestScaleServer(t *testing.T) *httptest.Server {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/containers/{id}/scale", api.scaleContainer).Methods("POST")
+	router.HandleFunc("/api/groups/{name}/scale", api.scaleGroup).Methods("POST")
+
+	return httptest.NewServer(router)
+}
+
+func TestApiScaleGroup(t *testing.T) {
+	ts := getTestScaleServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/groups/"+mock_test.TestContainerName+"/scale?replicas=3", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+
+	var result manager.GroupScaleResult
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, result.Replicas, 3)
+	assert.Equal(t, result.Nodes[mock_test.TestNode.Name], 3)
+}
+
+func TestApiScaleGroupUnknown(t *testing.T) {
+	ts := getTestScaleServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/groups/unknown/scale?replicas=3", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 500, "expected response code 500")
+}
+
+func TestApiScaleGroupInvalidReplicas(t *testing.T) {
+	ts := getTestScaleServer(t)
+	defer ts.Close()
+
+	for _, replicas := range []string{"", "-1", "many"} {
+		res, err := http.Post(ts.URL+"/api/groups/"+mock_test.TestContainerName+"/scale?replicas="+replicas, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, res.StatusCode, 400, "expected response code 400 for replicas="+replicas)
+	}
+}
+
+func TestApiScaleContainerReplicas(t *testing.T) {
+	ts := getTestScaleServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/containers/"+mock_test.TestContainerId+"/scale?replicas=0", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+}
+
+func TestApiScaleGroupInvalidPortStrategy(t *testing.T) {
+	ts := getTestScaleServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/groups/"+mock_test.TestContainerName+"/scale?replicas=3&ports=random", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 400, "expected response code 400")
+}
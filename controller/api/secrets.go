@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+)
+
+func (a *Api) secrets(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	secrets, err := a.manager.Secrets()
+	if err != nil {
+		writeSwarmError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(secrets); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) secret(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	secret, err := a.manager.Secret(mux.Vars(r)["id"])
+	if err != nil {
+		writeSwarmError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(secret); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// createSecret creates a secret from the spec.  The data is base64 encoded
+// as in the engine api.
+func (a *Api) createSecret(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	spec := &shipyard.SwarmSecretSpec{}
+	if err := json.NewDecoder(r.Body).Decode(spec); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := a.manager.CreateSecret(spec, currentUsername(r))
+	if err != nil {
+		writeSwarmError(w, err)
+		return
+	}
+
+	log.Infof("created secret: id=%s name=%s", id, spec.Name)
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(map[string]string{"ID": id}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) removeSecret(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := a.manager.RemoveSecret(id, currentUsername(r)); err != nil {
+		writeSwarmError(w, err)
+		return
+	}
+
+	log.Infof("removed secret: id=%s", id)
+	w.WriteHeader(http.StatusNoContent)
+}
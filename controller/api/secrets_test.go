@@ -0,0 +1,85 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/mock_test"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestSecretsServer(t *testing.T) *httptest.Server {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/secrets", api.secrets).Methods("GET")
+	router.HandleFunc("/api/secrets", api.createSecret).Methods("POST")
+	router.HandleFunc("/api/secrets/{id}", api.secret).Methods("GET")
+	router.HandleFunc("/api/secrets/{id}", api.removeSecret).Methods("DELETE")
+
+	return httptest.NewServer(router)
+}
+
+func TestApiGetSecrets(t *testing.T) {
+	ts := getTestSecretsServer(t)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+	secrets := []*shipyard.SwarmSecret{}
+	if err := json.NewDecoder(res.Body).Decode(&secrets); err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, secrets, 1) {
+		assert.Equal(t, secrets[0].Spec.Name, mock_test.TestSecret.Spec.Name)
+	}
+}
+
+func TestApiCreateSecret(t *testing.T) {
+	ts := getTestSecretsServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/secrets", "application/json", bytes.NewBufferString(`{"Name": "db-password", "Data": "c2VjcmV0"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, res.StatusCode, http.StatusCreated, "expected response code 201")
+
+	res, err = http.Post(ts.URL+"/api/secrets", "application/json", bytes.NewBufferString(`{"Name": "db-password", "Data": "not base64"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, res.StatusCode, 400, "expected response code 400")
+}
+
+func TestApiRemoveSecret(t *testing.T) {
+	ts := getTestSecretsServer(t)
+	defer ts.Close()
+
+	for id, status := range map[string]int{mock_test.TestSecret.ID: 204, "unknown": 404} {
+		req, err := http.NewRequest("DELETE", ts.URL+"/api/secrets/"+id, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, res.StatusCode, status, "unexpected response code for "+id)
+	}
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/manager"
+)
+
+// writeSwarmError writes the error of a swarm mode request.  Errors of the
+// engine keep their status (i.e. 503 if it is not a swarm manager).
+func writeSwarmError(w http.ResponseWriter, err error) {
+	if e, ok := err.(*manager.SwarmError); ok {
+		http.Error(w, e.Message, e.StatusCode)
+		return
+	}
+
+	switch err {
+	case manager.ErrServiceDoesNotExist, manager.ErrTaskDoesNotExist, manager.ErrSecretDoesNotExist:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (a *Api) services(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	services, err := a.manager.Services()
+	if err != nil {
+		writeSwarmError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(services); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) service(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	service, err := a.manager.Service(mux.Vars(r)["id"])
+	if err != nil {
+		writeSwarmError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(service); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) createService(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	spec := &shipyard.SwarmServiceSpec{}
+	if err := json.NewDecoder(r.Body).Decode(spec); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := a.manager.CreateService(spec, currentUsername(r))
+	if err != nil {
+		writeSwarmError(w, err)
+		return
+	}
+
+	log.Infof("created service: id=%s name=%s", id, spec.Name)
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(map[string]string{"ID": id}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// updateService replaces the spec of the service.  The version param
+// defaults to the current version of the service.
+func (a *Api) updateService(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	spec := &shipyard.SwarmServiceSpec{}
+	if err := json.NewDecoder(r.Body).Decode(spec); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var version uint64
+	if v := r.URL.Query().Get("version"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid version", http.StatusBadRequest)
+			return
+		}
+		version = n
+	} else {
+		service, err := a.manager.Service(id)
+		if err != nil {
+			writeSwarmError(w, err)
+			return
+		}
+		id = service.ID
+		version = service.Version.Index
+	}
+
+	if err := a.manager.UpdateService(id, version, spec, currentUsername(r)); err != nil {
+		writeSwarmError(w, err)
+		return
+	}
+
+	log.Infof("updated service: id=%s version=%d", id, version)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Api) removeService(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := a.manager.RemoveService(id, currentUsername(r)); err != nil {
+		writeSwarmError(w, err)
+		return
+	}
+
+	log.Infof("removed service: id=%s", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Api) serviceTasks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	id := mux.Vars(r)["id"]
+
+	// tasks are not found for an unknown service
+	if _, err := a.manager.Service(id); err != nil {
+		writeSwarmError(w, err)
+		return
+	}
+
+	tasks, err := a.manager.Tasks(id)
+	if err != nil {
+		writeSwarmError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(tasks); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// tasks returns all tasks or the tasks of the service param
+func (a *Api) tasks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	tasks, err := a.manager.Tasks(r.URL.Query().Get("service"))
+	if err != nil {
+		writeSwarmError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(tasks); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) task(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	task, err := a.manager.Task(mux.Vars(r)["id"])
+	if err != nil {
+		writeSwarmError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(task); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/mock_test"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestServicesServer(t *testing.T) *httptest.Server {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/services", api.services).Methods("GET")
+	router.HandleFunc("/api/services", api.createService).Methods("POST")
+	router.HandleFunc("/api/services/{id}", api.service).Methods("GET")
+	router.HandleFunc("/api/services/{id}", api.removeService).Methods("DELETE")
+	router.HandleFunc("/api/services/{id}/update", api.updateService).Methods("POST")
+	router.HandleFunc("/api/services/{id}/tasks", api.serviceTasks).Methods("GET")
+	router.HandleFunc("/api/tasks", api.tasks).Methods("GET")
+	router.HandleFunc("/api/tasks/{id}", api.task).Methods("GET")
+
+	return httptest.NewServer(router)
+}
+
+func TestApiGetServices(t *testing.T) {
+	ts := getTestServicesServer(t)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/services")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+	services := []*shipyard.SwarmService{}
+	if err := json.NewDecoder(res.Body).Decode(&services); err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, services, 1) {
+		assert.Equal(t, services[0].Spec.Name, mock_test.TestService.Spec.Name)
+		assert.Equal(t, services[0].Version.Index, uint64(10))
+	}
+}
+
+func TestApiGetServiceUnknown(t *testing.T) {
+	ts := getTestServicesServer(t)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/services/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 404, "expected response code 404")
+}
+
+func TestApiCreateService(t *testing.T) {
+	ts := getTestServicesServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/services", "application/json", bytes.NewBufferString(`{"Name": "web", "TaskTemplate": {"ContainerSpec": {"Image": "nginx"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, http.StatusCreated, "expected response code 201")
+	created := map[string]string{}
+	if err := json.NewDecoder(res.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, created["ID"], "service1")
+}
+
+func TestApiCreateServiceConflict(t *testing.T) {
+	ts := getTestServicesServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/services", "application/json", bytes.NewBufferString(`{"Name": "test-service"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, http.StatusConflict, "expected engine status to be kept")
+}
+
+func TestApiUpdateService(t *testing.T) {
+	ts := getTestServicesServer(t)
+	defer ts.Close()
+
+	// the current version of the service is used without a version param
+	res, err := http.Post(ts.URL+"/api/services/test-service/update", "application/json", bytes.NewBufferString(`{"Name": "test-service"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, res.StatusCode, 204, "expected response code 204")
+
+	res, err = http.Post(ts.URL+"/api/services/service0/update?version=x", "application/json", bytes.NewBufferString(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, res.StatusCode, 400, "expected response code 400")
+}
+
+func TestApiRemoveServiceUnknown(t *testing.T) {
+	ts := getTestServicesServer(t)
+	defer ts.Close()
+
+	req, err := http.NewRequest("DELETE", ts.URL+"/api/services/unknown", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 404, "expected response code 404")
+}
+
+func TestApiGetServiceTasks(t *testing.T) {
+	ts := getTestServicesServer(t)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/services/service0/tasks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+	tasks := []*shipyard.SwarmTask{}
+	if err := json.NewDecoder(res.Body).Decode(&tasks); err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, tasks[0].Status.State, "running")
+	}
+
+	res, err = http.Get(ts.URL + "/api/services/unknown/tasks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, res.StatusCode, 404, "expected response code 404")
+}
+
+func TestApiGetTask(t *testing.T) {
+	ts := getTestServicesServer(t)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/tasks/" + mock_test.TestTask.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+
+	res, err = http.Get(ts.URL + "/api/tasks/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, res.StatusCode, 404, "expected response code 404")
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/manager"
+)
+
+// storageRegistry returns the registry of the request or writes the error
+func (a *Api) storageRegistry(w http.ResponseWriter, r *http.Request) (*shipyard.Registry, bool) {
+	reg, err := a.manager.Registry(mux.Vars(r)["name"])
+	if err != nil {
+		if err == manager.ErrRegistryDoesNotExist {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return reg, true
+}
+
+func (a *Api) registryStorage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	reg, ok := a.storageRegistry(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := a.manager.StorageReport(reg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) repositoryStorage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	reg, ok := a.storageRegistry(w, r)
+	if !ok {
+		return
+	}
+
+	rs, err := a.manager.RepositoryStorage(reg, mux.Vars(r)["repo"])
+	if err != nil {
+		if err == manager.ErrRepositoryDoesNotExist {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(rs); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// registryStorageHistory returns the storage snapshots of the registry.  The
+// since parameter limits the history to a duration (i.e. "24h").
+func (a *Api) registryStorageHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	since := time.Time{}
+	if s := r.URL.Query().Get("since"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			http.Error(w, "invalid since duration", http.StatusBadRequest)
+			return
+		}
+
+		since = time.Now().Add(-d)
+	}
+
+	reg, ok := a.storageRegistry(w, r)
+	if !ok {
+		return
+	}
+
+	history, err := a.manager.StorageHistory(reg, since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(history); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApiRepositoryStorage(t *testing.T) {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/registries/{name}/repositories/{repo:.+}/storage", api.repositoryStorage)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/registries/test-registry/repositories/app/storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+	rs := &shipyard.RepositoryStorage{}
+	if err := json.NewDecoder(res.Body).Decode(rs); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, rs.Repository, "library/app", "expected library/app")
+	assert.Equal(t, len(rs.Tags), 2, "expected 2 tags")
+
+	res, err = http.Get(ts.URL + "/api/registries/test-registry/repositories/unknown/storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 404, "expected response code 404")
+}
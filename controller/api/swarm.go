@@ -2,11 +2,32 @@ package api
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/manager"
 )
 
 func (a *Api) swarmRedirect(w http.ResponseWriter, req *http.Request) {
+	if err := a.injectRegistryAuth(req); err != nil {
+		log.Errorf("error injecting registry auth: %s", err)
+	}
+
+	// containers must not be created on nodes in maintenance
+	if err := a.injectPlacementConstraints(req); err != nil {
+		log.Errorf("error injecting placement constraints: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	var err error
 	req.URL, err = url.ParseRequestURI(a.dUrl)
 	if err != nil {
@@ -16,6 +37,200 @@ func (a *Api) swarmRedirect(w http.ResponseWriter, req *http.Request) {
 	a.fwd.ServeHTTP(w, req)
 }
 
+// proxy forwards a docker api request to swarm.  Endpoints of unknown
+// resources are only forwarded if the proxy policy allows them.
+func (a *Api) proxy(w http.ResponseWriter, req *http.Request) {
+	_, path := shipyard.SplitAPIVersion(req.URL.Path)
+	if shipyard.ProxyResource(path) == "" && a.proxyPolicy != shipyard.ProxyPolicyAllow {
+		log.Warnf("unknown docker api endpoint denied: method=%s path=%s", req.Method, req.URL.Path)
+		http.Error(w, "endpoint denied by proxy policy", http.StatusForbidden)
+		return
+	}
+
+	if err := a.admitProxyNetwork(req); err != nil {
+		writeNetworkError(w, err)
+		return
+	}
+
+	a.swarmRedirect(w, req)
+}
+
+// admitProxyNetwork runs the admission checks for networks created through
+// the proxy
+func (a *Api) admitProxyNetwork(req *http.Request) error {
+	if req.Method != "POST" || !strings.HasSuffix(req.URL.Path, "/networks/create") || req.Body == nil {
+		return nil
+	}
+
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	config := &dockerclient.NetworkCreate{}
+	if err := json.Unmarshal(data, config); err != nil {
+		log.Debugf("invalid network configuration: %s", err)
+		return manager.ErrNetworkInvalid
+	}
+
+	return a.manager.AdmitNetwork(config, currentUsername(req))
+}
+
+// emptyRegistryAuth reports whether the X-Registry-Auth header has no
+// credentials.  The docker cli sends "e30=" ("{}") for registries it has no
+// login for.
+func emptyRegistryAuth(header string) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return true
+	}
+
+	data, err := base64.URLEncoding.DecodeString(header)
+	if err != nil {
+		if data, err = base64.StdEncoding.DecodeString(header); err != nil {
+			return false
+		}
+	}
+
+	var config map[string]interface{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return false
+	}
+
+	for _, v := range config {
+		if v != nil && v != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// injectRegistryAuth adds the stored registry credentials to image pulls
+// unless the client already sent credentials.  Requests without a user
+// (i.e. with a service key) must send their own credentials.
+func (a *Api) injectRegistryAuth(req *http.Request) error {
+	if req.Method != "POST" || !emptyRegistryAuth(req.Header.Get("X-Registry-Auth")) {
+		return nil
+	}
+
+	image := ""
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/images/create"):
+		image = req.URL.Query().Get("fromImage")
+	case strings.HasSuffix(req.URL.Path, "/containers/create"):
+		if req.Body == nil {
+			return nil
+		}
+
+		data, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+		var config struct {
+			Image string
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return err
+		}
+		image = config.Image
+	default:
+		return nil
+	}
+
+	if image == "" {
+		return nil
+	}
+
+	username := currentUsername(req)
+	if username == "" {
+		return nil
+	}
+
+	authConfig, err := a.manager.RegistryAuthConfig(username, image)
+	if err != nil {
+		return err
+	}
+
+	if authConfig == nil {
+		return nil
+	}
+
+	buf, err := json.Marshal(authConfig)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("using stored registry credentials: image=%s username=%s", image, username)
+	req.Header.Set("X-Registry-Auth", base64.URLEncoding.EncodeToString(buf))
+
+	return nil
+}
+
+// injectPlacementConstraints keeps containers created through the proxy
+// off the nodes in maintenance
+func (a *Api) injectPlacementConstraints(req *http.Request) error {
+	if req.Method != "POST" || !strings.HasSuffix(req.URL.Path, "/containers/create") || req.Body == nil {
+		return nil
+	}
+
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	// only the environment and labels are changed so that unknown fields
+	// pass through
+	// malformed configs are passed on for the engine to reject
+	config := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil
+	}
+
+	placed := &dockerclient.ContainerConfig{}
+	for key, v := range map[string]interface{}{"Env": &placed.Env, "Labels": &placed.Labels} {
+		if raw, ok := config[key]; ok {
+			if err := json.Unmarshal(raw, v); err != nil {
+				return nil
+			}
+		}
+	}
+
+	changed, err := a.manager.PlaceContainer(placed)
+	if err != nil {
+		return err
+	}
+
+	if !changed {
+		return nil
+	}
+
+	for key, v := range map[string]interface{}{"Env": placed.Env, "Labels": placed.Labels} {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		config[key] = raw
+	}
+
+	if data, err = json.Marshal(config); err != nil {
+		return err
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Length", strconv.Itoa(len(data)))
+
+	return nil
+}
+
 type proxyWriter struct {
 	Body       *bytes.Buffer
 	Headers    *map[string][]string
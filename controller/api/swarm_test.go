@@ -0,0 +1,300 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/mailgun/oxy/forward"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/mock_test"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeRegistryAuth(t *testing.T, req *http.Request) *dockerclient.AuthConfig {
+	header := req.Header.Get("X-Registry-Auth")
+	if header == "" {
+		return nil
+	}
+
+	data, err := base64.URLEncoding.DecodeString(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authConfig := &dockerclient.AuthConfig{}
+	if err := json.Unmarshal(data, authConfig); err != nil {
+		t.Fatal(err)
+	}
+
+	return authConfig
+}
+
+func TestApiInjectRegistryAuthImageCreate(t *testing.T) {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", "/v1.20/images/create?fromImage=localhost:5000/app&tag=latest", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Access-Token", "testuser:token")
+	// the docker cli sends empty credentials ("{}") without a login
+	req.Header.Set("X-Registry-Auth", "e30=")
+
+	if err := api.injectRegistryAuth(req); err != nil {
+		t.Fatal(err)
+	}
+
+	authConfig := decodeRegistryAuth(t, req)
+	if authConfig == nil {
+		t.Fatal("expected registry auth header")
+	}
+
+	assert.Equal(t, authConfig.Username, mock_test.TestRegistryAuthConfig.Username, "expected stored registry username")
+}
+
+func TestApiInjectRegistryAuthContainerCreate(t *testing.T) {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := []byte(`{"Image": "localhost:5000/app:1.0"}`)
+	req, err := http.NewRequest("POST", "/containers/create", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Access-Token", "testuser:token")
+
+	if err := api.injectRegistryAuth(req); err != nil {
+		t.Fatal(err)
+	}
+
+	authConfig := decodeRegistryAuth(t, req)
+	if authConfig == nil {
+		t.Fatal("expected registry auth header")
+	}
+
+	assert.Equal(t, authConfig.Password, mock_test.TestRegistryAuthConfig.Password, "expected stored registry password")
+
+	// the body must still be readable by the proxy
+	buf := new(bytes.Buffer)
+	if _, err := buf.ReadFrom(req.Body); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, buf.Bytes(), body, "expected original request body")
+}
+
+func TestApiInjectRegistryAuthServiceKey(t *testing.T) {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", "/images/create?fromImage=localhost:5000/app", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := api.injectRegistryAuth(req); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, req.Header.Get("X-Registry-Auth"), "", "expected no stored credentials without a user")
+}
+
+func TestEmptyRegistryAuth(t *testing.T) {
+	for header, expected := range map[string]bool{
+		"":     true,
+		"e30=": true,
+		"e30":  false,
+		base64.URLEncoding.EncodeToString([]byte(`{"username": "", "password": ""}`)):           true,
+		base64.URLEncoding.EncodeToString([]byte(`{"username": "user", "password": "secret"}`)): false,
+	} {
+		assert.Equal(t, emptyRegistryAuth(header), expected, "unexpected result for "+header)
+	}
+}
+
+func TestApiInjectRegistryAuthUnknownRegistry(t *testing.T) {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", "/images/create?fromImage=nginx", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := api.injectRegistryAuth(req); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, req.Header.Get("X-Registry-Auth"), "", "expected no registry auth header")
+}
+
+func TestApiInjectPlacementConstraints(t *testing.T) {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"Image": "nginx", "Env": ["A=1"], "Labels": {"app": "web"}, "Memory": 1073741824, "Custom": {"x": true}}`
+	req, err := http.NewRequest("POST", "/v1.20/containers/create?name=web", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := api.injectPlacementConstraints(req); err != nil {
+		t.Fatal(err)
+	}
+
+	config := map[string]json.RawMessage{}
+	if err := json.NewDecoder(req.Body).Decode(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	env := []string{}
+	if err := json.Unmarshal(config["Env"], &env); err != nil {
+		t.Fatal(err)
+	}
+
+	labels := map[string]string{}
+	if err := json.Unmarshal(config["Labels"], &labels); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, env, []string{"A=1"}, "expected the environment to be kept")
+	assert.Equal(t, labels, map[string]string{"app": "web", "com.docker.swarm.constraints": `["node!=/^` + mock_test.TestNode.Name + `$/"]`})
+	assert.Equal(t, string(config["Memory"]), "1073741824", "expected other fields to be kept")
+	assert.Equal(t, string(config["Custom"]), `{"x":true}`, "expected unknown fields to be kept")
+}
+
+func getTestProxyApi(t *testing.T, policy string, engine *httptest.Server) *Api {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api.proxyPolicy = policy
+	api.dUrl = engine.URL
+	if api.fwd, err = forward.New(); err != nil {
+		t.Fatal(err)
+	}
+
+	return api
+}
+
+func TestApiProxy(t *testing.T) {
+	paths := []string{}
+	engine := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+	defer engine.Close()
+
+	for policy, expected := range map[string]int{
+		shipyard.ProxyPolicyDeny:  http.StatusForbidden,
+		shipyard.ProxyPolicyAllow: http.StatusOK,
+	} {
+		ts := httptest.NewServer(http.HandlerFunc(getTestProxyApi(t, policy, engine).proxy))
+
+		res, err := http.Post(ts.URL+"/v1.26/containers/prune", "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, res.StatusCode, http.StatusOK, "expected known endpoint to be forwarded")
+
+		res, err = http.Get(ts.URL + "/v1.30/unknown/endpoint")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, res.StatusCode, expected, "unexpected response code for unknown endpoint with policy "+policy)
+
+		ts.Close()
+	}
+
+	sort.Strings(paths)
+	assert.Equal(t, paths, []string{"/v1.26/containers/prune", "/v1.26/containers/prune", "/v1.30/unknown/endpoint"}, "expected the api version to be kept")
+}
+
+func TestApiProxyNetworkAdmission(t *testing.T) {
+	paths := []string{}
+	engine := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+	defer engine.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(getTestProxyApi(t, shipyard.ProxyPolicyDeny, engine).proxy))
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/v1.24/networks/create", "application/json", bytes.NewBufferString(`{"Name": "public", "Driver": "overlay"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, res.StatusCode, http.StatusForbidden, "expected network to be denied by admission")
+
+	// classic swarm creates overlay networks without a driver
+	res, err = http.Post(ts.URL+"/v1.24/networks/create", "application/json", bytes.NewBufferString(`{"Name": "public"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, res.StatusCode, http.StatusForbidden, "expected network without driver to be denied by admission")
+
+	res, err = http.Post(ts.URL+"/v1.24/networks/create", "application/json", bytes.NewBufferString(`{"Name": `))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, res.StatusCode, http.StatusBadRequest, "expected malformed network to be rejected")
+
+	res, err = http.Post(ts.URL+"/v1.24/networks/create", "application/json", bytes.NewBufferString(`{"Name": "backend", "Driver": "overlay", "Internal": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, res.StatusCode, http.StatusOK, "expected internal network to be forwarded")
+
+	assert.Equal(t, paths, []string{"/v1.24/networks/create"}, "expected only the admitted network to be forwarded")
+}
+
+func TestNewApiProxyPolicy(t *testing.T) {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, api.proxyPolicy, shipyard.ProxyPolicyDeny, "expected unknown endpoints to be denied by default")
+
+	if _, err := NewApi(ApiConfig{ProxyPolicy: "maybe"}); err == nil {
+		t.Fatal("expected error for invalid proxy policy")
+	}
+}
+
+func TestProxyClassification(t *testing.T) {
+	for path, expected := range map[string][2]string{
+		"/v1.24/containers/json":  {"1.24", shipyard.ResourceContainers},
+		"/v1.26/exec/abc/start":   {"1.26", shipyard.ResourceContainers},
+		"/v1.30/distribution/x":   {"1.30", shipyard.ResourceImages},
+		"/volumes/prune":          {"", shipyard.ResourceVolumes},
+		"/v1.25/services/web":     {"1.25", shipyard.ResourceServices},
+		"/v1.24/_ping":            {"1.24", shipyard.ResourceSystem},
+		"/v1.31/unknown/endpoint": {"1.31", ""},
+		"/version":                {"", shipyard.ResourceSystem},
+		"/v2/containers/json":     {"2", shipyard.ResourceContainers},
+		"/vendor/containers/json": {"", ""},
+		"/api/containers/health":  {"", ""},
+	} {
+		version, rest := shipyard.SplitAPIVersion(path)
+		assert.Equal(t, version, expected[0], "unexpected version for "+path)
+		assert.Equal(t, shipyard.ProxyResource(rest), expected[1], "unexpected resource for "+path)
+	}
+
+	assert.True(t, shipyard.IsProxyPath("/v1.31/unknown"), "expected versioned path to be proxied")
+	assert.False(t, shipyard.IsProxyPath("/app/index.html"), "expected static file not to be proxied")
+}
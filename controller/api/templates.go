@@ -0,0 +1,163 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/manager"
+)
+
+func writeTemplateError(w http.ResponseWriter, err error) {
+	switch err.(type) {
+	case *manager.TemplateError:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch err {
+	case manager.ErrTemplateDoesNotExist:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case manager.ErrTemplateAccessDenied, manager.ErrTemplateDeployDenied:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func readTemplateDeployment(w http.ResponseWriter, r *http.Request) (*shipyard.TemplateDeployment, bool) {
+	deployment := &shipyard.TemplateDeployment{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(deployment); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+	}
+
+	return deployment, true
+}
+
+func (a *Api) templates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	templates, err := a.manager.Templates(currentUsername(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(templates); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// template returns the latest version of the template or the version
+// selected by the version param
+func (a *Api) template(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	version, err := intParam(r, "version", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t, err := a.manager.Template(mux.Vars(r)["name"], version, currentUsername(r))
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) templateVersions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	versions, err := a.manager.TemplateVersions(mux.Vars(r)["name"], currentUsername(r))
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(versions); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) saveTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	var t *shipyard.Template
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := a.manager.SaveTemplate(t, currentUsername(r)); err != nil {
+		switch err {
+		case manager.ErrTemplateAccessDenied:
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) removeTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := a.manager.RemoveTemplate(mux.Vars(r)["name"], currentUsername(r)); err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// renderTemplate returns the container config the deployment would create
+func (a *Api) renderTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	deployment, ok := readTemplateDeployment(w, r)
+	if !ok {
+		return
+	}
+
+	config, err := a.manager.RenderTemplate(mux.Vars(r)["name"], deployment, currentUsername(r))
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(config); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) deployTemplate(w http.ResponseWriter, r *http.Request) {
+	deployment, ok := readTemplateDeployment(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := a.manager.DeployTemplate(mux.Vars(r)["name"], deployment, currentUsername(r))
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	writeApplicationResult(w, result)
+}
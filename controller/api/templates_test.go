@@ -0,0 +1,93 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard/controller/mock_test"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestTemplatesServer(t *testing.T) *httptest.Server {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/templates", api.templates).Methods("GET")
+	router.HandleFunc("/api/templates/{name}", api.template).Methods("GET")
+	router.HandleFunc("/api/templates/{name}/render", api.renderTemplate).Methods("POST")
+	router.HandleFunc("/api/templates/{name}/deploy", api.deployTemplate).Methods("POST")
+
+	return httptest.NewServer(router)
+}
+
+func TestApiTemplate(t *testing.T) {
+	ts := getTestTemplatesServer(t)
+	defer ts.Close()
+
+	for path, code := range map[string]int{
+		"/api/templates":                         200,
+		"/api/templates/test-template":           200,
+		"/api/templates/test-template?version=2": 404,
+		"/api/templates/unknown":                 404,
+	} {
+		res, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, res.StatusCode, code, "unexpected response code for "+path)
+	}
+}
+
+func TestApiRenderTemplate(t *testing.T) {
+	ts := getTestTemplatesServer(t)
+	defer ts.Close()
+
+	body := bytes.NewBufferString(`{"variables": {"Version": "1.11"}}`)
+	res, err := http.Post(ts.URL+"/api/templates/"+mock_test.TestTemplate.Name+"/render", "application/json", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+
+	var config dockerclient.ContainerConfig
+	if err := json.NewDecoder(res.Body).Decode(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, config.Image, "nginx:1.11")
+}
+
+func TestApiDeployTemplateInvalidVariables(t *testing.T) {
+	ts := getTestTemplatesServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/templates/"+mock_test.TestTemplate.Name+"/deploy", "application/json", bytes.NewBufferString(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 400, "expected response code 400")
+}
+
+func TestApiDeployTemplate(t *testing.T) {
+	ts := getTestTemplatesServer(t)
+	defer ts.Close()
+
+	body := bytes.NewBufferString(`{"name": "web", "variables": {"Version": "1.11"}}`)
+	res, err := http.Post(ts.URL+"/api/templates/"+mock_test.TestTemplate.Name+"/deploy", "application/json", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/manager"
+)
+
+// progressWriter streams the progress of a rolling update or drain as a
+// JSON document per line
+type progressWriter struct {
+	w       http.ResponseWriter
+	enc     *json.Encoder
+	started bool
+}
+
+func newProgressWriter(w http.ResponseWriter) *progressWriter {
+	return &progressWriter{w: w, enc: json.NewEncoder(w)}
+}
+
+func (p *progressWriter) write(progress *shipyard.UpdateProgress) {
+	p.encode(progress)
+}
+
+func (p *progressWriter) writeDrain(progress *shipyard.DrainProgress) {
+	p.encode(progress)
+}
+
+func (p *progressWriter) encode(progress interface{}) {
+	if !p.started {
+		p.w.Header().Set("content-type", "application/json")
+		p.started = true
+	}
+
+	if err := p.enc.Encode(progress); err != nil {
+		log.Warnf("error writing progress: %s", err)
+		return
+	}
+
+	if f, ok := p.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func readRollingUpdate(w http.ResponseWriter, r *http.Request) (*shipyard.RollingUpdate, bool) {
+	update := &shipyard.RollingUpdate{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(update); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+	}
+
+	return update, true
+}
+
+// writeUpdateError reports an update which could not be started
+func writeUpdateError(w http.ResponseWriter, err error) {
+	switch err {
+	case manager.ErrGroupDoesNotExist:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+func (a *Api) updateApplication(w http.ResponseWriter, r *http.Request) {
+	app, ok := a.application(w, r)
+	if !ok {
+		return
+	}
+
+	update, ok := readRollingUpdate(w, r)
+	if !ok {
+		return
+	}
+
+	progress := newProgressWriter(w)
+	if _, err := a.manager.UpdateApplication(app, update, currentUsername(r), progress.write); err != nil {
+		writeUpdateError(w, err)
+		return
+	}
+}
+
+func (a *Api) updateGroup(w http.ResponseWriter, r *http.Request) {
+	group := mux.Vars(r)["name"]
+
+	update, ok := readRollingUpdate(w, r)
+	if !ok {
+		return
+	}
+
+	progress := newProgressWriter(w)
+	if _, err := a.manager.UpdateGroup(group, update, currentUsername(r), progress.write); err != nil {
+		writeUpdateError(w, err)
+		return
+	}
+}
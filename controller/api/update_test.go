@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/mock_test"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestUpdateServer(t *testing.T) *httptest.Server {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/applications/{name}/update", api.updateApplication).Methods("POST")
+	router.HandleFunc("/api/groups/{name}/update", api.updateGroup).Methods("POST")
+
+	return httptest.NewServer(router)
+}
+
+func TestApiUpdateGroup(t *testing.T) {
+	ts := getTestUpdateServer(t)
+	defer ts.Close()
+
+	body, err := json.Marshal(&shipyard.RollingUpdate{Image: "nginx:1.11", BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := http.Post(ts.URL+"/api/groups/"+mock_test.TestContainerName+"/update", "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+
+	stages := []string{}
+	var last shipyard.UpdateProgress
+	dec := json.NewDecoder(res.Body)
+	for dec.More() {
+		if err := dec.Decode(&last); err != nil {
+			t.Fatal(err)
+		}
+		stages = append(stages, last.Stage)
+	}
+
+	assert.Equal(t, stages, []string{"start", "replaced", "complete"})
+	if assert.NotNil(t, last.Result) {
+		assert.Equal(t, last.Result.Image, "nginx:1.11")
+	}
+}
+
+func TestApiUpdateGroupUnknown(t *testing.T) {
+	ts := getTestUpdateServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/groups/unknown/update", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 404, "expected response code 404")
+}
+
+func TestApiUpdateApplicationInvalid(t *testing.T) {
+	ts := getTestUpdateServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/applications/"+mock_test.TestApplication.Name+"/update", "application/json", bytes.NewBufferString(`{"batch_size": -1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 400, "expected response code 400")
+}
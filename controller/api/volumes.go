@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/manager"
+)
+
+func writeVolumeError(w http.ResponseWriter, err error) {
+	switch err {
+	case manager.ErrVolumeDoesNotExist:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case manager.ErrVolumeInUse:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// volumeUsage reports whether the disk usage of volumes is requested
+func volumeUsage(r *http.Request) bool {
+	return r.FormValue("usage") == "true" || r.FormValue("usage") == "1"
+}
+
+// volumes returns the volumes of the cluster.  Only dangling volumes are
+// returned if the dangling param is true.  The disk usage is included if
+// the usage param is true.
+func (a *Api) volumes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	volumes, err := a.manager.Volumes(volumeUsage(r))
+	if err != nil {
+		writeVolumeError(w, err)
+		return
+	}
+
+	if r.FormValue("dangling") == "true" || r.FormValue("dangling") == "1" {
+		dangling := []*shipyard.Volume{}
+		for _, v := range volumes {
+			if v.Dangling {
+				dangling = append(dangling, v)
+			}
+		}
+		volumes = dangling
+	}
+
+	if err := json.NewEncoder(w).Encode(volumes); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) volume(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	v, err := a.manager.Volume(mux.Vars(r)["name"], volumeUsage(r))
+	if err != nil {
+		writeVolumeError(w, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) createVolume(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	request := &dockerclient.VolumeCreateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	v, err := a.manager.CreateVolume(request, currentUsername(r))
+	if err != nil {
+		writeVolumeError(w, err)
+		return
+	}
+
+	log.Infof("created volume: name=%s driver=%s", v.Name, v.Driver)
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (a *Api) removeVolume(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	if err := a.manager.RemoveVolume(name, currentUsername(r)); err != nil {
+		writeVolumeError(w, err)
+		return
+	}
+
+	log.Infof("removed volume: name=%s", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// cleanupVolumes removes the dangling volumes if the confirm param is true.
+// Otherwise the volumes which would be removed are returned.
+func (a *Api) cleanupVolumes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	confirm := r.FormValue("confirm") == "true" || r.FormValue("confirm") == "1"
+
+	cleanup, err := a.manager.CleanupVolumes(confirm, currentUsername(r))
+	if err != nil {
+		writeVolumeError(w, err)
+		return
+	}
+
+	if confirm {
+		log.Infof("cleaned up volumes: removed=%d errors=%d", len(cleanup.Volumes), len(cleanup.Errors))
+	}
+
+	if err := json.NewEncoder(w).Encode(cleanup); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/mock_test"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestVolumesServer(t *testing.T) *httptest.Server {
+	api, err := getTestApi()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/volumes", api.volumes).Methods("GET")
+	router.HandleFunc("/api/volumes", api.createVolume).Methods("POST")
+	router.HandleFunc("/api/volumes/cleanup", api.cleanupVolumes).Methods("POST")
+	router.HandleFunc("/api/volumes/{name:.*}", api.volume).Methods("GET")
+	router.HandleFunc("/api/volumes/{name:.*}", api.removeVolume).Methods("DELETE")
+
+	return httptest.NewServer(router)
+}
+
+func TestApiGetVolumes(t *testing.T) {
+	ts := getTestVolumesServer(t)
+	defer ts.Close()
+
+	for url, count := range map[string]int{"/api/volumes": 2, "/api/volumes?dangling=true": 1} {
+		res, err := http.Get(ts.URL + url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+		volumes := []*shipyard.Volume{}
+		if err := json.NewDecoder(res.Body).Decode(&volumes); err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, volumes, count, "unexpected volumes for "+url)
+	}
+}
+
+func TestApiGetVolume(t *testing.T) {
+	ts := getTestVolumesServer(t)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/volumes/" + mock_test.TestVolume.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+	v := &shipyard.Volume{}
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, v.Containers, []string{mock_test.TestContainerName})
+	assert.Equal(t, v.Size, int64(-1), "expected no usage unless requested")
+
+	res, err = http.Get(ts.URL + "/api/volumes/" + mock_test.TestVolume.Name + "?usage=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v = &shipyard.Volume{}
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, v.Size, int64(1024), "expected usage")
+
+	res, err = http.Get(ts.URL + "/api/volumes/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, res.StatusCode, 404, "expected response code 404")
+}
+
+func TestApiCreateVolume(t *testing.T) {
+	ts := getTestVolumesServer(t)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/volumes", "application/json", bytes.NewBufferString(`{"Name": "logs"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, res.StatusCode, http.StatusCreated, "expected response code 201")
+	v := &shipyard.Volume{}
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, v.Name, "logs")
+}
+
+func TestApiRemoveVolume(t *testing.T) {
+	ts := getTestVolumesServer(t)
+	defer ts.Close()
+
+	for name, status := range map[string]int{mock_test.TestVolume.Name: 409, "unknown": 404} {
+		req, err := http.NewRequest("DELETE", ts.URL+"/api/volumes/"+name, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, res.StatusCode, status, "unexpected response code for "+name)
+	}
+}
+
+func TestApiCleanupVolumes(t *testing.T) {
+	ts := getTestVolumesServer(t)
+	defer ts.Close()
+
+	for url, confirmed := range map[string]bool{"/api/volumes/cleanup": false, "/api/volumes/cleanup?confirm=true": true} {
+		res, err := http.Post(ts.URL+url, "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, res.StatusCode, 200, "expected response code 200")
+		cleanup := &shipyard.VolumeCleanup{}
+		if err := json.NewDecoder(res.Body).Decode(cleanup); err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, cleanup.Confirmed, confirmed, "unexpected confirmation for "+url)
+		assert.Equal(t, cleanup.Volumes, []string{"node1/cache"})
+	}
+}
@@ -23,6 +23,7 @@ func CmdServer(c *cli.Context) {
 	listenAddr := c.String("listen")
 	authWhitelist := c.StringSlice("auth-whitelist-cidr")
 	enableCors := c.Bool("enable-cors")
+	proxyPolicy := c.String("proxy-unknown-endpoints")
 	ldapServer := c.String("ldap-server")
 	ldapPort := c.Int("ldap-port")
 	ldapBaseDn := c.String("ldap-base-dn")
@@ -74,6 +75,7 @@ func CmdServer(c *cli.Context) {
 		TLSCACertPath:      shipyardTlsCACert,
 		TLSCertPath:        shipyardTlsCert,
 		TLSKeyPath:         shipyardTlsKey,
+		ProxyPolicy:        proxyPolicy,
 	}
 
 	shipyardApi, err := api.NewApi(apiConfig)
@@ -100,6 +100,11 @@ func main() {
 					Name:  "enable-cors",
 					Usage: "enable cors with swarm",
 				},
+				cli.StringFlag{
+					Name:  "proxy-unknown-endpoints",
+					Value: "deny",
+					Usage: "allow or deny docker api endpoints unknown to the proxy",
+				},
 				cli.StringFlag{
 					Name:  "ldap-server",
 					Usage: "LDAP server address",
@@ -0,0 +1,513 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/compose"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+const (
+	applicationStopTimeout = 10
+)
+
+var (
+	applicationNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+)
+
+// labelFilter returns the container list filter for the label.  An empty
+// value matches all containers with the label.
+func labelFilter(key, value string) string {
+	label := key
+	if value != "" {
+		label += "=" + value
+	}
+
+	filters, _ := json.Marshal(map[string][]string{
+		"label": {label},
+	})
+
+	return string(filters)
+}
+
+// containerName returns the name of the container without the node prefix
+// added by swarm
+func containerName(c dockerclient.Container) string {
+	if len(c.Names) == 0 {
+		return c.Id
+	}
+
+	return path.Base(c.Names[0])
+}
+
+func applicationStatus(containers []dockerclient.Container) *shipyard.ApplicationStatus {
+	status := &shipyard.ApplicationStatus{
+		Total: len(containers),
+	}
+
+	for _, c := range containers {
+		if strings.HasPrefix(c.Status, "Up") {
+			status.Running++
+		}
+
+		if strings.Contains(c.Status, "(unhealthy)") {
+			status.Unhealthy++
+		}
+	}
+
+	return status
+}
+
+// withDefaultTag adds the latest tag to image names without a tag or digest
+func withDefaultTag(image string) string {
+	if strings.Contains(image, "@") {
+		return image
+	}
+
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image
+	}
+
+	return image + ":latest"
+}
+
+func (m DefaultManager) logUserEvent(eventType, message, username string, tags []string) {
+	evt := &shipyard.Event{
+		Type:     eventType,
+		Time:     time.Now(),
+		Message:  message,
+		Username: username,
+		Tags:     tags,
+	}
+
+	if err := m.SaveEvent(evt); err != nil {
+		log.Errorf("error logging event: %s", err)
+	}
+}
+
+// createContainer creates the container off the nodes in maintenance using
+// the stored registry credentials and pulls the image first if the engine
+// does not have it
+func (m DefaultManager) createContainer(config *dockerclient.ContainerConfig, name, username string) (string, error) {
+	nodes, err := m.maintenanceNodeNames()
+	if err != nil {
+		return "", err
+	}
+	addMaintenanceConstraints(config, nodes)
+
+	authConfig, err := m.RegistryAuthConfig(username, config.Image)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := m.client.CreateContainer(config, name, authConfig)
+	if err != dockerclient.ErrImageNotFound {
+		return id, err
+	}
+
+	log.Debugf("pulling image: image=%s", config.Image)
+	if err := m.client.PullImage(withDefaultTag(config.Image), authConfig); err != nil {
+		return "", err
+	}
+
+	return m.client.CreateContainer(config, name, authConfig)
+}
+
+// Applications returns the deployed applications and the applications
+// formed by containers labeled with an application name
+func (m DefaultManager) Applications() ([]*shipyard.Application, error) {
+	res, err := r.Table(tblNameApplications).OrderBy(r.Asc("name")).Run(m.session)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := []*shipyard.Application{}
+	if err := res.All(&stored); err != nil {
+		return nil, err
+	}
+
+	containers, err := m.client.ListContainers(true, false, labelFilter(shipyard.ApplicationLabel, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string][]dockerclient.Container{}
+	for _, c := range containers {
+		name := c.Labels[shipyard.ApplicationLabel]
+		groups[name] = append(groups[name], c)
+	}
+
+	apps := []*shipyard.Application{}
+	for _, app := range stored {
+		app.Containers = groups[app.Name]
+		delete(groups, app.Name)
+		apps = append(apps, app)
+	}
+
+	for name, c := range groups {
+		apps = append(apps, &shipyard.Application{
+			Name:       name,
+			Containers: c,
+		})
+	}
+
+	for _, app := range apps {
+		if app.Containers == nil {
+			app.Containers = []dockerclient.Container{}
+		}
+		app.Status = applicationStatus(app.Containers)
+	}
+
+	sort.Sort(applicationsByName(apps))
+
+	return apps, nil
+}
+
+type applicationsByName []*shipyard.Application
+
+func (a applicationsByName) Len() int           { return len(a) }
+func (a applicationsByName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a applicationsByName) Less(i, j int) bool { return a[i].Name < a[j].Name }
+
+// Application returns the application including its containers.  Labeled
+// containers form an application even if it was not deployed from a
+// compose definition.
+func (m DefaultManager) Application(name string) (*shipyard.Application, error) {
+	res, err := r.Table(tblNameApplications).Filter(map[string]string{"name": name}).Run(m.session)
+	if err != nil {
+		return nil, err
+	}
+
+	app := &shipyard.Application{
+		Name: name,
+	}
+
+	stored := !res.IsNil()
+	if stored {
+		if err := res.One(&app); err != nil {
+			return nil, err
+		}
+	}
+
+	containers, err := m.applicationContainers(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !stored && len(containers) == 0 {
+		return nil, ErrApplicationDoesNotExist
+	}
+
+	app.Containers = containers
+	app.Status = applicationStatus(containers)
+
+	return app, nil
+}
+
+func (m DefaultManager) applicationContainers(name string) ([]dockerclient.Container, error) {
+	return m.client.ListContainers(true, false, labelFilter(shipyard.ApplicationLabel, name))
+}
+
+// CreateApplication stores the application and deploys its compose
+// definition
+func (m DefaultManager) CreateApplication(app *shipyard.Application, username string) error {
+	if !applicationNameRegexp.MatchString(app.Name) {
+		return fmt.Errorf("invalid application name: %s", app.Name)
+	}
+
+	if _, err := m.Application(app.Name); err == nil {
+		// also refuses names of applications formed by labeled containers
+		return ErrApplicationExists
+	} else if err != ErrApplicationDoesNotExist {
+		return err
+	}
+
+	project, err := compose.Load([]byte(app.Definition))
+	if err != nil {
+		return err
+	}
+
+	services, err := project.Order()
+	if err != nil {
+		return err
+	}
+
+	defer applicationOperations.begin(app.Name)()
+
+	app.ID = ""
+	app.Services = services
+	app.Username = username
+	app.Created = time.Now()
+	app.Updated = app.Created
+
+	res, err := r.Table(tblNameApplications).Insert(app).RunWrite(m.session)
+	if err != nil {
+		return err
+	}
+
+	if len(res.GeneratedKeys) > 0 {
+		app.ID = res.GeneratedKeys[0]
+	}
+
+	// the reconciler needs the revision of the deployed application
+	err = m.deployApplication(app, project, username)
+	if err == nil {
+		err = m.recordApplicationRevision(app, "deploy", username)
+	}
+
+	if err != nil {
+		// remove what was deployed so that creating the application can
+		// be retried
+		if result := m.removeApplicationContainers(app); len(result.Errors) > 0 {
+			log.Errorf("error removing containers of failed application: name=%s err=%s", app.Name, strings.Join(result.Errors, "; "))
+		}
+
+		if _, derr := r.Table(tblNameApplications).Get(app.ID).Delete().RunWrite(m.session); derr != nil {
+			log.Errorf("error removing failed application: name=%s err=%s", app.Name, derr)
+		}
+		app.ID = ""
+
+		return err
+	}
+
+	m.logUserEvent("deploy-application", fmt.Sprintf("name=%s services=%s", app.Name, strings.Join(services, ",")), username, []string{"applications"})
+
+	return nil
+}
+
+// deployApplication creates and starts the containers of the services in
+// dependency order
+func (m DefaultManager) deployApplication(app *shipyard.Application, project *compose.Project, username string) error {
+	for name, v := range project.Volumes {
+		volumeName := project.VolumeName(app.Name, name)
+		if volumeName == name {
+			// external volume
+			continue
+		}
+
+		if _, err := m.client.CreateVolume(&dockerclient.VolumeCreateRequest{
+			Name:       volumeName,
+			Driver:     v.Driver,
+			DriverOpts: v.DriverOpts,
+			Labels:     map[string]string{shipyard.ApplicationLabel: app.Name},
+		}); err != nil {
+			return fmt.Errorf("error creating volume %s: %s", volumeName, err)
+		}
+	}
+
+	for _, service := range app.Services {
+		config, err := project.ContainerConfig(app.Name, service)
+		if err != nil {
+			return err
+		}
+
+		config.Labels[shipyard.ApplicationLabel] = app.Name
+		config.Labels[shipyard.ServiceLabel] = service
+
+		name := project.ContainerName(app.Name, service)
+		id, err := m.createContainer(config, name, username)
+		if err != nil {
+			return fmt.Errorf("error creating service %s: %s", service, err)
+		}
+
+		if err := m.client.StartContainer(id, &config.HostConfig); err != nil {
+			return fmt.Errorf("error starting service %s: %s", service, err)
+		}
+
+		log.Debugf("deployed application service: app=%s service=%s id=%s", app.Name, service, id)
+	}
+
+	return nil
+}
+
+// serviceContainers returns the containers of the application ordered by
+// service dependency order.  Containers of unknown services come last.
+func (m DefaultManager) serviceContainers(app *shipyard.Application) ([]dockerclient.Container, error) {
+	containers, err := m.applicationContainers(app.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return orderContainers(containers, app.Services), nil
+}
+
+func orderContainers(containers []dockerclient.Container, services []string) []dockerclient.Container {
+	ordered := []dockerclient.Container{}
+	seen := map[string]bool{}
+	for _, service := range services {
+		for _, c := range containers {
+			if c.Labels[shipyard.ServiceLabel] == service && !seen[c.Id] {
+				seen[c.Id] = true
+				ordered = append(ordered, c)
+			}
+		}
+	}
+
+	rest := []dockerclient.Container{}
+	for _, c := range containers {
+		if !seen[c.Id] {
+			rest = append(rest, c)
+		}
+	}
+	sort.Sort(containersByName(rest))
+
+	return append(ordered, rest...)
+}
+
+type containersByName []dockerclient.Container
+
+func (c containersByName) Len() int           { return len(c) }
+func (c containersByName) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c containersByName) Less(i, j int) bool { return containerName(c[i]) < containerName(c[j]) }
+
+// applyToContainers runs op for every container of the application in
+// dependency order or in reverse dependency order.  Failures are reported
+// per container and do not stop the operation.
+func (m DefaultManager) applyToContainers(app *shipyard.Application, reverse bool, op func(c dockerclient.Container) error) ApplicationResult {
+	result := ApplicationResult{Containers: make([]string, 0), Errors: make([]string, 0)}
+
+	containers, err := m.serviceContainers(app)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	for i := range containers {
+		c := containers[i]
+		if reverse {
+			c = containers[len(containers)-1-i]
+		}
+
+		if err := op(c); err != nil {
+			log.Errorf("application operation failed: app=%s container=%s err=%s", app.Name, containerName(c), err)
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", containerName(c), strings.TrimSpace(err.Error())))
+			continue
+		}
+
+		result.Containers = append(result.Containers, c.Id)
+	}
+
+	return result
+}
+
+func (m DefaultManager) StartApplication(app *shipyard.Application, username string) ApplicationResult {
+	result := m.applyToContainers(app, false, func(c dockerclient.Container) error {
+		return m.client.StartContainer(c.Id, nil)
+	})
+
+	m.logUserEvent("start-application", fmt.Sprintf("name=%s containers=%d errors=%d", app.Name, len(result.Containers), len(result.Errors)), username, []string{"applications"})
+
+	return result
+}
+
+// StopApplication stops the containers in reverse dependency order
+func (m DefaultManager) StopApplication(app *shipyard.Application, username string) ApplicationResult {
+	result := m.applyToContainers(app, true, func(c dockerclient.Container) error {
+		return m.client.StopContainer(c.Id, applicationStopTimeout)
+	})
+
+	m.logUserEvent("stop-application", fmt.Sprintf("name=%s containers=%d errors=%d", app.Name, len(result.Containers), len(result.Errors)), username, []string{"applications"})
+
+	return result
+}
+
+func (m DefaultManager) RestartApplication(app *shipyard.Application, username string) ApplicationResult {
+	result := m.applyToContainers(app, false, func(c dockerclient.Container) error {
+		return m.client.RestartContainer(c.Id, applicationStopTimeout)
+	})
+
+	m.logUserEvent("restart-application", fmt.Sprintf("name=%s containers=%d errors=%d", app.Name, len(result.Containers), len(result.Errors)), username, []string{"applications"})
+
+	return result
+}
+
+func (m DefaultManager) removeApplicationContainers(app *shipyard.Application) ApplicationResult {
+	return m.applyToContainers(app, true, func(c dockerclient.Container) error {
+		return m.client.RemoveContainer(c.Id, true, false)
+	})
+}
+
+// RedeployApplication recreates the containers of the application.  A non
+// empty definition replaces the compose definition of the application.
+func (m DefaultManager) RedeployApplication(app *shipyard.Application, definition, username string) error {
+	if definition == "" {
+		definition = app.Definition
+	}
+
+	if app.ID == "" || definition == "" {
+		return ErrApplicationNotDeployed
+	}
+
+	project, err := compose.Load([]byte(definition))
+	if err != nil {
+		return err
+	}
+
+	services, err := project.Order()
+	if err != nil {
+		return err
+	}
+
+	defer applicationOperations.begin(app.Name)()
+
+	if result := m.removeApplicationContainers(app); len(result.Errors) > 0 {
+		return fmt.Errorf("error removing containers: %s", strings.Join(result.Errors, "; "))
+	}
+
+	app.Definition = definition
+	app.Services = services
+	app.Updated = time.Now()
+
+	if _, err := r.Table(tblNameApplications).Get(app.ID).Update(map[string]interface{}{
+		"definition": app.Definition,
+		"services":   app.Services,
+		"updated":    app.Updated,
+	}).RunWrite(m.session); err != nil {
+		return err
+	}
+
+	if err := m.deployApplication(app, project, username); err != nil {
+		return err
+	}
+
+	if err := m.recordApplicationRevision(app, "redeploy", username); err != nil {
+		return err
+	}
+
+	m.logUserEvent("redeploy-application", fmt.Sprintf("name=%s services=%s", app.Name, strings.Join(services, ",")), username, []string{"applications"})
+
+	return nil
+}
+
+// RemoveApplication removes the containers of the application.  Named
+// volumes are kept.  The stored application is only removed once all of
+// its containers are removed; until then its reconciliation is paused.
+func (m DefaultManager) RemoveApplication(app *shipyard.Application, username string) ApplicationResult {
+	defer applicationOperations.begin(app.Name)()
+
+	result := m.removeApplicationContainers(app)
+
+	if app.ID != "" {
+		if len(result.Errors) == 0 {
+			if _, err := r.Table(tblNameApplications).Get(app.ID).Delete().RunWrite(m.session); err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			}
+		} else if err := m.SetReconcilePaused(app, true, username); err != nil {
+			// keep the reconciler from recreating the removed containers
+			result.Errors = append(result.Errors, err.Error())
+		}
+	}
+
+	m.logUserEvent("remove-application", fmt.Sprintf("name=%s containers=%d errors=%d", app.Name, len(result.Containers), len(result.Errors)), username, []string{"applications"})
+
+	return result
+}
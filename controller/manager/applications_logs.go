@@ -0,0 +1,162 @@
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+const (
+	defaultApplicationLogTail = 100
+)
+
+type logLine struct {
+	stream string
+	text   string
+}
+
+// readLogLines splits a container log stream into lines.  The output of
+// containers without a tty is multiplexed in frames with an 8 byte header
+// holding the stream and the frame size.
+func readLogLines(r io.Reader, tty bool) ([]logLine, error) {
+	lines := []logLine{}
+
+	if tty {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines = append(lines, logLine{stream: "stdout", text: scanner.Text()})
+		}
+
+		return lines, scanner.Err()
+	}
+
+	buffers := map[string]*bytes.Buffer{
+		"stdout": {},
+		"stderr": {},
+	}
+
+	flush := func(stream string, all bool) {
+		buf := buffers[stream]
+		for {
+			i := bytes.IndexByte(buf.Bytes(), '\n')
+			if i == -1 {
+				break
+			}
+
+			lines = append(lines, logLine{stream: stream, text: string(buf.Next(i + 1)[:i])})
+		}
+
+		if all && buf.Len() > 0 {
+			lines = append(lines, logLine{stream: stream, text: buf.String()})
+			buf.Reset()
+		}
+	}
+
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		stream := "stdout"
+		if header[0] == 2 {
+			stream = "stderr"
+		}
+
+		size := binary.BigEndian.Uint32(header[4:])
+		if _, err := io.CopyN(buffers[stream], r, int64(size)); err != nil {
+			return nil, err
+		}
+
+		flush(stream, false)
+	}
+
+	flush("stdout", true)
+	flush("stderr", true)
+
+	return lines, nil
+}
+
+// parseLogTimestamp splits the timestamp added by the engine from the log
+// message
+func parseLogTimestamp(line string) (time.Time, string) {
+	parts := strings.SplitN(line, " ", 2)
+	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, line
+	}
+
+	if len(parts) == 1 {
+		return t, ""
+	}
+
+	return t, parts[1]
+}
+
+type logsByTime []*shipyard.ApplicationLog
+
+func (l logsByTime) Len() int           { return len(l) }
+func (l logsByTime) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l logsByTime) Less(i, j int) bool { return l[i].Time.Before(l[j].Time) }
+
+// ApplicationLogs returns the last log lines of every container of the
+// application merged by time
+func (m DefaultManager) ApplicationLogs(app *shipyard.Application, tail int) ([]*shipyard.ApplicationLog, error) {
+	if tail <= 0 {
+		tail = defaultApplicationLogTail
+	}
+
+	containers, err := m.serviceContainers(app)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := []*shipyard.ApplicationLog{}
+	for _, c := range containers {
+		info, err := m.client.InspectContainer(c.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		rc, err := m.client.ContainerLogs(c.Id, &dockerclient.LogOptions{
+			Stdout:     true,
+			Stderr:     true,
+			Timestamps: true,
+			Tail:       int64(tail),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		lines, err := readLogLines(rc, info.Config != nil && info.Config.Tty)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, l := range lines {
+			t, msg := parseLogTimestamp(l.text)
+			logs = append(logs, &shipyard.ApplicationLog{
+				Container: containerName(c),
+				Service:   c.Labels[shipyard.ServiceLabel],
+				Time:      t,
+				Stream:    l.stream,
+				Message:   msg,
+			})
+		}
+	}
+
+	sort.Stable(logsByTime(logs))
+
+	return logs, nil
+}
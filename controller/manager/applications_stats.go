@@ -0,0 +1,134 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+const (
+	statsTimeout = 5 * time.Second
+)
+
+// cpuPercent returns the cpu usage between two samples in percent of a
+// single core
+func cpuPercent(prev, cur *dockerclient.Stats) float64 {
+	cpuDelta := float64(cur.CpuStats.CpuUsage.TotalUsage) - float64(prev.CpuStats.CpuUsage.TotalUsage)
+	systemDelta := float64(cur.CpuStats.SystemUsage) - float64(prev.CpuStats.SystemUsage)
+
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+
+	return cpuDelta / systemDelta * float64(len(cur.CpuStats.CpuUsage.PercpuUsage)) * 100
+}
+
+// containerStats reads two samples from the stats stream of the container
+func (m DefaultManager) containerStats(id string) (*shipyard.ContainerStats, error) {
+	stop := make(chan struct{})
+	samples, err := m.client.ContainerStats(id, stop)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		close(stop)
+		// the stream goroutine blocks until its last sample is read
+		go func() {
+			for range samples {
+			}
+		}()
+	}()
+
+	var prev, cur *dockerclient.Stats
+	timeout := time.After(statsTimeout)
+	for cur == nil {
+		select {
+		case s, ok := <-samples:
+			if !ok {
+				return nil, fmt.Errorf("stats stream closed")
+			}
+			if s.Error != nil {
+				return nil, s.Error
+			}
+
+			stats := s.Stats
+			if prev == nil {
+				prev = &stats
+			} else {
+				cur = &stats
+			}
+		case <-timeout:
+			return nil, fmt.Errorf("timed out reading stats")
+		}
+	}
+
+	stats := &shipyard.ContainerStats{
+		CPUPercent:  cpuPercent(prev, cur),
+		MemoryUsage: cur.MemoryStats.Usage,
+		MemoryLimit: cur.MemoryStats.Limit,
+		NetworkRx:   cur.NetworkStats.RxBytes,
+		NetworkTx:   cur.NetworkStats.TxBytes,
+	}
+
+	return stats, nil
+}
+
+// ApplicationStats returns the resource usage of the running containers of
+// the application and their sum
+func (m DefaultManager) ApplicationStats(app *shipyard.Application) (*shipyard.ApplicationStats, error) {
+	containers, err := m.serviceContainers(app)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &shipyard.ApplicationStats{
+		Application: app.Name,
+		Containers:  []*shipyard.ContainerStats{},
+		Errors:      []string{},
+	}
+
+	running := []dockerclient.Container{}
+	for _, c := range containers {
+		if strings.HasPrefix(c.Status, "Up") {
+			running = append(running, c)
+		}
+	}
+
+	stats := make([]*shipyard.ContainerStats, len(running))
+	errs := make([]error, len(running))
+
+	var wg sync.WaitGroup
+	for i, c := range running {
+		wg.Add(1)
+		go func(i int, c dockerclient.Container) {
+			defer wg.Done()
+			stats[i], errs[i] = m.containerStats(c.Id)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for i, c := range running {
+		if errs[i] != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", containerName(c), errs[i]))
+			continue
+		}
+
+		s := stats[i]
+		s.Container = containerName(c)
+		s.Service = c.Labels[shipyard.ServiceLabel]
+
+		result.CPUPercent += s.CPUPercent
+		result.MemoryUsage += s.MemoryUsage
+		result.MemoryLimit += s.MemoryLimit
+		result.NetworkRx += s.NetworkRx
+		result.NetworkTx += s.NetworkTx
+		result.Containers = append(result.Containers, s)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,123 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+func TestWithDefaultTag(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                       "nginx:latest",
+		"nginx:1.9":                   "nginx:1.9",
+		"localhost:5000/app":          "localhost:5000/app:latest",
+		"localhost:5000/app:2.0":      "localhost:5000/app:2.0",
+		"app@sha256:0123456789abcdef": "app@sha256:0123456789abcdef",
+	}
+
+	for image, expected := range tests {
+		if s := withDefaultTag(image); s != expected {
+			t.Errorf("expected %s for %s; received %s", expected, image, s)
+		}
+	}
+}
+
+func TestLabelFilter(t *testing.T) {
+	expected := `{"label":["com.shipyard.application=blog"]}`
+	if s := labelFilter("com.shipyard.application", "blog"); s != expected {
+		t.Fatalf("expected %s; received %s", expected, s)
+	}
+}
+
+func TestApplicationStatus(t *testing.T) {
+	status := applicationStatus([]dockerclient.Container{
+		{Status: "Up 2 minutes"},
+		{Status: "Up 5 minutes (unhealthy)"},
+		{Status: "Exited (1) 3 minutes ago"},
+	})
+
+	if status.Total != 3 || status.Running != 2 || status.Unhealthy != 1 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+}
+
+func TestOrderContainers(t *testing.T) {
+	containers := []dockerclient.Container{
+		{Id: "1", Names: []string{"/node1/extra"}},
+		{Id: "2", Names: []string{"/node1/blog_web_1"}, Labels: map[string]string{shipyard.ServiceLabel: "web"}},
+		{Id: "3", Names: []string{"/node2/blog_db_1"}, Labels: map[string]string{shipyard.ServiceLabel: "db"}},
+		{Id: "4", Names: []string{"/node2/another"}},
+	}
+
+	ordered := orderContainers(containers, []string{"db", "web"})
+	ids := []string{}
+	for _, c := range ordered {
+		ids = append(ids, c.Id)
+	}
+
+	expected := []string{"3", "2", "4", "1"}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Fatalf("expected order %v; received %v", expected, ids)
+		}
+	}
+}
+
+func logFrame(stream byte, data string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(data)))
+	return append(header, data...)
+}
+
+func TestReadLogLines(t *testing.T) {
+	buf := &bytes.Buffer{}
+	buf.Write(logFrame(1, "2016-08-01T10:00:00.000000001Z first\n2016-08-01T10:00:02Z par"))
+	buf.Write(logFrame(2, "2016-08-01T10:00:01Z error\n"))
+	buf.Write(logFrame(1, "tial\n"))
+
+	lines, err := readLogLines(buf, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines; received %d", len(lines))
+	}
+
+	if lines[1].stream != "stderr" {
+		t.Fatalf("expected stderr line; received %s", lines[1].stream)
+	}
+
+	ts, msg := parseLogTimestamp(lines[2].text)
+	if msg != "partial" || ts.Second() != 2 {
+		t.Fatalf("unexpected line: %s %q", ts, msg)
+	}
+
+	lines, err = readLogLines(bytes.NewBufferString("a\nb\n"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lines) != 2 || lines[1].text != "b" {
+		t.Fatalf("unexpected tty lines: %v", lines)
+	}
+}
+
+func TestCPUPercent(t *testing.T) {
+	prev := &dockerclient.Stats{}
+	prev.CpuStats.CpuUsage.TotalUsage = 100
+	prev.CpuStats.SystemUsage = 1000
+
+	cur := &dockerclient.Stats{}
+	cur.CpuStats.CpuUsage.TotalUsage = 200
+	cur.CpuStats.CpuUsage.PercpuUsage = []uint64{0, 0}
+	cur.CpuStats.SystemUsage = 2000
+
+	if p := cpuPercent(prev, cur); p != 20 {
+		t.Fatalf("expected 20 percent; received %f", p)
+	}
+}
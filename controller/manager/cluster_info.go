@@ -0,0 +1,153 @@
+package manager
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+var (
+	clusterInfoInterval = 1 * time.Minute
+	// clusterInfoHistory is the number of samples kept (one hour)
+	clusterInfoHistory = 60
+
+	clusterInfoSamples = &clusterInfoStore{}
+)
+
+// clusterInfoStore holds the recent totals of the cluster
+type clusterInfoStore struct {
+	sync.Mutex
+	samples []*shipyard.ClusterInfoSample
+}
+
+func (s *clusterInfoStore) record(sample *shipyard.ClusterInfoSample) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.samples = append(s.samples, sample)
+	if len(s.samples) > clusterInfoHistory {
+		s.samples = s.samples[len(s.samples)-clusterInfoHistory:]
+	}
+}
+
+func (s *clusterInfoStore) history() []*shipyard.ClusterInfoSample {
+	s.Lock()
+	defer s.Unlock()
+
+	return append([]*shipyard.ClusterInfoSample{}, s.samples...)
+}
+
+// nodeHealthy reports whether swarm reports the node as available.  Classic
+// swarm reports Healthy and swarm mode ready.
+func nodeHealthy(n *shipyard.Node) bool {
+	return n.Status == "Healthy" || n.Status == "ready"
+}
+
+// buildClusterInfo aggregates the engine info and the nodes of the cluster.
+// Only healthy nodes count towards the totals.  The totals of the engine are
+// used if the nodes are unknown (i.e. a single engine).
+func buildClusterInfo(info *dockerclient.Info, nodes []*shipyard.Node) *shipyard.ClusterInfo {
+	clusterInfo := &shipyard.ClusterInfo{
+		ContainerCount: int(info.Containers),
+		ImageCount:     int(info.Images),
+		EngineCount:    len(nodes),
+		Nodes:          []*shipyard.ClusterNodeInfo{},
+	}
+
+	for _, n := range nodes {
+		containers, _ := strconv.Atoi(n.Containers)
+
+		clusterInfo.Nodes = append(clusterInfo.Nodes, &shipyard.ClusterNodeInfo{
+			Name:           n.Name,
+			Addr:           n.Addr,
+			Status:         n.Status,
+			Health:         n.Health,
+			Cpus:           n.CPUsTotal,
+			Memory:         float64(n.MemoryTotal),
+			ContainerCount: containers,
+			ReservedCpus:   n.CPUsReserved,
+			ReservedMemory: float64(n.MemoryReserved),
+		})
+
+		if !nodeHealthy(n) {
+			continue
+		}
+
+		clusterInfo.Cpus += n.CPUsTotal
+		clusterInfo.Memory += float64(n.MemoryTotal)
+		clusterInfo.ReservedCpus += n.CPUsReserved
+		clusterInfo.ReservedMemory += float64(n.MemoryReserved)
+	}
+
+	if len(nodes) == 0 {
+		clusterInfo.EngineCount = 1
+		clusterInfo.Cpus = float64(info.NCPU)
+		clusterInfo.Memory = float64(info.MemTotal)
+	}
+
+	return clusterInfo
+}
+
+func clusterInfoSample(info *shipyard.ClusterInfo, t time.Time) *shipyard.ClusterInfoSample {
+	return &shipyard.ClusterInfoSample{
+		Time:           t,
+		Cpus:           info.Cpus,
+		Memory:         info.Memory,
+		ContainerCount: info.ContainerCount,
+		EngineCount:    info.EngineCount,
+		ReservedCpus:   info.ReservedCpus,
+		ReservedMemory: info.ReservedMemory,
+	}
+}
+
+func (m DefaultManager) clusterInfo() (*shipyard.ClusterInfo, error) {
+	info, err := m.client.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	return buildClusterInfo(info, m.infoNodes(info)), nil
+}
+
+// ClusterInfo returns the capacity of the cluster with the breakdown per
+// node and the totals recorded in the last hour
+func (m DefaultManager) ClusterInfo() (*shipyard.ClusterInfo, error) {
+	clusterInfo, err := m.clusterInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	if version, err := m.client.Version(); err != nil {
+		log.Warnf("error getting cluster version: %s", err)
+	} else {
+		clusterInfo.Version = version.Version
+	}
+
+	clusterInfo.History = clusterInfoSamples.history()
+
+	return clusterInfo, nil
+}
+
+func (m DefaultManager) recordClusterInfo() {
+	clusterInfo, err := m.clusterInfo()
+	if err != nil {
+		log.Warnf("cluster info recorder: error getting cluster info: %s", err)
+		return
+	}
+
+	clusterInfoSamples.record(clusterInfoSample(clusterInfo, time.Now()))
+}
+
+// clusterInfoRecorder periodically records the totals of the cluster
+func (m DefaultManager) clusterInfoRecorder() {
+	m.recordClusterInfo()
+
+	t := time.NewTicker(clusterInfoInterval).C
+	for range t {
+		m.recordClusterInfo()
+	}
+}
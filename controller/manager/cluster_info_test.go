@@ -0,0 +1,94 @@
+package manager
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+func TestBuildClusterInfo(t *testing.T) {
+	info := &dockerclient.Info{Containers: 5, Images: 3, NCPU: 16, MemTotal: 1024}
+	nodes := []*shipyard.Node{
+		{Name: "node1", Status: "Healthy", Containers: "3", CPUsTotal: 4, CPUsReserved: 1, MemoryTotal: 4096, MemoryReserved: 1024, Health: NodeHealthUp},
+		{Name: "node2", Status: "ready", Containers: "2", CPUsTotal: 2, CPUsReserved: 0.5, MemoryTotal: 2048, MemoryReserved: 512},
+		{Name: "node3", Status: "Unhealthy", CPUsTotal: 8, CPUsReserved: 2, MemoryTotal: 8192, MemoryReserved: 2048},
+		{Name: "node4", Status: "Pending", CPUsTotal: 8, MemoryTotal: 8192},
+	}
+
+	clusterInfo := buildClusterInfo(info, nodes)
+
+	if clusterInfo.Cpus != 6 || clusterInfo.Memory != 6144 {
+		t.Fatalf("expected totals of the nodes; received cpus=%v memory=%v", clusterInfo.Cpus, clusterInfo.Memory)
+	}
+
+	if clusterInfo.ReservedCpus != 1.5 || clusterInfo.ReservedMemory != 1536 {
+		t.Fatalf("expected reservations of the nodes; received cpus=%v memory=%v", clusterInfo.ReservedCpus, clusterInfo.ReservedMemory)
+	}
+
+	if clusterInfo.ContainerCount != 5 || clusterInfo.ImageCount != 3 || clusterInfo.EngineCount != 4 {
+		t.Fatalf("unexpected counts: %+v", clusterInfo)
+	}
+
+	if len(clusterInfo.Nodes) != 4 || clusterInfo.Nodes[0].ContainerCount != 3 || clusterInfo.Nodes[0].Health != NodeHealthUp {
+		t.Fatalf("unexpected node breakdown: %+v", clusterInfo.Nodes)
+	}
+}
+
+func TestBuildClusterInfoSingleEngine(t *testing.T) {
+	clusterInfo := buildClusterInfo(&dockerclient.Info{Containers: 2, NCPU: 8, MemTotal: 1024}, []*shipyard.Node{})
+
+	if clusterInfo.EngineCount != 1 || clusterInfo.Cpus != 8 || clusterInfo.Memory != 1024 {
+		t.Fatalf("expected totals of the engine; received %+v", clusterInfo)
+	}
+}
+
+func TestClusterInfoStore(t *testing.T) {
+	s := &clusterInfoStore{}
+	now := time.Now()
+
+	for i := 0; i < clusterInfoHistory+5; i++ {
+		s.record(clusterInfoSample(&shipyard.ClusterInfo{ContainerCount: i}, now.Add(time.Duration(i)*time.Minute)))
+	}
+
+	history := s.history()
+	if len(history) != clusterInfoHistory {
+		t.Fatalf("expected %d samples; received %d", clusterInfoHistory, len(history))
+	}
+
+	if history[0].ContainerCount != 5 || history[len(history)-1].ContainerCount != clusterInfoHistory+4 {
+		t.Fatalf("expected the oldest samples to be dropped; received %d to %d", history[0].ContainerCount, history[len(history)-1].ContainerCount)
+	}
+}
+
+func TestClusterInfoNodes(t *testing.T) {
+	infoRequests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		infoRequests++
+		json.NewEncoder(w).Encode(map[string]interface{}{"Containers": 3, "DriverStatus": swarm12DriverStatus})
+	}))
+	defer ts.Close()
+
+	client, err := dockerclient.NewDockerClient(ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := DefaultManager{client: client}
+
+	clusterInfo, err := m.clusterInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if infoRequests != 1 {
+		t.Fatalf("expected a single info request; received %d", infoRequests)
+	}
+
+	if clusterInfo.ContainerCount != 3 || clusterInfo.EngineCount == 0 {
+		t.Fatalf("expected info and nodes of the cluster; received %+v", clusterInfo)
+	}
+}
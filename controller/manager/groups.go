@@ -0,0 +1,473 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+// containerNode returns the swarm node of a listed container.  Swarm
+// prefixes container names with the node name (i.e. "/node-1/web").
+func containerNode(c dockerclient.Container) string {
+	if len(c.Names) == 0 {
+		return ""
+	}
+
+	parts := strings.Split(strings.TrimPrefix(c.Names[0], "/"), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+
+	return parts[0]
+}
+
+// containerHealth ranks containers for removal: stopped containers first,
+// then unhealthy and then healthy containers
+func containerHealth(c dockerclient.Container) int {
+	switch {
+	case !strings.HasPrefix(c.Status, "Up"):
+		return 0
+	case strings.Contains(c.Status, "(unhealthy)"):
+		return 1
+	}
+
+	return 2
+}
+
+type containersByRemoval []dockerclient.Container
+
+func (c containersByRemoval) Len() int      { return len(c) }
+func (c containersByRemoval) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+
+func (c containersByRemoval) Less(i, j int) bool {
+	if hi, hj := containerHealth(c[i]), containerHealth(c[j]); hi != hj {
+		return hi < hj
+	}
+
+	return c[i].Created < c[j].Created
+}
+
+// removalOrder returns the containers sorted by the order they are removed
+// in when scaling down: least healthy first and oldest first
+func removalOrder(containers []dockerclient.Container) []dockerclient.Container {
+	sorted := make([]dockerclient.Container, len(containers))
+	copy(sorted, containers)
+	sort.Stable(containersByRemoval(sorted))
+
+	return sorted
+}
+
+// spreadNodes picks a node for each of n new replicas.  Nodes running the
+// fewest replicas are picked first.  Without nodes no node is picked.
+func spreadNodes(nodes []string, counts map[string]int, n int) []string {
+	picks := make([]string, n)
+	if len(nodes) == 0 {
+		return picks
+	}
+
+	c := map[string]int{}
+	for _, node := range nodes {
+		c[node] = counts[node]
+	}
+
+	sorted := append([]string{}, nodes...)
+	sort.Strings(sorted)
+
+	for i := 0; i < n; i++ {
+		best := sorted[0]
+		for _, node := range sorted[1:] {
+			if c[node] < c[best] {
+				best = node
+			}
+		}
+
+		picks[i] = best
+		c[best]++
+	}
+
+	return picks
+}
+
+// replicaName returns the first free name of the form group_n
+func replicaName(group string, used map[string]bool) string {
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("%s_%d", group, i)
+		if !used[name] {
+			return name
+		}
+	}
+}
+
+// swarmConstraintsLabel holds the constraints of classic swarm.  Unlike
+// constraint entries of the environment it is not visible to the
+// application.  Swarm moves the constraints of the environment there.
+const swarmConstraintsLabel = "com.docker.swarm.constraints"
+
+// setConstraints removes the swarm constraints for which drop returns true
+// from the environment and the constraints label of the config and adds the
+// constraints to the label.  The labels are copied so that configs sharing
+// them are left unchanged.
+func setConstraints(config *dockerclient.ContainerConfig, drop func(string) bool, add ...string) {
+	var env []string
+	for _, e := range config.Env {
+		if strings.HasPrefix(e, "constraint:") && drop(strings.TrimPrefix(e, "constraint:")) {
+			continue
+		}
+		env = append(env, e)
+	}
+	config.Env = env
+
+	constraints := []string{}
+	if v := config.Labels[swarmConstraintsLabel]; v != "" {
+		existing := []string{}
+		if err := json.Unmarshal([]byte(v), &existing); err != nil {
+			log.Warnf("ignoring invalid swarm constraints: %s", v)
+		}
+
+		for _, c := range existing {
+			if !drop(c) {
+				constraints = append(constraints, c)
+			}
+		}
+	}
+	constraints = append(constraints, add...)
+
+	value := ""
+	if len(constraints) > 0 {
+		data, _ := json.Marshal(constraints)
+		value = string(data)
+	}
+
+	if value == config.Labels[swarmConstraintsLabel] {
+		return
+	}
+
+	labels := map[string]string{}
+	for k, v := range config.Labels {
+		labels[k] = v
+	}
+
+	if value == "" {
+		delete(labels, swarmConstraintsLabel)
+	} else {
+		labels[swarmConstraintsLabel] = value
+	}
+	config.Labels = labels
+}
+
+// withNodeConstraint replaces the swarm node constraint of the config
+func withNodeConstraint(config *dockerclient.ContainerConfig, node string) {
+	add := []string{}
+	if node != "" {
+		add = append(add, "node=="+node)
+	}
+
+	setConstraints(config, func(c string) bool {
+		return strings.HasPrefix(c, "node==")
+	}, add...)
+}
+
+// containerGroup returns the group of the container.  Containers without
+// a group label form a group named after the container.
+func containerGroup(info *dockerclient.ContainerInfo) string {
+	if group := info.Config.Labels[shipyard.GroupLabel]; group != "" {
+		return group
+	}
+
+	return path.Base(info.Name)
+}
+
+// cloneContainer creates and starts a copy of the container in the group.
+// The copy is scheduled on the node if one is given.
+func (m DefaultManager) cloneContainer(info *dockerclient.ContainerInfo, group, name, node string) (string, error) {
+	config := *info.Config
+	// clear hostname to get a newly generated
+	config.Hostname = ""
+	config.Labels = map[string]string{}
+	for k, v := range info.Config.Labels {
+		config.Labels[k] = v
+	}
+	config.Labels[shipyard.GroupLabel] = group
+	withNodeConstraint(&config, node)
+	if _, err := m.PlaceContainer(&config); err != nil {
+		return "", err
+	}
+	// sending hostconfig via the Start-endpoint is deprecated starting with docker-engine 1.12
+	config.HostConfig = *info.HostConfig
+
+	id, err := m.createContainer(&config, name, "")
+	if err != nil {
+		return "", err
+	}
+
+	if err := m.client.StartContainer(id, &config.HostConfig); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// groupContainers returns the containers of the group
+func (m DefaultManager) groupContainers(group string) ([]dockerclient.Container, error) {
+	return m.client.ListContainers(true, false, labelFilter(shipyard.GroupLabel, group))
+}
+
+// containerNames returns the names of all containers of the cluster
+func (m DefaultManager) containerNames() (map[string]bool, error) {
+	containers, err := m.client.ListContainers(true, false, "")
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for _, c := range containers {
+		names[containerName(c)] = true
+	}
+
+	return names, nil
+}
+
+// ScaleGroup creates or removes containers of the group until it has the
+// requested number of replicas
+func (m DefaultManager) ScaleGroup(group string, replicas int, ports PortStrategy, username string) GroupScaleResult {
+	result := newGroupScaleResult(group)
+
+	members, err := m.groupContainers(group)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	if len(members) == 0 {
+		result.Errors = append(result.Errors, ErrGroupDoesNotExist.Error())
+		return result
+	}
+
+	// the healthiest and newest member is the template for new replicas
+	sorted := removalOrder(members)
+	template, err := m.Container(sorted[len(sorted)-1].Id)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	return m.scaleGroup(group, template, members, replicas, ports, username)
+}
+
+// ScaleContainerReplicas scales the group of the container to the requested
+// number of replicas using the container as template
+func (m DefaultManager) ScaleContainerReplicas(id string, replicas int, ports PortStrategy, username string) GroupScaleResult {
+	info, err := m.Container(id)
+	if err != nil {
+		result := newGroupScaleResult("")
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	defer beginContainerOperation(info)()
+
+	group := containerGroup(info)
+
+	if info.Config.Labels[shipyard.GroupLabel] == "" {
+		info, err = m.labelGroupMember(info, group, username)
+		if err != nil {
+			result := newGroupScaleResult(group)
+			result.Errors = append(result.Errors, err.Error())
+			return result
+		}
+	}
+
+	members, err := m.groupContainers(group)
+	if err != nil {
+		result := newGroupScaleResult(group)
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	return m.scaleGroup(group, info, members, replicas, ports, username)
+}
+
+// labelGroupMember recreates the container with the group label so that
+// it counts as a replica of the group.  Labels cannot be changed in place.
+func (m DefaultManager) labelGroupMember(info *dockerclient.ContainerInfo, group, username string) (*dockerclient.ContainerInfo, error) {
+	rep := &replacement{
+		previous:   info,
+		wasRunning: info.State.Running,
+		name:       path.Base(info.Name),
+	}
+
+	config := *info.Config
+	config.Labels = map[string]string{}
+	for k, v := range info.Config.Labels {
+		config.Labels[k] = v
+	}
+	config.Labels[shipyard.GroupLabel] = group
+	// keep the container on its node where its volumes and ports are
+	filters, _ := json.Marshal(map[string][]string{"id": {info.Id}})
+	listed, err := m.client.ListContainers(true, false, string(filters))
+	if err != nil {
+		return nil, err
+	}
+	if len(listed) > 0 {
+		if node := containerNode(listed[0]); node != "" {
+			withNodeConstraint(&config, node)
+		}
+	}
+	if _, err := m.PlaceContainer(&config); err != nil {
+		return nil, err
+	}
+	config.HostConfig = *info.HostConfig
+
+	if rep.wasRunning {
+		if err := m.client.StopContainer(info.Id, updateStopTimeout); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := m.client.RenameContainer(info.Id, previousName(rep.name)); err != nil {
+		if rep.wasRunning {
+			m.client.StartContainer(info.Id, nil)
+		}
+		return nil, err
+	}
+
+	fail := func(err error) (*dockerclient.ContainerInfo, error) {
+		if rerr := m.restoreContainer(rep); rerr != nil {
+			return nil, fmt.Errorf("%s (restoring previous container: %s)", err, rerr)
+		}
+		return nil, err
+	}
+
+	id, err := m.createContainer(&config, rep.name, username)
+	if err != nil {
+		return fail(err)
+	}
+	rep.id = id
+
+	if rep.wasRunning {
+		if err := m.client.StartContainer(id, &config.HostConfig); err != nil {
+			return fail(err)
+		}
+	}
+
+	if err := m.client.RemoveContainer(info.Id, true, false); err != nil {
+		log.Warnf("error removing unlabeled container: id=%s err=%s", info.Id, err)
+	}
+
+	return m.Container(id)
+}
+
+func newGroupScaleResult(group string) GroupScaleResult {
+	return GroupScaleResult{
+		Group:   group,
+		Created: make([]string, 0),
+		Removed: make([]string, 0),
+		Errors:  make([]string, 0),
+		Nodes:   make(map[string]int),
+	}
+}
+
+func (m DefaultManager) scaleGroup(group string, template *dockerclient.ContainerInfo, members []dockerclient.Container, replicas int, ports PortStrategy, username string) GroupScaleResult {
+	result := newGroupScaleResult(group)
+
+	defer beginContainerOperation(template)()
+
+	if diff := replicas - len(members); diff > 0 {
+		nodeNames := m.nodeNames()
+		report, err := m.portReport(template.HostConfig, ports, nodeNames)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			return result
+		}
+		result.Ports = report
+
+		// replica names must be unique in the cluster, not only the group
+		used, err := m.containerNames()
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			return result
+		}
+
+		counts := map[string]int{}
+		for _, c := range members {
+			counts[containerNode(c)]++
+		}
+
+		picks := spreadNodes(nodeNames, counts, diff)
+		if report != nil {
+			switch ports {
+			case PortsDynamic:
+				t := *template
+				hostConfig := withDynamicPorts(*template.HostConfig)
+				t.HostConfig = &hostConfig
+				template = &t
+			case PortsAffinity:
+				// every node with room for the host ports runs one new replica
+				picks = report.Available
+				if len(picks) > diff {
+					picks = picks[:diff]
+				}
+
+				for i := len(picks); i < diff; i++ {
+					result.Errors = append(result.Errors, errNoPortRoom(report).Error())
+				}
+			}
+		}
+
+		for _, node := range picks {
+			name := replicaName(group, used)
+			used[name] = true
+
+			log.Debugf("scaling group: group=%s name=%s node=%s", group, name, node)
+			id, err := m.cloneContainer(template, group, name, node)
+			if err != nil {
+				msg := strings.TrimSpace(err.Error())
+				if node != "" {
+					msg = fmt.Sprintf("node %s: %s", node, msg)
+				}
+				log.Errorf("error scaling group: group=%s err=%s", group, msg)
+				result.Errors = append(result.Errors, msg)
+				continue
+			}
+
+			result.Created = append(result.Created, id)
+		}
+	} else if diff < 0 {
+		for _, c := range removalOrder(members)[:-diff] {
+			if err := m.client.RemoveContainer(c.Id, true, false); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", containerName(c), strings.TrimSpace(err.Error())))
+				continue
+			}
+
+			result.Removed = append(result.Removed, c.Id)
+		}
+	}
+
+	// report the state after scaling
+	final, err := m.groupContainers(group)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	result.Replicas = len(final)
+	for _, c := range final {
+		result.Nodes[containerNode(c)]++
+	}
+
+	if len(result.Created) > 0 || len(result.Removed) > 0 {
+		if err := m.recordScaleRevisions(group, template, username); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+	}
+
+	m.logUserEvent("scale-group", fmt.Sprintf("group=%s replicas=%d created=%d removed=%d errors=%d", group, result.Replicas, len(result.Created), len(result.Removed), len(result.Errors)), username, []string{"containers"})
+
+	return result
+}
@@ -0,0 +1,159 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+func TestContainerNode(t *testing.T) {
+	for name, node := range map[string]string{
+		"/node-1/web": "node-1",
+		"/web":        "",
+	} {
+		c := dockerclient.Container{Names: []string{name}}
+		if n := containerNode(c); n != node {
+			t.Errorf("expected node %q for %s; received %q", node, name, n)
+		}
+	}
+}
+
+func TestRemovalOrder(t *testing.T) {
+	containers := []dockerclient.Container{
+		{Id: "new", Created: 3, Status: "Up 1 minute"},
+		{Id: "old", Created: 1, Status: "Up 1 hour"},
+		{Id: "unhealthy", Created: 2, Status: "Up 1 hour (unhealthy)"},
+		{Id: "exited", Created: 4, Status: "Exited (1) 1 minute ago"},
+	}
+
+	expected := []string{"exited", "unhealthy", "old", "new"}
+	for i, c := range removalOrder(containers) {
+		if c.Id != expected[i] {
+			t.Fatalf("expected %s at %d; received %s", expected[i], i, c.Id)
+		}
+	}
+
+	if containers[0].Id != "new" {
+		t.Fatal("expected containers not to be modified")
+	}
+}
+
+func TestSpreadNodes(t *testing.T) {
+	picks := spreadNodes([]string{"c", "b", "a"}, map[string]int{"a": 2, "b": 0}, 4)
+
+	expected := []string{"b", "c", "b", "c"}
+	for i, node := range picks {
+		if node != expected[i] {
+			t.Fatalf("expected %v; received %v", expected, picks)
+		}
+	}
+}
+
+func TestSpreadNodesWithoutNodes(t *testing.T) {
+	picks := spreadNodes(nil, nil, 2)
+	if len(picks) != 2 || picks[0] != "" || picks[1] != "" {
+		t.Fatalf("expected no nodes to be picked; received %v", picks)
+	}
+}
+
+func TestReplicaName(t *testing.T) {
+	name := replicaName("web", map[string]bool{"web_1": true, "web_3": true})
+	if name != "web_2" {
+		t.Fatalf("expected web_2; received %s", name)
+	}
+}
+
+func TestWithNodeConstraint(t *testing.T) {
+	config := &dockerclient.ContainerConfig{
+		Env:    []string{"A=1", "constraint:node==node-1", "constraint:region==eu"},
+		Labels: map[string]string{swarmConstraintsLabel: `["node==node-1","storage==ssd"]`},
+	}
+	labels := config.Labels
+
+	withNodeConstraint(config, "node-2")
+
+	expected := []string{"A=1", "constraint:region==eu"}
+	if fmt.Sprint(config.Env) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v; received %v", expected, config.Env)
+	}
+
+	if c := config.Labels[swarmConstraintsLabel]; c != `["storage==ssd","node==node-2"]` {
+		t.Fatalf("expected node constraint to be replaced; received %s", c)
+	}
+
+	if labels[swarmConstraintsLabel] != `["node==node-1","storage==ssd"]` {
+		t.Fatal("expected the labels of the original config to be kept")
+	}
+
+	withNodeConstraint(config, "")
+	if c := config.Labels[swarmConstraintsLabel]; c != `["storage==ssd"]` {
+		t.Fatalf("expected node constraint to be removed; received %s", c)
+	}
+}
+
+func TestLabelGroupMember(t *testing.T) {
+	var created dockerclient.ContainerConfig
+	removed := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1.15/containers/json":
+			fmt.Fprint(w, `[{"Id": "c1", "Names": ["/node-1/web"]}]`)
+		case "POST /v1.15/containers/c1/stop", "POST /containers/c1/rename", "POST /v1.15/containers/c2/start":
+			w.WriteHeader(http.StatusNoContent)
+		case "POST /v1.15/containers/create":
+			if name := r.URL.Query().Get("name"); name != "web" {
+				t.Errorf("expected container to keep its name; received %q", name)
+			}
+			json.NewDecoder(r.Body).Decode(&created)
+			fmt.Fprint(w, `{"Id": "c2"}`)
+		case "DELETE /v1.15/containers/c1":
+			removed = true
+			w.WriteHeader(http.StatusNoContent)
+		case "GET /v1.15/containers/c2/json":
+			fmt.Fprint(w, `{"Id": "c2", "Name": "/web", "Config": {"Labels": {"com.shipyard.group": "web"}}}`)
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := dockerclient.NewDockerClient(ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := DefaultManager{client: client}
+
+	defer maintenanceCache.invalidate()
+	maintenanceCache.set([]string{})
+
+	info := &dockerclient.ContainerInfo{
+		Id:         "c1",
+		Name:       "/web",
+		Config:     &dockerclient.ContainerConfig{Image: "nginx", Env: []string{"A=1"}},
+		State:      &dockerclient.State{Running: true},
+		HostConfig: &dockerclient.HostConfig{},
+	}
+
+	labeled, err := m.labelGroupMember(info, "web", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if labeled.Id != "c2" || !removed {
+		t.Fatalf("expected the container to be replaced; received %s (removed=%v)", labeled.Id, removed)
+	}
+
+	if created.Labels[shipyard.GroupLabel] != "web" {
+		t.Fatalf("expected group label; received %v", created.Labels)
+	}
+
+	if c := created.Labels[swarmConstraintsLabel]; c != `["node==node-1"]` {
+		t.Fatalf("expected container to stay on its node; received %s", c)
+	}
+}
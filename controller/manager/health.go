@@ -0,0 +1,302 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+var (
+	ErrContainerNotMonitored = errors.New("container does not have a health probe")
+
+	// healthCheckInterval is how often the monitor looks for due probes
+	healthCheckInterval = 5 * time.Second
+	// defaults for probes which do not set an interval or threshold
+	defaultProbeInterval  = 30 * time.Second
+	defaultProbeThreshold = 3
+	// replaceHealthTimeout limits waiting for a replaced container to run
+	replaceHealthTimeout = 30
+
+	healthStates = &healthStore{
+		states:   map[string]*shipyard.ContainerHealth{},
+		checking: map[string]bool{},
+	}
+)
+
+// healthStore holds the health of the monitored containers by id
+type healthStore struct {
+	sync.Mutex
+	states   map[string]*shipyard.ContainerHealth
+	checking map[string]bool
+}
+
+type healthCheck struct {
+	container dockerclient.Container
+	probe     *shipyard.HealthProbe
+}
+
+func probeInterval(probe *shipyard.HealthProbe) time.Duration {
+	if probe.Interval > 0 {
+		return time.Duration(probe.Interval) * time.Second
+	}
+
+	return defaultProbeInterval
+}
+
+func probeThreshold(probe *shipyard.HealthProbe) int {
+	if probe.Threshold > 0 {
+		return probe.Threshold
+	}
+
+	return defaultProbeThreshold
+}
+
+// applyProbeResult records the outcome of a probe.  A container becomes
+// unhealthy once the threshold of consecutive failures is reached and
+// healthy with the first successful probe.  It returns the previous status
+// and whether the action of the probe should be taken.
+func applyProbeResult(h *shipyard.ContainerHealth, probeErr error, now time.Time) (string, bool) {
+	previous := h.Status
+	h.LastCheck = now
+
+	status := h.Status
+	if probeErr == nil {
+		h.Failures = 0
+		h.LastError = ""
+		status = shipyard.HealthHealthy
+	} else {
+		h.Failures++
+		h.LastError = probeErr.Error()
+		if h.Failures >= probeThreshold(h.Probe) {
+			status = shipyard.HealthUnhealthy
+		}
+	}
+
+	if status != h.Status {
+		h.Status = status
+		h.Since = now
+	}
+
+	act := h.Status == shipyard.HealthUnhealthy && h.Probe.Action != "" && h.Failures >= probeThreshold(h.Probe)
+	if act {
+		// the next action is only taken after another threshold of failures
+		h.Failures = 0
+		h.Actions++
+	}
+
+	return previous, act
+}
+
+// due returns the containers whose probe is due and marks them as being
+// checked.  The health of containers which are no longer running is
+// dropped.
+func (s *healthStore) due(containers []dockerclient.Container, now time.Time) []healthCheck {
+	s.Lock()
+	defer s.Unlock()
+
+	checks := []healthCheck{}
+	running := map[string]bool{}
+	for _, c := range containers {
+		probe, err := probeFromLabels(c.Labels)
+		if err != nil {
+			log.Debugf("health monitor: ignoring container: name=%s err=%s", containerName(c), err)
+			continue
+		}
+		if probe == nil {
+			continue
+		}
+		running[c.Id] = true
+
+		h, ok := s.states[c.Id]
+		if !ok {
+			h = &shipyard.ContainerHealth{
+				ID:     c.Id,
+				Status: shipyard.HealthStarting,
+				Since:  now,
+			}
+			s.states[c.Id] = h
+		}
+		h.Name = containerName(c)
+		h.Probe = probe
+
+		if s.checking[c.Id] || now.Sub(h.LastCheck) < probeInterval(probe) {
+			continue
+		}
+
+		s.checking[c.Id] = true
+		checks = append(checks, healthCheck{container: c, probe: probe})
+	}
+
+	for id := range s.states {
+		if !running[id] {
+			delete(s.states, id)
+		}
+	}
+
+	return checks
+}
+
+// record applies the probe result to the container and returns a copy of
+// its health
+func (s *healthStore) record(id string, probeErr error, now time.Time) (shipyard.ContainerHealth, string, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.checking, id)
+
+	h, ok := s.states[id]
+	if !ok {
+		return shipyard.ContainerHealth{}, "", false
+	}
+
+	previous, act := applyProbeResult(h, probeErr, now)
+
+	return *h, previous, act
+}
+
+func (s *healthStore) list() []*shipyard.ContainerHealth {
+	s.Lock()
+	defer s.Unlock()
+
+	list := []*shipyard.ContainerHealth{}
+	for _, h := range s.states {
+		c := *h
+		list = append(list, &c)
+	}
+
+	sort.Sort(healthByName(list))
+
+	return list
+}
+
+type healthByName []*shipyard.ContainerHealth
+
+func (h healthByName) Len() int           { return len(h) }
+func (h healthByName) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h healthByName) Less(i, j int) bool { return h[i].Name < h[j].Name }
+
+// healthMonitor runs the health probes of labeled containers
+func (m DefaultManager) healthMonitor() {
+	t := time.NewTicker(healthCheckInterval)
+	for range t.C {
+		m.checkHealth()
+	}
+}
+
+func (m DefaultManager) checkHealth() {
+	containers, err := m.client.ListContainers(false, false, labelFilter(shipyard.HealthTypeLabel, ""))
+	if err != nil {
+		log.Warnf("health monitor: error listing containers: %s", err)
+		return
+	}
+
+	checks := healthStates.due(containers, time.Now())
+	if len(checks) == 0 {
+		return
+	}
+
+	hosts := m.nodeHosts()
+
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check healthCheck) {
+			defer wg.Done()
+			m.probeContainer(check, hosts[containerNode(check.container)])
+		}(check)
+	}
+	wg.Wait()
+}
+
+// probeContainer runs the probe of the container, records status changes as
+// events and takes the action of the probe if the container is unhealthy
+func (m DefaultManager) probeContainer(check healthCheck, nodeHost string) {
+	c := check.container
+
+	info, err := m.client.InspectContainer(c.Id)
+	if err == nil {
+		err = m.checkProbe(info, check.probe, nodeHost)
+	}
+
+	h, previous, act := healthStates.record(c.Id, err, time.Now())
+	if h.Status != previous {
+		msg := fmt.Sprintf("container=%s name=%s status=%s previous=%s", c.Id, h.Name, h.Status, previous)
+		if h.LastError != "" {
+			msg += " error=" + h.LastError
+		}
+
+		log.Infof("container health changed: %s", msg)
+		m.logEvent("container-health", msg, []string{"containers", "health"})
+	}
+
+	if act {
+		m.healthAction(c, check.probe, nodeHost)
+	}
+}
+
+// healthAction restarts or replaces the unhealthy container.  Containers of
+// applications changed by a running operation are left alone.
+func (m DefaultManager) healthAction(c dockerclient.Container, probe *shipyard.HealthProbe, nodeHost string) {
+	if app := c.Labels[shipyard.ApplicationLabel]; app != "" {
+		end, ok := applicationOperations.tryBegin(app)
+		if !ok {
+			log.Debugf("health monitor: application busy; skipping %s: name=%s", probe.Action, containerName(c))
+			return
+		}
+		defer end()
+	}
+
+	var err error
+	switch probe.Action {
+	case shipyard.HealthActionRestart:
+		err = m.client.RestartContainer(c.Id, updateStopTimeout)
+	case shipyard.HealthActionReplace:
+		err = m.replaceUnhealthy(c, nodeHost)
+	}
+
+	msg := fmt.Sprintf("container=%s name=%s action=%s", c.Id, containerName(c), probe.Action)
+	if err != nil {
+		msg += " error=" + err.Error()
+		log.Errorf("error taking health action: %s", msg)
+	} else {
+		log.Infof("took health action: %s", msg)
+	}
+
+	m.logEvent("container-health-action", msg, []string{"containers", "health"})
+}
+
+// replaceUnhealthy recreates the container from its configuration on the
+// same node
+func (m DefaultManager) replaceUnhealthy(c dockerclient.Container, nodeHost string) error {
+	rep, err := m.replaceContainer(c, &shipyard.RollingUpdate{Timeout: replaceHealthTimeout}, "", nodeHost)
+	if err != nil {
+		return err
+	}
+
+	return m.client.RemoveContainer(rep.previous.Id, true, false)
+}
+
+// HealthStatuses returns the health of all monitored containers sorted by
+// name
+func (m DefaultManager) HealthStatuses() ([]*shipyard.ContainerHealth, error) {
+	return healthStates.list(), nil
+}
+
+// ContainerHealth returns the health of the container by id, id prefix or
+// name
+func (m DefaultManager) ContainerHealth(id string) (*shipyard.ContainerHealth, error) {
+	for _, h := range healthStates.list() {
+		if h.ID == id || h.Name == id || (len(id) >= 12 && strings.HasPrefix(h.ID, id)) {
+			return h, nil
+		}
+	}
+
+	return nil, ErrContainerNotMonitored
+}
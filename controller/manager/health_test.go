@@ -0,0 +1,125 @@
+package manager
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+func TestProbeFromLabels(t *testing.T) {
+	probe, err := probeFromLabels(map[string]string{
+		shipyard.HealthTypeLabel:      shipyard.HealthProbeExec,
+		shipyard.HealthCommandLabel:   "pg_isready",
+		shipyard.HealthIntervalLabel:  "10",
+		shipyard.HealthThresholdLabel: "2",
+		shipyard.HealthActionLabel:    shipyard.HealthActionRestart,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if probe.Command != "pg_isready" || probe.Interval != 10 || probe.Threshold != 2 || probe.Action != shipyard.HealthActionRestart {
+		t.Fatalf("unexpected probe: %+v", probe)
+	}
+
+	labels := probeLabels(probe)
+	if len(labels) != 5 || labels[shipyard.HealthIntervalLabel] != "10" {
+		t.Fatalf("expected labels to round trip; received %v", labels)
+	}
+}
+
+func TestProbeFromLabelsInvalid(t *testing.T) {
+	for _, labels := range []map[string]string{
+		{shipyard.HealthTypeLabel: shipyard.HealthProbeHTTP},
+		{shipyard.HealthTypeLabel: shipyard.HealthProbeExec},
+		{shipyard.HealthTypeLabel: shipyard.HealthProbeTCP, shipyard.HealthPortLabel: "80", shipyard.HealthIntervalLabel: "soon"},
+		{shipyard.HealthTypeLabel: shipyard.HealthProbeTCP, shipyard.HealthPortLabel: "80", shipyard.HealthActionLabel: "reboot"},
+	} {
+		if _, err := probeFromLabels(labels); err == nil {
+			t.Fatalf("expected error for %v", labels)
+		}
+	}
+
+	if probe, err := probeFromLabels(map[string]string{}); probe != nil || err != nil {
+		t.Fatalf("expected no probe without labels; received %v %v", probe, err)
+	}
+}
+
+func TestApplyProbeResult(t *testing.T) {
+	h := &shipyard.ContainerHealth{
+		Status: shipyard.HealthStarting,
+		Probe:  &shipyard.HealthProbe{Type: shipyard.HealthProbeTCP, Port: "80", Threshold: 2, Action: shipyard.HealthActionRestart},
+	}
+	now := time.Now()
+	failed := errors.New("connection refused")
+
+	steps := []struct {
+		err      error
+		status   string
+		previous string
+		act      bool
+	}{
+		{nil, shipyard.HealthHealthy, shipyard.HealthStarting, false},
+		{failed, shipyard.HealthHealthy, shipyard.HealthHealthy, false},
+		{failed, shipyard.HealthUnhealthy, shipyard.HealthHealthy, true},
+		{failed, shipyard.HealthUnhealthy, shipyard.HealthUnhealthy, false},
+		{nil, shipyard.HealthHealthy, shipyard.HealthUnhealthy, false},
+	}
+
+	for i, step := range steps {
+		previous, act := applyProbeResult(h, step.err, now.Add(time.Duration(i)*time.Second))
+		if h.Status != step.status || previous != step.previous || act != step.act {
+			t.Fatalf("step %d: expected status=%s previous=%s act=%t; received status=%s previous=%s act=%t",
+				i, step.status, step.previous, step.act, h.Status, previous, act)
+		}
+	}
+
+	if h.Actions != 1 {
+		t.Fatalf("expected 1 action; received %d", h.Actions)
+	}
+
+	if !h.Since.Equal(now.Add(4 * time.Second)) {
+		t.Fatalf("expected status change time to be recorded; received %s", h.Since)
+	}
+}
+
+func TestHealthStoreDue(t *testing.T) {
+	store := &healthStore{
+		states:   map[string]*shipyard.ContainerHealth{"gone": {ID: "gone"}},
+		checking: map[string]bool{},
+	}
+	labels := map[string]string{
+		shipyard.HealthTypeLabel:     shipyard.HealthProbeTCP,
+		shipyard.HealthPortLabel:     "80",
+		shipyard.HealthIntervalLabel: "10",
+	}
+	containers := []dockerclient.Container{
+		{Id: "web", Names: []string{"/web"}, Labels: labels},
+		{Id: "plain", Names: []string{"/plain"}},
+	}
+	now := time.Now()
+
+	if checks := store.due(containers, now); len(checks) != 1 || checks[0].container.Id != "web" {
+		t.Fatalf("expected web to be due; received %v", checks)
+	}
+
+	if _, ok := store.states["gone"]; ok {
+		t.Fatal("expected state of removed container to be dropped")
+	}
+
+	if checks := store.due(containers, now); len(checks) != 0 {
+		t.Fatal("expected running check not to be due again")
+	}
+
+	store.record("web", nil, now)
+	if checks := store.due(containers, now.Add(5*time.Second)); len(checks) != 0 {
+		t.Fatal("expected check not to be due before the interval")
+	}
+
+	if checks := store.due(containers, now.Add(10*time.Second)); len(checks) != 1 {
+		t.Fatal("expected check to be due after the interval")
+	}
+}
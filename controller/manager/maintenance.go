@@ -0,0 +1,414 @@
+package manager
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+// maintenanceConstraintPrefix starts the swarm constraints keeping
+// containers off nodes in maintenance.  They use an anchored regular
+// expression to tell them apart from constraints set by users.
+const maintenanceConstraintPrefix = "node!=/^"
+
+var (
+	// maintenanceCacheTTL is how long the nodes in maintenance are cached
+	// for placements.  Changes by other controllers show up after it.
+	maintenanceCacheTTL = 10 * time.Second
+
+	maintenanceCache = &maintenanceNodeCache{}
+)
+
+// maintenanceNodeCache holds the names of the nodes in maintenance since
+// every placement needs them
+type maintenanceNodeCache struct {
+	sync.Mutex
+	names  []string
+	loaded time.Time
+}
+
+func (c *maintenanceNodeCache) get() ([]string, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.loaded.IsZero() || time.Since(c.loaded) > maintenanceCacheTTL {
+		return nil, false
+	}
+
+	return c.names, true
+}
+
+func (c *maintenanceNodeCache) set(names []string) {
+	c.Lock()
+	c.names = names
+	c.loaded = time.Now()
+	c.Unlock()
+}
+
+func (c *maintenanceNodeCache) invalidate() {
+	c.Lock()
+	c.loaded = time.Time{}
+	c.Unlock()
+}
+
+func maintenanceConstraint(node string) string {
+	return maintenanceConstraintPrefix + regexp.QuoteMeta(node) + "$/"
+}
+
+// withMaintenanceConstraints replaces the maintenance constraints of the
+// config with constraints for the nodes.  Node constraints pinning the
+// container to one of the nodes are dropped as they cannot be satisfied.
+func withMaintenanceConstraints(config *dockerclient.ContainerConfig, nodes []string) {
+	excluded := map[string]bool{}
+	add := []string{}
+	for _, node := range nodes {
+		excluded[node] = true
+		add = append(add, maintenanceConstraint(node))
+	}
+
+	setConstraints(config, func(c string) bool {
+		return strings.HasPrefix(c, maintenanceConstraintPrefix) ||
+			(strings.HasPrefix(c, "node==") && excluded[strings.TrimPrefix(c, "node==")])
+	}, add...)
+}
+
+// addMaintenanceConstraints adds constraints for the nodes to the config.
+// Unlike withMaintenanceConstraints it keeps the maintenance constraints of
+// the config (i.e. of a node being drained).
+func addMaintenanceConstraints(config *dockerclient.ContainerConfig, nodes []string) {
+	excluded := map[string]bool{}
+	constraints := map[string]bool{}
+	add := []string{}
+	for _, node := range nodes {
+		excluded[node] = true
+		constraints[maintenanceConstraint(node)] = true
+		add = append(add, maintenanceConstraint(node))
+	}
+
+	setConstraints(config, func(c string) bool {
+		return constraints[c] || (strings.HasPrefix(c, "node==") && excluded[strings.TrimPrefix(c, "node==")])
+	}, add...)
+}
+
+// managedContainer reports whether the container was created by Shipyard
+func managedContainer(c dockerclient.Container) bool {
+	for _, label := range []string{shipyard.ApplicationLabel, shipyard.GroupLabel, shipyard.TemplateLabel} {
+		if c.Labels[label] != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MaintenanceNodes returns the nodes in maintenance
+func (m DefaultManager) MaintenanceNodes() ([]*shipyard.NodeMaintenance, error) {
+	res, err := r.Table(tblNameMaintenance).OrderBy(r.Asc("node")).Run(m.session)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []*shipyard.NodeMaintenance{}
+	if err := res.All(&nodes); err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+// maintenanceNodeNames returns the names of the nodes in maintenance
+func (m DefaultManager) maintenanceNodeNames() ([]string, error) {
+	if names, ok := maintenanceCache.get(); ok {
+		return names, nil
+	}
+
+	nodes, err := m.MaintenanceNodes()
+	if err != nil {
+		return nil, fmt.Errorf("error loading nodes in maintenance: %s", err)
+	}
+
+	names := []string{}
+	for _, n := range nodes {
+		names = append(names, n.Node)
+	}
+	maintenanceCache.set(names)
+
+	return names, nil
+}
+
+// PlaceContainer adds constraints keeping the container off the nodes in
+// maintenance to its config.  Constraints for nodes no longer in
+// maintenance are removed.  It reports whether the config was changed.  The
+// config is left unchanged if the nodes in maintenance cannot be loaded.
+func (m DefaultManager) PlaceContainer(config *dockerclient.ContainerConfig) (bool, error) {
+	nodes, err := m.maintenanceNodeNames()
+	if err != nil {
+		return false, err
+	}
+
+	env, labels := config.Env, config.Labels
+	withMaintenanceConstraints(config, nodes)
+
+	return !reflect.DeepEqual(env, config.Env) || !reflect.DeepEqual(labels, config.Labels), nil
+}
+
+// SetNodeMaintenance puts the node into maintenance.  Nodes already in
+// maintenance are left unchanged.
+func (m DefaultManager) SetNodeMaintenance(node, reason, username string) error {
+	n, err := m.Node(node)
+	if err != nil {
+		return err
+	}
+
+	if n == nil {
+		return ErrNodeDoesNotExist
+	}
+
+	res, err := r.Table(tblNameMaintenance).Filter(map[string]string{"node": node}).Run(m.session)
+	if err != nil {
+		return err
+	}
+
+	if !res.IsNil() {
+		return nil
+	}
+
+	maintenance := &shipyard.NodeMaintenance{
+		Node:     node,
+		Reason:   reason,
+		Username: username,
+		Started:  time.Now(),
+	}
+	if _, err := r.Table(tblNameMaintenance).Insert(maintenance).RunWrite(m.session); err != nil {
+		return err
+	}
+	maintenanceCache.invalidate()
+
+	m.logUserEvent("node-maintenance", fmt.Sprintf("node=%s maintenance=true reason=%s", node, reason), username, []string{"nodes"})
+
+	return nil
+}
+
+// ClearNodeMaintenance ends the maintenance of the node
+func (m DefaultManager) ClearNodeMaintenance(node, username string) error {
+	if _, err := r.Table(tblNameMaintenance).Filter(map[string]string{"node": node}).Delete().RunWrite(m.session); err != nil {
+		return err
+	}
+	maintenanceCache.invalidate()
+
+	m.logUserEvent("node-maintenance", fmt.Sprintf("node=%s maintenance=false", node), username, []string{"nodes"})
+
+	return nil
+}
+
+// DrainNode puts the node into maintenance and recreates its Shipyard
+// managed containers on other nodes.  Revisions are recorded for the
+// applications and groups of the moved containers.
+func (m DefaultManager) DrainNode(node, username string, progress func(*shipyard.DrainProgress)) (*shipyard.DrainResult, error) {
+	if err := m.SetNodeMaintenance(node, "drain", username); err != nil {
+		return nil, err
+	}
+
+	containers, err := m.client.ListContainers(true, false, "")
+	if err != nil {
+		return nil, err
+	}
+
+	onNode := []dockerclient.Container{}
+	for _, c := range containers {
+		if containerNode(c) == node {
+			onNode = append(onNode, c)
+		}
+	}
+	sort.Sort(containersByName(onNode))
+
+	exclude, err := m.maintenanceNodeNames()
+	if err != nil {
+		return nil, err
+	}
+
+	// the drained node is excluded even if it left maintenance meanwhile
+	excluded := false
+	for _, n := range exclude {
+		excluded = excluded || n == node
+	}
+	if !excluded {
+		exclude = append(exclude, node)
+	}
+
+	result := m.drainContainers(node, onNode, exclude, username, progress)
+
+	moved := map[string]bool{}
+	for _, name := range result.Moved {
+		moved[name] = true
+	}
+
+	apps := map[string]bool{}
+	groups := map[string]bool{}
+	for _, c := range onNode {
+		if !moved[containerName(c)] {
+			continue
+		}
+
+		if name := c.Labels[shipyard.ApplicationLabel]; name != "" && !apps[name] {
+			apps[name] = true
+			if app, err := m.Application(name); err == nil && app.ID != "" {
+				if err := m.recordApplicationRevision(app, "drain", username); err != nil {
+					result.Errors = append(result.Errors, err.Error())
+				}
+			}
+		}
+
+		if group := c.Labels[shipyard.GroupLabel]; group != "" && !groups[group] {
+			groups[group] = true
+			if err := m.recordGroupRevision(group, "drain", username); err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			}
+		}
+	}
+
+	m.logUserEvent("drain-node", fmt.Sprintf("node=%s moved=%d failed=%d skipped=%d", node, len(result.Moved), len(result.Failed), len(result.Skipped)), username, []string{"nodes"})
+
+	return result, nil
+}
+
+// drainContainers moves the managed containers to nodes other than the
+// excluded ones
+func (m DefaultManager) drainContainers(node string, containers []dockerclient.Container, exclude []string, username string, progress func(*shipyard.DrainProgress)) *shipyard.DrainResult {
+	result := &shipyard.DrainResult{
+		Node:    node,
+		Moved:   []string{},
+		Failed:  []string{},
+		Skipped: []string{},
+		Errors:  []string{},
+	}
+
+	total := 0
+	for _, c := range containers {
+		if managedContainer(c) {
+			total++
+		}
+	}
+
+	report := func(stage, container, message string) {
+		if progress == nil {
+			return
+		}
+
+		p := &shipyard.DrainProgress{
+			Node:      node,
+			Stage:     stage,
+			Container: container,
+			Message:   message,
+			Moved:     len(result.Moved),
+			Failed:    len(result.Failed),
+			Total:     total,
+			Time:      time.Now(),
+		}
+		if stage == "complete" {
+			p.Result = result
+		}
+		progress(p)
+	}
+
+	report("start", "", fmt.Sprintf("draining %d containers", total))
+
+	for _, c := range containers {
+		name := containerName(c)
+		if !managedContainer(c) {
+			result.Skipped = append(result.Skipped, name)
+			report("skipped", name, "not managed by shipyard")
+			continue
+		}
+
+		report("moving", name, "")
+		if err := m.moveContainer(c, exclude, username); err != nil {
+			msg := strings.TrimSpace(err.Error())
+			log.Errorf("error draining container: node=%s name=%s err=%s", node, name, msg)
+			result.Failed = append(result.Failed, name)
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", name, msg))
+			report("failed", name, msg)
+			continue
+		}
+
+		result.Moved = append(result.Moved, name)
+		report("moved", name, "")
+	}
+
+	report("complete", "", "")
+
+	return result
+}
+
+// moveContainer recreates the container with the same name and config
+// away from the excluded nodes.  The previous container is restored if the
+// new one cannot be created or started.
+func (m DefaultManager) moveContainer(c dockerclient.Container, exclude []string, username string) error {
+	info, err := m.client.InspectContainer(c.Id)
+	if err != nil {
+		return err
+	}
+
+	defer beginContainerOperation(info)()
+
+	rep := &replacement{
+		previous:   info,
+		wasRunning: info.State.Running,
+		name:       containerName(c),
+	}
+
+	config := *info.Config
+	// clear hostname to get a newly generated
+	config.Hostname = ""
+	withNodeConstraint(&config, "")
+	withMaintenanceConstraints(&config, exclude)
+	config.HostConfig = *info.HostConfig
+
+	if rep.wasRunning {
+		if err := m.client.StopContainer(info.Id, updateStopTimeout); err != nil {
+			return err
+		}
+	}
+
+	if err := m.client.RenameContainer(info.Id, previousName(rep.name)); err != nil {
+		if rep.wasRunning {
+			m.client.StartContainer(info.Id, nil)
+		}
+		return err
+	}
+
+	fail := func(err error) error {
+		if rerr := m.restoreContainer(rep); rerr != nil {
+			return fmt.Errorf("%s (restoring previous container: %s)", err, rerr)
+		}
+		return err
+	}
+
+	id, err := m.createContainer(&config, rep.name, username)
+	if err != nil {
+		return fail(err)
+	}
+	rep.id = id
+
+	if rep.wasRunning {
+		if err := m.client.StartContainer(id, &config.HostConfig); err != nil {
+			return fail(err)
+		}
+	}
+
+	// the container runs elsewhere even if the previous one is left behind
+	if err := m.client.RemoveContainer(info.Id, true, false); err != nil {
+		log.Warnf("error removing drained container: name=%s err=%s", previousName(rep.name), err)
+	}
+
+	return nil
+}
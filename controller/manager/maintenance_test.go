@@ -0,0 +1,189 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+func TestWithMaintenanceConstraints(t *testing.T) {
+	config := &dockerclient.ContainerConfig{
+		Env:    []string{"A=1", "constraint:node!=/^old$/"},
+		Labels: map[string]string{swarmConstraintsLabel: `["node!=/^older$/","node==node1"]`},
+	}
+
+	withMaintenanceConstraints(config, []string{"node2", "node.3"})
+	if fmt.Sprint(config.Env) != "[A=1]" {
+		t.Fatalf("expected stale constraint to be removed from the environment; received %v", config.Env)
+	}
+
+	expected := `["node==node1","node!=/^node2$/","node!=/^node\\.3$/"]`
+	if c := config.Labels[swarmConstraintsLabel]; c != expected {
+		t.Fatalf("expected %s; received %s", expected, c)
+	}
+
+	// a node in maintenance cannot take the container
+	withMaintenanceConstraints(config, []string{"node1"})
+	if c := config.Labels[swarmConstraintsLabel]; c != `["node!=/^node1$/"]` {
+		t.Fatalf("expected node constraint to be dropped; received %s", c)
+	}
+
+	withMaintenanceConstraints(config, nil)
+	if _, ok := config.Labels[swarmConstraintsLabel]; ok {
+		t.Fatalf("expected constraints to be removed; received %v", config.Labels)
+	}
+}
+
+func TestPlaceContainer(t *testing.T) {
+	defer maintenanceCache.invalidate()
+	maintenanceCache.set([]string{"node1"})
+
+	m := DefaultManager{}
+
+	config := &dockerclient.ContainerConfig{Env: []string{"A=1", "constraint:node==node1"}}
+	if changed, err := m.PlaceContainer(config); err != nil || !changed {
+		t.Fatalf("expected config to be changed; received %v", err)
+	}
+
+	if fmt.Sprint(config.Env) != "[A=1]" || config.Labels[swarmConstraintsLabel] != `["node!=/^node1$/"]` {
+		t.Fatalf("expected container to be moved off node1; received env=%v labels=%v", config.Env, config.Labels)
+	}
+
+	if changed, err := m.PlaceContainer(config); err != nil || changed {
+		t.Fatalf("expected placed config to be kept; received %v", err)
+	}
+}
+
+func TestAddMaintenanceConstraints(t *testing.T) {
+	config := &dockerclient.ContainerConfig{
+		Env:    []string{"constraint:node==node2"},
+		Labels: map[string]string{swarmConstraintsLabel: `["node!=/^node1$/"]`},
+	}
+
+	addMaintenanceConstraints(config, []string{"node2"})
+
+	if len(config.Env) != 0 || config.Labels[swarmConstraintsLabel] != `["node!=/^node1$/","node!=/^node2$/"]` {
+		t.Fatalf("expected constraints of both nodes; received env=%v labels=%v", config.Env, config.Labels)
+	}
+}
+
+func TestManagedContainer(t *testing.T) {
+	if managedContainer(dockerclient.Container{Labels: map[string]string{"other": "x"}}) {
+		t.Fatal("expected unlabeled container not to be managed")
+	}
+
+	if !managedContainer(dockerclient.Container{Labels: map[string]string{shipyard.GroupLabel: "web"}}) {
+		t.Fatal("expected group container to be managed")
+	}
+}
+
+// drainEngine is a stand-in engine for the containers of a drained node
+type drainEngine struct {
+	sync.Mutex
+	failCreate string
+	created    map[string]dockerclient.ContainerConfig
+	removed    []string
+}
+
+func (e *drainEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.Lock()
+	defer e.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1.15")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	switch {
+	case r.Method == "GET" && len(parts) == 3 && parts[2] == "json":
+		json.NewEncoder(w).Encode(&dockerclient.ContainerInfo{
+			Id:   parts[1],
+			Name: "/node1/" + parts[1],
+			Config: &dockerclient.ContainerConfig{
+				Image:  "nginx",
+				Env:    []string{"A=1", "constraint:node==node1"},
+				Labels: map[string]string{shipyard.GroupLabel: "web"},
+			},
+			HostConfig: &dockerclient.HostConfig{},
+			State:      &dockerclient.State{Running: true},
+		})
+	case r.Method == "POST" && path == "/containers/create":
+		name := r.URL.Query().Get("name")
+		if name == e.failCreate {
+			http.Error(w, "no resources available", http.StatusInternalServerError)
+			return
+		}
+
+		var config dockerclient.ContainerConfig
+		json.NewDecoder(r.Body).Decode(&config)
+		e.created[name] = config
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"Id": "new-%s"}`, name)
+	case r.Method == "DELETE":
+		e.removed = append(e.removed, parts[1])
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		// stop, rename and start
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestDrainContainers(t *testing.T) {
+	// the drained node is excluded even when no node is in maintenance
+	defer maintenanceCache.invalidate()
+	maintenanceCache.set([]string{})
+
+	engine := &drainEngine{failCreate: "db", created: map[string]dockerclient.ContainerConfig{}}
+	ts := httptest.NewServer(engine)
+	defer ts.Close()
+
+	client, err := dockerclient.NewDockerClient(ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := DefaultManager{client: client}
+
+	labels := map[string]string{shipyard.GroupLabel: "web"}
+	containers := []dockerclient.Container{
+		{Id: "web", Names: []string{"/node1/web"}, Labels: labels},
+		{Id: "db", Names: []string{"/node1/db"}, Labels: labels},
+		{Id: "other", Names: []string{"/node1/other"}},
+	}
+
+	stages := []string{}
+	result := m.drainContainers("node1", containers, []string{"node1"}, "admin", func(p *shipyard.DrainProgress) {
+		stages = append(stages, p.Stage)
+		if p.Total != 2 {
+			t.Fatalf("expected 2 managed containers; received %d", p.Total)
+		}
+	})
+
+	if fmt.Sprint(result.Moved) != "[web]" || fmt.Sprint(result.Failed) != "[db]" || fmt.Sprint(result.Skipped) != "[other]" {
+		t.Fatalf("unexpected drain result: %+v", result)
+	}
+
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "no resources available") {
+		t.Fatalf("expected create error; received %v", result.Errors)
+	}
+
+	expected := "[start moving moved moving failed skipped complete]"
+	if fmt.Sprint(stages) != expected {
+		t.Fatalf("expected stages %s; received %v", expected, stages)
+	}
+
+	created := engine.created["web"]
+	if fmt.Sprint(created.Env) != "[A=1]" || created.Labels[swarmConstraintsLabel] != `["node!=/^node1$/"]` {
+		t.Fatalf("expected node constraint to be replaced; received env=%v labels=%v", created.Env, created.Labels)
+	}
+
+	// the moved container is removed and the failed one is left in place
+	if fmt.Sprint(engine.removed) != "[web]" {
+		t.Fatalf("expected only the moved container to be removed; received %v", engine.removed)
+	}
+}
@@ -21,34 +21,52 @@ import (
 )
 
 const (
-	tblNameConfig      = "config"
-	tblNameEvents      = "events"
-	tblNameAccounts    = "accounts"
-	tblNameRoles       = "roles"
-	tblNameServiceKeys = "service_keys"
-	tblNameExtensions  = "extensions"
-	tblNameWebhookKeys = "webhook_keys"
-	tblNameRegistries  = "registries"
-	tblNameConsole     = "console"
-	storeKey           = "shipyard"
-	trackerHost        = "http://tracker.shipyard-project.com"
-	NodeHealthUp       = "up"
-	NodeHealthDown     = "down"
+	tblNameConfig            = "config"
+	tblNameEvents            = "events"
+	tblNameAccounts          = "accounts"
+	tblNameRoles             = "roles"
+	tblNameServiceKeys       = "service_keys"
+	tblNameExtensions        = "extensions"
+	tblNameWebhookKeys       = "webhook_keys"
+	tblNameRegistries        = "registries"
+	tblNameConsole           = "console"
+	tblNameRetentionPolicies = "retention_policies"
+	tblNameStorageSnapshots  = "storage_snapshots"
+	tblNameApplications      = "applications"
+	tblNameRevisions         = "revisions"
+	tblNameTemplates         = "templates"
+	tblNameMaintenance       = "maintenance"
+	storeKey                 = "shipyard"
+	trackerHost              = "http://tracker.shipyard-project.com"
+	NodeHealthUp             = "up"
+	NodeHealthDown           = "down"
 )
 
 var (
-	ErrLoginFailure               = errors.New("invalid username or password")
-	ErrAccountExists              = errors.New("account already exists")
-	ErrAccountDoesNotExist        = errors.New("account does not exist")
-	ErrRoleDoesNotExist           = errors.New("role does not exist")
-	ErrNodeDoesNotExist           = errors.New("node does not exist")
-	ErrServiceKeyDoesNotExist     = errors.New("service key does not exist")
-	ErrInvalidAuthToken           = errors.New("invalid auth token")
-	ErrExtensionDoesNotExist      = errors.New("extension does not exist")
-	ErrWebhookKeyDoesNotExist     = errors.New("webhook key does not exist")
-	ErrRegistryDoesNotExist       = errors.New("registry does not exist")
-	ErrConsoleSessionDoesNotExist = errors.New("console session does not exist")
-	store                         = sessions.NewCookieStore([]byte(storeKey))
+	ErrLoginFailure                = errors.New("invalid username or password")
+	ErrAccountExists               = errors.New("account already exists")
+	ErrAccountDoesNotExist         = errors.New("account does not exist")
+	ErrRoleDoesNotExist            = errors.New("role does not exist")
+	ErrNodeDoesNotExist            = errors.New("node does not exist")
+	ErrServiceKeyDoesNotExist      = errors.New("service key does not exist")
+	ErrInvalidAuthToken            = errors.New("invalid auth token")
+	ErrExtensionDoesNotExist       = errors.New("extension does not exist")
+	ErrWebhookKeyDoesNotExist      = errors.New("webhook key does not exist")
+	ErrRegistryDoesNotExist        = errors.New("registry does not exist")
+	ErrConsoleSessionDoesNotExist  = errors.New("console session does not exist")
+	ErrRetentionPolicyDoesNotExist = errors.New("retention policy does not exist")
+	ErrRepositoryInUse             = errors.New("repository is used by running containers")
+	ErrRepositoryDoesNotExist      = errors.New("repository does not exist")
+	ErrRegistryAccessDenied        = errors.New("access to registry denied")
+	ErrApplicationDoesNotExist     = errors.New("application does not exist")
+	ErrApplicationExists           = errors.New("application already exists")
+	ErrApplicationNotDeployed      = errors.New("application was not deployed from a compose definition")
+	ErrGroupDoesNotExist           = errors.New("group does not exist")
+	ErrRevisionDoesNotExist        = errors.New("revision does not exist")
+	ErrTemplateDoesNotExist        = errors.New("template does not exist")
+	ErrTemplateAccessDenied        = errors.New("template is not shared with the account")
+	ErrTemplateDeployDenied        = errors.New("deploying templates requires the containers:rw role")
+	store                          = sessions.NewCookieStore([]byte(storeKey))
 )
 
 type (
@@ -63,9 +81,32 @@ type (
 		disableUsageInfo bool
 	}
 
+	// ScaleResult lists the created copies of a container.  Ports is set
+	// if the container publishes fixed host ports.
 	ScaleResult struct {
 		Scaled []string
 		Errors []string
+		Ports  *PortReport
+	}
+
+	// GroupScaleResult is the state of a group after scaling it.  Nodes
+	// counts the replicas per node and Ports is set if the replicas publish
+	// fixed host ports.
+	GroupScaleResult struct {
+		Group    string
+		Replicas int
+		Created  []string
+		Removed  []string
+		Errors   []string
+		Nodes    map[string]int
+		Ports    *PortReport
+	}
+
+	// ApplicationResult lists the containers an application operation
+	// succeeded for and the errors of the containers it failed for
+	ApplicationResult struct {
+		Containers []string
+		Errors     []string
 	}
 
 	Manager interface {
@@ -80,7 +121,27 @@ type (
 		Store() *sessions.CookieStore
 		StoreKey() string
 		Container(id string) (*dockerclient.ContainerInfo, error)
-		ScaleContainer(id string, numInstances int) ScaleResult
+		ScaleContainer(id string, numInstances int, ports PortStrategy, username string) ScaleResult
+		ScaleContainerReplicas(id string, replicas int, ports PortStrategy, username string) GroupScaleResult
+		ScaleGroup(group string, replicas int, ports PortStrategy, username string) GroupScaleResult
+		PlaceContainer(config *dockerclient.ContainerConfig) (bool, error)
+		HealthStatuses() ([]*shipyard.ContainerHealth, error)
+		ContainerHealth(id string) (*shipyard.ContainerHealth, error)
+		UpdateApplication(app *shipyard.Application, update *shipyard.RollingUpdate, username string, progress func(*shipyard.UpdateProgress)) (*shipyard.UpdateResult, error)
+		UpdateGroup(group string, update *shipyard.RollingUpdate, username string, progress func(*shipyard.UpdateProgress)) (*shipyard.UpdateResult, error)
+		Revisions(kind, target string) ([]*shipyard.Revision, error)
+		Revision(kind, target string, number int) (*shipyard.Revision, error)
+		DiffRevisions(kind, target string, from, to int) (*shipyard.RevisionDiff, error)
+		RollbackApplication(app *shipyard.Application, number int, username string) (ApplicationResult, error)
+		ReconcileApplication(app *shipyard.Application, username string) (*shipyard.ApplicationDrift, error)
+		SetReconcilePaused(app *shipyard.Application, paused bool, username string) error
+		Templates(username string) ([]*shipyard.Template, error)
+		Template(name string, version int, username string) (*shipyard.Template, error)
+		TemplateVersions(name, username string) ([]*shipyard.Template, error)
+		SaveTemplate(t *shipyard.Template, username string) error
+		RemoveTemplate(name, username string) error
+		RenderTemplate(name string, deployment *shipyard.TemplateDeployment, username string) (*dockerclient.ContainerConfig, error)
+		DeployTemplate(name string, deployment *shipyard.TemplateDeployment, username string) (ApplicationResult, error)
 		SaveServiceKey(key *auth.ServiceKey) error
 		RemoveServiceKey(key string) error
 		SaveEvent(event *shipyard.Event) error
@@ -102,11 +163,68 @@ type (
 
 		Nodes() ([]*shipyard.Node, error)
 		Node(name string) (*shipyard.Node, error)
+		ClusterInfo() (*shipyard.ClusterInfo, error)
+		MaintenanceNodes() ([]*shipyard.NodeMaintenance, error)
+		SetNodeMaintenance(node, reason, username string) error
+		ClearNodeMaintenance(node, username string) error
+		DrainNode(node, username string, progress func(*shipyard.DrainProgress)) (*shipyard.DrainResult, error)
+
+		Services() ([]*shipyard.SwarmService, error)
+		Service(id string) (*shipyard.SwarmService, error)
+		CreateService(spec *shipyard.SwarmServiceSpec, username string) (string, error)
+		UpdateService(id string, version uint64, spec *shipyard.SwarmServiceSpec, username string) error
+		RemoveService(id, username string) error
+		Tasks(service string) ([]*shipyard.SwarmTask, error)
+		Task(id string) (*shipyard.SwarmTask, error)
+		SwarmNodes() ([]*shipyard.SwarmNode, error)
+		Secrets() ([]*shipyard.SwarmSecret, error)
+		Secret(id string) (*shipyard.SwarmSecret, error)
+		CreateSecret(spec *shipyard.SwarmSecretSpec, username string) (string, error)
+		RemoveSecret(id, username string) error
+		Networks() ([]*dockerclient.NetworkResource, error)
+		Network(id string) (*dockerclient.NetworkResource, error)
+		AdmitNetwork(config *dockerclient.NetworkCreate, username string) error
+		CreateNetwork(config *dockerclient.NetworkCreate, username string) (*dockerclient.NetworkResource, error)
+		RemoveNetwork(id, username string) error
+		ConnectNetwork(id, container, username string) error
+		DisconnectNetwork(id, container string, force bool, username string) error
+		Volumes(usage bool) ([]*shipyard.Volume, error)
+		Volume(name string, usage bool) (*shipyard.Volume, error)
+		CreateVolume(request *dockerclient.VolumeCreateRequest, username string) (*shipyard.Volume, error)
+		RemoveVolume(name, username string) error
+		CleanupVolumes(confirm bool, username string) (*shipyard.VolumeCleanup, error)
 
 		AddRegistry(registry *shipyard.Registry) error
 		RemoveRegistry(registry *shipyard.Registry) error
 		Registries() ([]*shipyard.Registry, error)
 		Registry(name string) (*shipyard.Registry, error)
+		RegistryAuthConfig(username, image string) (*dockerclient.AuthConfig, error)
+		DeleteTag(registry *shipyard.Registry, repo, tag string) error
+		RepositoryUsage(registry *shipyard.Registry, repo string) (*shipyard.RepositoryUsage, error)
+		DeleteRepository(registry *shipyard.Registry, repo, username string, force bool) (*shipyard.RepositoryDeletion, error)
+		PromoteImage(promotion *shipyard.Promotion, username string) (*shipyard.Promotion, error)
+		DeployRepository(registry *shipyard.Registry, repo string, deployment *shipyard.RepositoryDeployment, username string) (*shipyard.RepositoryDeployment, error)
+		SearchImages(query, username string, timeout time.Duration) (*shipyard.ImageSearch, error)
+		StorageReport(registry *shipyard.Registry) (*shipyard.StorageReport, error)
+		RepositoryStorage(registry *shipyard.Registry, repo string) (*shipyard.RepositoryStorage, error)
+		StorageHistory(registry *shipyard.Registry, since time.Time) ([]*shipyard.StorageSnapshot, error)
+
+		Applications() ([]*shipyard.Application, error)
+		Application(name string) (*shipyard.Application, error)
+		CreateApplication(app *shipyard.Application, username string) error
+		StartApplication(app *shipyard.Application, username string) ApplicationResult
+		StopApplication(app *shipyard.Application, username string) ApplicationResult
+		RestartApplication(app *shipyard.Application, username string) ApplicationResult
+		RedeployApplication(app *shipyard.Application, definition, username string) error
+		RemoveApplication(app *shipyard.Application, username string) ApplicationResult
+		ApplicationLogs(app *shipyard.Application, tail int) ([]*shipyard.ApplicationLog, error)
+		ApplicationStats(app *shipyard.Application) (*shipyard.ApplicationStats, error)
+
+		RetentionPolicies() ([]*shipyard.RetentionPolicy, error)
+		RetentionPolicy(id string) (*shipyard.RetentionPolicy, error)
+		SaveRetentionPolicy(policy *shipyard.RetentionPolicy) error
+		RemoveRetentionPolicy(policy *shipyard.RetentionPolicy) error
+		ApplyRetentionPolicy(policy *shipyard.RetentionPolicy, dryRun bool) (*shipyard.RetentionReport, error)
 
 		CreateConsoleSession(c *shipyard.ConsoleSession) error
 		RemoveConsoleSession(c *shipyard.ConsoleSession) error
@@ -157,7 +275,7 @@ func (m DefaultManager) StoreKey() string {
 
 func (m DefaultManager) initdb() {
 	// create tables if needed
-	tables := []string{tblNameConfig, tblNameEvents, tblNameAccounts, tblNameRoles, tblNameConsole, tblNameServiceKeys, tblNameRegistries, tblNameExtensions, tblNameWebhookKeys}
+	tables := []string{tblNameConfig, tblNameEvents, tblNameAccounts, tblNameRoles, tblNameConsole, tblNameServiceKeys, tblNameRegistries, tblNameExtensions, tblNameWebhookKeys, tblNameRetentionPolicies, tblNameStorageSnapshots, tblNameApplications, tblNameRevisions, tblNameTemplates, tblNameMaintenance}
 	for _, tbl := range tables {
 		_, err := r.Table(tbl).Run(m.session)
 		if err != nil {
@@ -171,6 +289,12 @@ func (m DefaultManager) initdb() {
 func (m DefaultManager) init() error {
 	// anonymous usage info
 	go m.usageReport()
+	go m.retentionScheduler()
+	go m.storageRecorder()
+	go m.reconciler()
+	go m.healthMonitor()
+	go m.nodeHealthMonitor()
+	go m.clusterInfoRecorder()
 	return nil
 }
 
@@ -231,7 +355,9 @@ func (m DefaultManager) Container(id string) (*dockerclient.ContainerInfo, error
 	return m.client.InspectContainer(id)
 }
 
-func (m DefaultManager) ScaleContainer(id string, numInstances int) ScaleResult {
+// ScaleContainer creates numInstances copies of the container.  Fixed host
+// ports are handled as selected by the port strategy.
+func (m DefaultManager) ScaleContainer(id string, numInstances int, ports PortStrategy, username string) ScaleResult {
 	var (
 		errChan = make(chan (error))
 		resChan = make(chan (string))
@@ -244,28 +370,73 @@ func (m DefaultManager) ScaleContainer(id string, numInstances int) ScaleResult
 		return result
 	}
 
-	for i := 0; i < numInstances; i++ {
-		go func(instance int) {
-			log.Debugf("scaling: id=%s #=%d", containerInfo.Id, instance)
-			config := containerInfo.Config
+	// copies join the group of the container
+	labels := map[string]string{}
+	for k, v := range containerInfo.Config.Labels {
+		labels[k] = v
+	}
+	labels[shipyard.GroupLabel] = containerGroup(containerInfo)
+	containerInfo.Config.Labels = labels
+
+	defer beginContainerOperation(containerInfo)()
+
+	report, err := m.portReport(containerInfo.HostConfig, ports, m.nodeNames())
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+	result.Ports = report
+
+	hostConfig := *containerInfo.HostConfig
+	nodes := make([]string, numInstances)
+	if report != nil {
+		switch ports {
+		case PortsDynamic:
+			hostConfig = withDynamicPorts(hostConfig)
+		case PortsAffinity:
+			// every node with room for the host ports runs one copy
+			nodes = report.Available
+			if len(nodes) > numInstances {
+				nodes = nodes[:numInstances]
+			}
+
+			for i := len(nodes); i < numInstances; i++ {
+				result.Errors = append(result.Errors, errNoPortRoom(report).Error())
+			}
+		}
+	}
+
+	maintenance, err := m.maintenanceNodeNames()
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	for i, node := range nodes {
+		go func(instance int, node string) {
+			log.Debugf("scaling: id=%s #=%d node=%s", containerInfo.Id, instance, node)
+			config := *containerInfo.Config
 			// clear hostname to get a newly generated
 			config.Hostname = ""
-			hostConfig := containerInfo.HostConfig
-			config.HostConfig = *hostConfig // sending hostconfig via the Start-endpoint is deprecated starting with docker-engine 1.12
-			id, err := m.client.CreateContainer(config, "", nil)
+			if node != "" {
+				withNodeConstraint(&config, node)
+			}
+			withMaintenanceConstraints(&config, maintenance)
+			config.HostConfig = hostConfig // sending hostconfig via the Start-endpoint is deprecated starting with docker-engine 1.12
+			id, err := m.client.CreateContainer(&config, "", nil)
 			if err != nil {
 				errChan <- err
 				return
 			}
-			if err := m.client.StartContainer(id, hostConfig); err != nil {
+			if err := m.client.StartContainer(id, &hostConfig); err != nil {
 				errChan <- err
 				return
 			}
 			resChan <- id
-		}(i)
+		}(i, node)
 	}
 
-	for i := 0; i < numInstances; i++ {
+	for range nodes {
 		select {
 		case id := <-resChan:
 			result.Scaled = append(result.Scaled, id)
@@ -275,6 +446,12 @@ func (m DefaultManager) ScaleContainer(id string, numInstances int) ScaleResult
 		}
 	}
 
+	if len(result.Scaled) > 0 {
+		if err := m.recordScaleRevisions(labels[shipyard.GroupLabel], containerInfo, username); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+	}
+
 	return result
 }
 
@@ -678,14 +855,38 @@ func (m DefaultManager) Nodes() ([]*shipyard.Node, error) {
 		return nil, err
 	}
 
-	nodes, err := parseClusterNodes(info.DriverStatus)
-	if err != nil {
-		return nil, err
+	return m.infoNodes(info), nil
+}
+
+// infoNodes returns the nodes listed in the engine info with their health
+func (m DefaultManager) infoNodes(info *dockerclient.Info) []*shipyard.Node {
+	nodes := m.clusterNodes(info)
+	for _, node := range nodes {
+		withNodeHealth(node, false)
+	}
+
+	return nodes
+}
+
+// clusterNodes returns the nodes of classic swarm or swarm mode clusters
+func (m DefaultManager) clusterNodes(info *dockerclient.Info) []*shipyard.Node {
+	nodes := parseClusterNodes(info.DriverStatus)
+
+	// engines in swarm mode do not list the nodes in the driver status
+	if len(nodes) == 0 {
+		swarmNodes, err := m.swarmModeNodes()
+		if err != nil {
+			log.Debugf("engine is not a swarm mode manager: %s", err)
+			return nodes
+		}
+
+		return swarmNodes
 	}
 
-	return nodes, nil
+	return nodes
 }
 
+// Node returns the node including its recent health history
 func (m DefaultManager) Node(name string) (*shipyard.Node, error) {
 	nodes, err := m.Nodes()
 	if err != nil {
@@ -693,7 +894,8 @@ func (m DefaultManager) Node(name string) (*shipyard.Node, error) {
 	}
 
 	for _, node := range nodes {
-		if node.Name == name {
+		if node.Name == name || (node.ID != "" && node.ID == name) {
+			withNodeHealth(node, true)
 			return node, nil
 		}
 	}
@@ -702,10 +904,18 @@ func (m DefaultManager) Node(name string) (*shipyard.Node, error) {
 }
 
 func (m DefaultManager) AddRegistry(registry *shipyard.Registry) error {
-	resp, err := http.Get(fmt.Sprintf("%s/v1/search", registry.Addr))
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/v1/search", registry.Addr), nil)
 	if err != nil {
 		return err
 	}
+	if registry.HasCredentials() {
+		req.SetBasicAuth(registry.Username, registry.Password)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
 	if resp.StatusCode != 200 {
 		return errors.New(resp.Status)
 	}
@@ -734,6 +944,19 @@ func (m DefaultManager) RemoveRegistry(registry *shipyard.Registry) error {
 	return nil
 }
 
+// loadRegistry returns a registry with a configured client from a stored registry
+func loadRegistry(stored *shipyard.Registry) (*shipyard.Registry, error) {
+	reg, err := shipyard.NewRegistry(stored.ID, stored.Name, stored.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	reg.Roles = stored.Roles
+	reg.SetCredentials(stored.Username, stored.Password, stored.Email)
+
+	return reg, nil
+}
+
 func (m DefaultManager) Registries() ([]*shipyard.Registry, error) {
 	res, err := r.Table(tblNameRegistries).OrderBy(r.Asc("name")).Run(m.session)
 	if err != nil {
@@ -747,7 +970,7 @@ func (m DefaultManager) Registries() ([]*shipyard.Registry, error) {
 
 	registries := []*shipyard.Registry{}
 	for _, r := range regs {
-		reg, err := shipyard.NewRegistry(r.ID, r.Name, r.Addr)
+		reg, err := loadRegistry(r)
 		if err != nil {
 			return nil, err
 		}
@@ -772,7 +995,7 @@ func (m DefaultManager) Registry(name string) (*shipyard.Registry, error) {
 		return nil, err
 	}
 
-	registry, err := shipyard.NewRegistry(reg.ID, reg.Name, reg.Addr)
+	registry, err := loadRegistry(reg)
 	if err != nil {
 		return nil, err
 	}
@@ -780,6 +1003,57 @@ func (m DefaultManager) Registry(name string) (*shipyard.Registry, error) {
 	return registry, nil
 }
 
+// registryRoles returns the roles checked against the roles of registries.
+// Requests without a username (i.e. service keys) have no roles and may
+// only use the credentials of unrestricted registries.
+func (m DefaultManager) registryRoles(username string) ([]string, error) {
+	if username == "" {
+		return nil, nil
+	}
+
+	acct, err := m.Account(username)
+	if err != nil {
+		return nil, err
+	}
+
+	return acct.Roles, nil
+}
+
+// RegistryAuthConfig returns the stored credentials for the registry serving
+// the image.  Nil is returned for images without a matching registry or
+// when the account is not allowed to use the credentials.
+func (m DefaultManager) RegistryAuthConfig(username, image string) (*dockerclient.AuthConfig, error) {
+	host, _, _ := parseImageName(image)
+	if host == "" {
+		return nil, nil
+	}
+
+	registries, err := m.Registries()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, reg := range registries {
+		if reg.Host() != host || !reg.HasCredentials() {
+			continue
+		}
+
+		roles, err := m.registryRoles(username)
+		if err != nil {
+			return nil, err
+		}
+
+		if !reg.CanUseCredentials(roles) {
+			log.Warnf("registry credentials denied: registry=%s username=%s", reg.Name, username)
+			return nil, nil
+		}
+
+		return reg.AuthConfig(), nil
+	}
+
+	return nil, nil
+}
+
 func (m DefaultManager) CreateConsoleSession(c *shipyard.ConsoleSession) error {
 	if _, err := r.Table(tblNameConsole).Insert(c).RunWrite(m.session); err != nil {
 		return err
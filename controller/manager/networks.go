@@ -0,0 +1,214 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard/auth"
+)
+
+var (
+	ErrNetworkDoesNotExist      = errors.New("network does not exist")
+	ErrNetworkNameRequired      = errors.New("network name is required")
+	ErrNetworkPredefined        = errors.New("predefined networks cannot be created or removed")
+	ErrNetworkInUse             = errors.New("network has connected containers")
+	ErrNetworkNotAdmitted       = errors.New("only admins may create overlay networks that are not internal")
+	ErrNetworkContainerRequired = errors.New("container is required")
+	ErrNetworkInvalid           = errors.New("invalid network configuration")
+)
+
+// predefinedNetworks are created by the engine on every node
+var predefinedNetworks = map[string]bool{
+	"bridge":          true,
+	"host":            true,
+	"none":            true,
+	"docker_gwbridge": true,
+	"ingress":         true,
+}
+
+// predefinedNetwork reports whether the network is created by the engine.
+// Networks of classic swarm clusters are prefixed with the node name.
+func predefinedNetwork(name string) bool {
+	return predefinedNetworks[path.Base(name)]
+}
+
+// admitNetwork checks whether the account may create the network.  Overlay
+// networks span every node so only admins may create ones that are not
+// internal.
+func admitNetwork(config *dockerclient.NetworkCreate, acct *auth.Account) error {
+	if config.Name == "" {
+		return ErrNetworkNameRequired
+	}
+
+	if predefinedNetwork(config.Name) {
+		return ErrNetworkPredefined
+	}
+
+	if config.Driver == "overlay" && !config.Internal && !isAdmin(acct) {
+		return ErrNetworkNotAdmitted
+	}
+
+	return nil
+}
+
+// Networks returns the networks of the cluster with their connected
+// containers.  Engines that do not list the containers are asked for each
+// network.
+func (m DefaultManager) Networks() ([]*dockerclient.NetworkResource, error) {
+	networks, err := m.client.ListNetworks("")
+	if err != nil {
+		return nil, err
+	}
+
+	for i, n := range networks {
+		if n.Containers != nil {
+			continue
+		}
+
+		network, err := m.client.InspectNetwork(n.ID)
+		if err != nil {
+			log.Warnf("error inspecting network: id=%s err=%s", n.ID, err)
+			continue
+		}
+		networks[i] = network
+	}
+
+	sort.Sort(networksByName(networks))
+
+	return networks, nil
+}
+
+func (m DefaultManager) Network(id string) (*dockerclient.NetworkResource, error) {
+	network, err := m.client.InspectNetwork(id)
+	if err == dockerclient.ErrNotFound {
+		return nil, ErrNetworkDoesNotExist
+	}
+
+	return network, err
+}
+
+// defaultNetworkDriver returns the driver the engine uses for networks
+// created without one.  Classic swarm creates overlay networks and
+// engines create bridge networks.
+func (m DefaultManager) defaultNetworkDriver() (string, error) {
+	info, err := m.client.Info()
+	if err != nil {
+		return "", err
+	}
+
+	if classicSwarm(info.DriverStatus) {
+		return "overlay", nil
+	}
+
+	return "bridge", nil
+}
+
+// AdmitNetwork runs the admission checks for the user creating the network.
+// A missing driver is set to the one the engine would use.
+func (m DefaultManager) AdmitNetwork(config *dockerclient.NetworkCreate, username string) error {
+	acct, err := m.userAccount(username)
+	if err != nil {
+		return err
+	}
+
+	if config.Driver == "" {
+		driver, err := m.defaultNetworkDriver()
+		if err != nil {
+			return err
+		}
+		config.Driver = driver
+	}
+
+	return admitNetwork(config, acct)
+}
+
+// CreateNetwork creates the network if it passes the admission checks for
+// the user
+func (m DefaultManager) CreateNetwork(config *dockerclient.NetworkCreate, username string) (*dockerclient.NetworkResource, error) {
+	if err := m.AdmitNetwork(config, username); err != nil {
+		return nil, err
+	}
+
+	config.CheckDuplicate = true
+	created, err := m.client.CreateNetwork(config)
+	if err != nil {
+		return nil, err
+	}
+
+	m.logUserEvent("create-network", fmt.Sprintf("id=%s name=%s driver=%s internal=%v", created.ID, config.Name, config.Driver, config.Internal), username, []string{"networks"})
+
+	return m.Network(created.ID)
+}
+
+// RemoveNetwork removes the network.  Networks with connected containers
+// are not removed.
+func (m DefaultManager) RemoveNetwork(id, username string) error {
+	network, err := m.Network(id)
+	if err != nil {
+		return err
+	}
+
+	if predefinedNetwork(network.Name) {
+		return ErrNetworkPredefined
+	}
+
+	if len(network.Containers) > 0 {
+		return ErrNetworkInUse
+	}
+
+	if err := m.client.RemoveNetwork(network.ID); err != nil {
+		return err
+	}
+
+	m.logUserEvent("remove-network", fmt.Sprintf("id=%s name=%s", network.ID, network.Name), username, []string{"networks"})
+
+	return nil
+}
+
+func (m DefaultManager) ConnectNetwork(id, container, username string) error {
+	if container == "" {
+		return ErrNetworkContainerRequired
+	}
+
+	network, err := m.Network(id)
+	if err != nil {
+		return err
+	}
+
+	if err := m.client.ConnectNetwork(network.ID, container); err != nil {
+		return err
+	}
+
+	m.logUserEvent("connect-network", fmt.Sprintf("id=%s name=%s container=%s", network.ID, network.Name, container), username, []string{"networks"})
+
+	return nil
+}
+
+func (m DefaultManager) DisconnectNetwork(id, container string, force bool, username string) error {
+	if container == "" {
+		return ErrNetworkContainerRequired
+	}
+
+	network, err := m.Network(id)
+	if err != nil {
+		return err
+	}
+
+	if err := m.client.DisconnectNetwork(network.ID, container, force); err != nil {
+		return err
+	}
+
+	m.logUserEvent("disconnect-network", fmt.Sprintf("id=%s name=%s container=%s force=%v", network.ID, network.Name, container, force), username, []string{"networks"})
+
+	return nil
+}
+
+type networksByName []*dockerclient.NetworkResource
+
+func (n networksByName) Len() int           { return len(n) }
+func (n networksByName) Swap(i, j int)      { n[i], n[j] = n[j], n[i] }
+func (n networksByName) Less(i, j int) bool { return n[i].Name < n[j].Name }
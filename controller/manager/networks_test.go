@@ -0,0 +1,113 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard/auth"
+)
+
+func TestAdmitNetwork(t *testing.T) {
+	admin := &auth.Account{Username: "admin", Roles: []string{"admin"}}
+	user := &auth.Account{Username: "user", Roles: []string{"networks:rw"}}
+
+	tests := []struct {
+		config *dockerclient.NetworkCreate
+		acct   *auth.Account
+		err    error
+	}{
+		{&dockerclient.NetworkCreate{Name: "public", Driver: "overlay"}, admin, nil},
+		{&dockerclient.NetworkCreate{Name: "public", Driver: "overlay"}, serviceAccount, nil},
+		{&dockerclient.NetworkCreate{Name: "public", Driver: "overlay"}, user, ErrNetworkNotAdmitted},
+		{&dockerclient.NetworkCreate{Name: "backend", Driver: "overlay", Internal: true}, user, nil},
+		{&dockerclient.NetworkCreate{Name: "local", Driver: "bridge"}, user, nil},
+		{&dockerclient.NetworkCreate{Driver: "bridge"}, user, ErrNetworkNameRequired},
+		{&dockerclient.NetworkCreate{Name: "node1/bridge", Driver: "bridge"}, admin, ErrNetworkPredefined},
+	}
+
+	for _, test := range tests {
+		if err := admitNetwork(test.config, test.acct); err != test.err {
+			t.Fatalf("expected %v for %+v; received %v", test.err, test.config, err)
+		}
+	}
+}
+
+func TestNetworksMembers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1.15/networks":
+			fmt.Fprint(w, `[{"Name": "node1/bridge", "Id": "n1", "Containers": {}}, {"Name": "app", "Id": "n2", "Driver": "overlay"}]`)
+		case "/v1.15/networks/n2":
+			fmt.Fprint(w, `{"Name": "app", "Id": "n2", "Driver": "overlay", "IPAM": {"Driver": "default", "Config": [{"Subnet": "10.0.0.0/24"}]}, "Containers": {"c1": {"Name": "web", "IPv4Address": "10.0.0.2/24"}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := dockerclient.NewDockerClient(ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := DefaultManager{client: client}
+
+	networks, err := m.Networks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(networks) != 2 || networks[0].Name != "app" {
+		t.Fatalf("expected networks sorted by name; received %v", networks)
+	}
+
+	if networks[0].Containers["c1"].Name != "web" || networks[0].IPAM.Config[0].Subnet != "10.0.0.0/24" {
+		t.Fatalf("expected members and ipam of inspected network; received %+v", networks[0])
+	}
+
+	if err := m.RemoveNetwork("n2", "admin"); err != ErrNetworkInUse {
+		t.Fatalf("expected ErrNetworkInUse; received %v", err)
+	}
+
+	if err := m.RemoveNetwork("missing", "admin"); err != ErrNetworkDoesNotExist {
+		t.Fatalf("expected ErrNetworkDoesNotExist; received %v", err)
+	}
+}
+
+func TestAdmitNetworkDefaultDriver(t *testing.T) {
+	for _, c := range []struct {
+		driverStatus [][]string
+		driver       string
+	}{
+		{swarm12DriverStatus, "overlay"},
+		{[][]string{{"Backing Filesystem", "extfs"}}, "bridge"},
+	} {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]interface{}{"DriverStatus": c.driverStatus})
+		}))
+
+		client, err := dockerclient.NewDockerClient(ts.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := DefaultManager{client: client}
+
+		config := &dockerclient.NetworkCreate{Name: "public"}
+		if err := m.AdmitNetwork(config, ""); err != nil {
+			t.Fatal(err)
+		}
+
+		if config.Driver != c.driver {
+			t.Fatalf("expected driver %s; received %s", c.driver, config.Driver)
+		}
+
+		if err := admitNetwork(config, &auth.Account{Username: "user"}); c.driver == "overlay" && err != ErrNetworkNotAdmitted {
+			t.Fatalf("expected ErrNetworkNotAdmitted; received %v", err)
+		}
+
+		ts.Close()
+	}
+}
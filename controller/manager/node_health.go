@@ -0,0 +1,257 @@
+package manager
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shipyard/shipyard"
+)
+
+var (
+	nodeHealthInterval = 10 * time.Second
+	nodePingTimeout    = 5 * time.Second
+	// nodeHealthHistory is the number of samples kept per node (one hour)
+	nodeHealthHistory = 360
+
+	nodeHealthStates = &nodeHealthStore{nodes: map[string]*nodeHealth{}}
+)
+
+// nodeHealth is the health of a node as computed by the monitor from its
+// samples
+type nodeHealth struct {
+	health       string
+	responseTime float64
+	uptime       float64
+	lastSeen     time.Time
+	history      []*shipyard.NodeHealthSample
+}
+
+// nodeHealthStore holds the recent ping results of the nodes by name
+type nodeHealthStore struct {
+	sync.Mutex
+	nodes map[string]*nodeHealth
+}
+
+// record adds the sample to the history of the node and returns the
+// previous health.  The previous health is empty for unknown nodes.
+func (s *nodeHealthStore) record(name string, sample *shipyard.NodeHealthSample) string {
+	s.Lock()
+	defer s.Unlock()
+
+	h, ok := s.nodes[name]
+	if !ok {
+		h = &nodeHealth{}
+		s.nodes[name] = h
+	}
+
+	previous := ""
+	if n := len(h.history); n > 0 {
+		previous = h.history[n-1].Health
+	}
+
+	h.history = append(h.history, sample)
+	if len(h.history) > nodeHealthHistory {
+		h.history = h.history[len(h.history)-nodeHealthHistory:]
+	}
+
+	if sample.Health == NodeHealthUp {
+		h.lastSeen = sample.Time
+	}
+
+	up := 0
+	for _, s := range h.history {
+		if s.Health == NodeHealthUp {
+			up++
+		}
+	}
+
+	h.health = sample.Health
+	h.responseTime = sample.ResponseTime
+	h.uptime = float64(up) * 100 / float64(len(h.history))
+
+	return previous
+}
+
+// vanished returns the nodes which are no longer in the cluster and were
+// up when last checked.  Their down event would be lost otherwise.
+func (s *nodeHealthStore) vanished(names map[string]bool) []string {
+	s.Lock()
+	defer s.Unlock()
+
+	result := []string{}
+	for name, h := range s.nodes {
+		if names[name] || len(h.history) == 0 {
+			continue
+		}
+
+		if h.history[len(h.history)-1].Health == NodeHealthUp {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// prune drops the health of nodes which are no longer in the cluster
+func (s *nodeHealthStore) prune(names map[string]bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	for name := range s.nodes {
+		if !names[name] {
+			delete(s.nodes, name)
+		}
+	}
+}
+
+// get returns a copy of the health of the node.  The history is only
+// included if requested.
+func (s *nodeHealthStore) get(name string, history bool) (nodeHealth, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	h, ok := s.nodes[name]
+	if !ok || len(h.history) == 0 {
+		return nodeHealth{}, false
+	}
+
+	health := *h
+	health.history = nil
+	if history {
+		health.history = append([]*shipyard.NodeHealthSample{}, h.history...)
+	}
+
+	return health, true
+}
+
+// withNodeHealth sets the health recorded by the monitor on the node
+func withNodeHealth(node *shipyard.Node, history bool) {
+	h, ok := nodeHealthStates.get(node.Name, history)
+	if !ok {
+		return
+	}
+
+	node.Health = h.health
+	node.ResponseTime = h.responseTime
+	node.Uptime = h.uptime
+	node.LastSeen = h.lastSeen
+	node.HealthHistory = h.history
+}
+
+// engineURL returns the url of the engine api for the node address
+func engineURL(addr string, tls bool) string {
+	if i := strings.Index(addr, "://"); i != -1 {
+		addr = addr[i+3:]
+	}
+
+	if tls {
+		return "https://" + addr
+	}
+
+	return "http://" + addr
+}
+
+// pingEngine pings the engine and returns the sample of the result
+func pingEngine(client *http.Client, url string) *shipyard.NodeHealthSample {
+	sample := &shipyard.NodeHealthSample{
+		Time:   time.Now(),
+		Health: NodeHealthDown,
+	}
+
+	resp, err := client.Get(url + "/_ping")
+	if err != nil {
+		sample.Error = err.Error()
+		return sample
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		sample.Error = fmt.Sprintf("unexpected status: %s", resp.Status)
+		return sample
+	}
+
+	sample.Health = NodeHealthUp
+	sample.ResponseTime = float64(time.Since(sample.Time)) / float64(time.Millisecond)
+
+	return sample
+}
+
+// swarmModeSample returns the sample of a swarm mode node from the state
+// reported by the manager.  The engine api of swarm mode nodes is usually
+// not exposed so they are not pinged.
+func swarmModeSample(node *shipyard.Node) *shipyard.NodeHealthSample {
+	return &shipyard.NodeHealthSample{
+		Time:   time.Now(),
+		Health: node.Health,
+		Error:  node.Error,
+	}
+}
+
+// nodeHealthMonitor pings the engines of the cluster
+func (m DefaultManager) nodeHealthMonitor() {
+	client := &http.Client{
+		Timeout:   nodePingTimeout,
+		Transport: &http.Transport{TLSClientConfig: m.client.TLSConfig},
+	}
+
+	t := time.NewTicker(nodeHealthInterval)
+	for range t.C {
+		m.checkNodes(client)
+	}
+}
+
+func (m DefaultManager) checkNodes(client *http.Client) {
+	info, err := m.client.Info()
+	if err != nil {
+		log.Warnf("node health monitor: error getting cluster info: %s", err)
+		return
+	}
+
+	nodes := m.clusterNodes(info)
+
+	names := map[string]bool{}
+	var wg sync.WaitGroup
+	for _, node := range nodes {
+		names[node.Name] = true
+
+		wg.Add(1)
+		go func(node *shipyard.Node) {
+			defer wg.Done()
+
+			var sample *shipyard.NodeHealthSample
+			if node.ID != "" {
+				sample = swarmModeSample(node)
+			} else {
+				sample = pingEngine(client, engineURL(node.Addr, m.client.TLSConfig != nil))
+			}
+			previous := nodeHealthStates.record(node.Name, sample)
+			// a node found up is not a transition
+			if sample.Health == previous || (previous == "" && sample.Health == NodeHealthUp) {
+				return
+			}
+
+			msg := fmt.Sprintf("node=%s addr=%s", node.Name, node.Addr)
+			if sample.Error != "" {
+				msg += " error=" + sample.Error
+			}
+
+			log.Infof("node %s: %s", sample.Health, msg)
+			m.logEvent("node-"+sample.Health, msg, []string{"nodes"})
+		}(node)
+	}
+	wg.Wait()
+
+	for _, name := range nodeHealthStates.vanished(names) {
+		msg := fmt.Sprintf("node=%s error=removed from the cluster", name)
+		log.Infof("node %s: %s", NodeHealthDown, msg)
+		m.logEvent("node-"+NodeHealthDown, msg, []string{"nodes"})
+	}
+
+	nodeHealthStates.prune(names)
+}
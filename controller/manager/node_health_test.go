@@ -0,0 +1,210 @@
+package manager
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+func TestNodeHealthStore(t *testing.T) {
+	store := &nodeHealthStore{nodes: map[string]*nodeHealth{}}
+	now := time.Now()
+
+	samples := []struct {
+		health   string
+		previous string
+	}{
+		{NodeHealthUp, ""},
+		{NodeHealthUp, NodeHealthUp},
+		{NodeHealthDown, NodeHealthUp},
+		{NodeHealthUp, NodeHealthDown},
+	}
+
+	for i, s := range samples {
+		sample := &shipyard.NodeHealthSample{
+			Time:         now.Add(time.Duration(i) * time.Second),
+			Health:       s.health,
+			ResponseTime: float64(i),
+		}
+		if previous := store.record("node-1", sample); previous != s.previous {
+			t.Fatalf("sample %d: expected previous health %q; received %q", i, s.previous, previous)
+		}
+	}
+
+	h, ok := store.get("node-1", false)
+	if !ok {
+		t.Fatal("expected health of node-1")
+	}
+
+	if h.health != NodeHealthUp || h.uptime != 75 || h.responseTime != 3 {
+		t.Fatalf("unexpected node health: health=%s uptime=%f response_time=%f", h.health, h.uptime, h.responseTime)
+	}
+
+	if !h.lastSeen.Equal(now.Add(3 * time.Second)) {
+		t.Fatalf("expected last seen at the last sample; received %s", h.lastSeen)
+	}
+
+	if h.history != nil {
+		t.Fatal("expected history to be omitted")
+	}
+
+	h, _ = store.get("node-1", true)
+	if len(h.history) != 4 {
+		t.Fatalf("expected 4 samples; received %d", len(h.history))
+	}
+
+	store.prune(map[string]bool{})
+	if len(store.nodes) != 0 {
+		t.Fatal("expected removed node to be pruned")
+	}
+}
+
+func TestNodeHealthStoreVanished(t *testing.T) {
+	store := &nodeHealthStore{nodes: map[string]*nodeHealth{}}
+	store.record("node-1", &shipyard.NodeHealthSample{Health: NodeHealthUp})
+	store.record("node-2", &shipyard.NodeHealthSample{Health: NodeHealthUp})
+	store.record("node-3", &shipyard.NodeHealthSample{Health: NodeHealthDown})
+
+	// node-3 was reported down already
+	vanished := store.vanished(map[string]bool{"node-1": true})
+	if len(vanished) != 1 || vanished[0] != "node-2" {
+		t.Fatalf("expected node-2 to have vanished; received %v", vanished)
+	}
+
+	store.prune(map[string]bool{"node-1": true})
+	if len(store.vanished(map[string]bool{"node-1": true})) != 0 {
+		t.Fatal("expected pruned nodes not to vanish again")
+	}
+}
+
+func TestNodesHealth(t *testing.T) {
+	defer func(states *nodeHealthStore) { nodeHealthStates = states }(nodeHealthStates)
+	nodeHealthStates = &nodeHealthStore{nodes: map[string]*nodeHealth{}}
+	nodeHealthStates.record("node-1", &shipyard.NodeHealthSample{Health: NodeHealthUp, ResponseTime: 2})
+	nodeHealthStates.record("node-1", &shipyard.NodeHealthSample{Health: NodeHealthDown})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"DriverStatus": swarm12DriverStatus})
+	}))
+	defer ts.Close()
+
+	client, err := dockerclient.NewDockerClient(ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := DefaultManager{client: client}
+
+	nodes, err := m.Nodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nodes) != 1 || nodes[0].Health != NodeHealthDown || nodes[0].Uptime != 50 || nodes[0].HealthHistory != nil {
+		t.Fatalf("expected health of node-1 without history; received %+v", nodes)
+	}
+
+	node, err := m.Node("node-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(node.HealthHistory) != 2 {
+		t.Fatalf("expected the history of node-1; received %v", node.HealthHistory)
+	}
+}
+
+func TestNodeHealthStoreHistoryLimit(t *testing.T) {
+	store := &nodeHealthStore{nodes: map[string]*nodeHealth{}}
+
+	for i := 0; i < nodeHealthHistory+10; i++ {
+		store.record("node-1", &shipyard.NodeHealthSample{Health: NodeHealthDown})
+	}
+
+	if n := len(store.nodes["node-1"].history); n != nodeHealthHistory {
+		t.Fatalf("expected %d samples; received %d", nodeHealthHistory, n)
+	}
+
+	if !store.nodes["node-1"].lastSeen.IsZero() {
+		t.Fatal("expected node never to be seen")
+	}
+}
+
+func TestPingEngine(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_ping" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("OK"))
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Timeout: time.Second}
+
+	if sample := pingEngine(client, ts.URL); sample.Health != NodeHealthUp || sample.Error != "" {
+		t.Fatalf("expected node to be up; received %+v", sample)
+	}
+
+	if sample := pingEngine(client, ts.URL+"/broken"); sample.Health != NodeHealthDown || sample.Error == "" {
+		t.Fatalf("expected node to be down; received %+v", sample)
+	}
+}
+
+func TestEngineURL(t *testing.T) {
+	for addr, expected := range map[string]string{
+		"10.0.0.1:2375":        "http://10.0.0.1:2375",
+		"tcp://127.0.0.1:3375": "http://127.0.0.1:3375",
+	} {
+		if u := engineURL(addr, false); u != expected {
+			t.Errorf("expected %s for %s; received %s", expected, addr, u)
+		}
+	}
+
+	if u := engineURL("10.0.0.1:2376", true); u != "https://10.0.0.1:2376" {
+		t.Errorf("expected https url; received %s", u)
+	}
+}
+
+func TestSwarmModeNodesHealth(t *testing.T) {
+	defer func(states *nodeHealthStore) { nodeHealthStates = states }(nodeHealthStates)
+	nodeHealthStates = &nodeHealthStore{nodes: map[string]*nodeHealth{}}
+
+	ts := swarmModeEngine(t)
+	defer ts.Close()
+	m := getSwarmModeManager(t, ts.URL)
+
+	info, err := m.client.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := m.clusterNodes(info)
+
+	// the monitor records the state reported by the manager
+	for _, node := range nodes {
+		sample := swarmModeSample(node)
+		if node.Name == "worker1" && (sample.Health != NodeHealthDown || sample.Error != "heartbeat failure") {
+			t.Fatalf("expected worker1 to be down; received %+v", sample)
+		}
+		nodeHealthStates.record(node.Name, sample)
+	}
+
+	nodes, err = m.Nodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, node := range nodes {
+		if node.Name == "manager1" && (node.Uptime != 100 || node.LastSeen.IsZero()) {
+			t.Fatalf("expected uptime of manager1; received %+v", node)
+		}
+		if node.Name == "worker1" && node.Uptime != 0 {
+			t.Fatalf("expected no uptime of worker1; received %+v", node)
+		}
+	}
+}
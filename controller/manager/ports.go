@@ -0,0 +1,209 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/samalba/dockerclient"
+)
+
+// PortStrategy selects how replicas of a container publishing fixed host
+// ports are created
+type PortStrategy string
+
+const (
+	// PortsAffinity keeps the host ports and only schedules replicas on
+	// nodes where all of them are free.  Every node can run one replica.
+	PortsAffinity PortStrategy = "affinity"
+	// PortsDynamic publishes the ports of replicas on random host ports
+	PortsDynamic PortStrategy = "dynamic"
+)
+
+// ParsePortStrategy returns the strategy with the name.  An empty name
+// selects PortsAffinity.
+func ParsePortStrategy(name string) (PortStrategy, error) {
+	switch PortStrategy(name) {
+	case "", PortsAffinity:
+		return PortsAffinity, nil
+	case PortsDynamic:
+		return PortsDynamic, nil
+	}
+
+	return "", fmt.Errorf("unknown port strategy: %s (expected %s or %s)", name, PortsAffinity, PortsDynamic)
+}
+
+// PortReport describes the fixed host ports of a scaled container.
+// Available lists the nodes which had room for them and Conflicts lists the
+// ports already in use on the others.
+type PortReport struct {
+	Strategy  PortStrategy
+	Ports     []string
+	Available []string
+	Conflicts map[string][]string
+}
+
+// fixedHostPorts returns the host ports bound by the host config (i.e.
+// "80/tcp").  Bindings without a host port are published dynamically and
+// are not returned.
+func fixedHostPorts(hostConfig *dockerclient.HostConfig) []string {
+	ports := []string{}
+	if hostConfig == nil {
+		return ports
+	}
+
+	seen := map[string]bool{}
+	for key, bindings := range hostConfig.PortBindings {
+		proto := "tcp"
+		if i := strings.Index(key, "/"); i != -1 {
+			proto = key[i+1:]
+		}
+
+		for _, b := range bindings {
+			if b.HostPort == "" || b.HostPort == "0" {
+				continue
+			}
+
+			port := b.HostPort + "/" + proto
+			if !seen[port] {
+				seen[port] = true
+				ports = append(ports, port)
+			}
+		}
+	}
+
+	sort.Strings(ports)
+
+	return ports
+}
+
+// withDynamicPorts returns a copy of the host config publishing all ports on
+// random host ports
+func withDynamicPorts(hostConfig dockerclient.HostConfig) dockerclient.HostConfig {
+	bindings := map[string][]dockerclient.PortBinding{}
+	for key, b := range hostConfig.PortBindings {
+		dynamic := make([]dockerclient.PortBinding, len(b))
+		for i := range b {
+			dynamic[i] = dockerclient.PortBinding{HostIp: b[i].HostIp}
+		}
+		bindings[key] = dynamic
+	}
+
+	hostConfig.PortBindings = bindings
+
+	return hostConfig
+}
+
+// usedHostPorts returns the host ports published by the containers per node
+func usedHostPorts(containers []dockerclient.Container) map[string]map[string]bool {
+	used := map[string]map[string]bool{}
+	for _, c := range containers {
+		node := containerNode(c)
+		for _, p := range c.Ports {
+			if p.PublicPort == 0 {
+				continue
+			}
+
+			if used[node] == nil {
+				used[node] = map[string]bool{}
+			}
+			used[node][fmt.Sprintf("%d/%s", p.PublicPort, p.Type)] = true
+		}
+	}
+
+	return used
+}
+
+// portRoom checks which nodes have all of the ports free
+func portRoom(nodes, ports []string, used map[string]map[string]bool) ([]string, map[string][]string) {
+	available := []string{}
+	conflicts := map[string][]string{}
+
+	for _, node := range nodes {
+		for _, port := range ports {
+			if used[node][port] {
+				conflicts[node] = append(conflicts[node], port)
+			}
+		}
+
+		if len(conflicts[node]) == 0 {
+			available = append(available, node)
+		}
+	}
+
+	sort.Strings(available)
+
+	return available, conflicts
+}
+
+// portReport checks which nodes have room for the fixed host ports.  It
+// returns nil if no fixed host ports are published.
+func (m DefaultManager) portReport(hostConfig *dockerclient.HostConfig, strategy PortStrategy, nodes []string) (*PortReport, error) {
+	ports := fixedHostPorts(hostConfig)
+	if len(ports) == 0 {
+		return nil, nil
+	}
+
+	report := &PortReport{
+		Strategy:  strategy,
+		Ports:     ports,
+		Available: []string{},
+		Conflicts: map[string][]string{},
+	}
+
+	if strategy == PortsDynamic {
+		return report, nil
+	}
+
+	containers, err := m.client.ListContainers(false, false, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// a single engine is reported as a node without a name
+	if len(nodes) == 0 {
+		nodes = []string{""}
+	}
+
+	report.Available, report.Conflicts = portRoom(nodes, ports, usedHostPorts(containers))
+
+	return report, nil
+}
+
+// nodeNames returns the names of the cluster nodes new containers may be
+// placed on.  Nodes in maintenance are left out and a single engine has no
+// named nodes.
+func (m DefaultManager) nodeNames() []string {
+	names := []string{}
+
+	nodes, err := m.Nodes()
+	if err != nil {
+		return names
+	}
+
+	maintenanceNodes, err := m.maintenanceNodeNames()
+	if err != nil {
+		log.Error(err)
+		return names
+	}
+
+	maintenance := map[string]bool{}
+	for _, n := range maintenanceNodes {
+		maintenance[n] = true
+	}
+
+	for _, n := range nodes {
+		if !maintenance[n.Name] {
+			names = append(names, n.Name)
+		}
+	}
+
+	return names
+}
+
+// errNoPortRoom is returned for replicas which could not be placed because
+// no node has the host ports free
+func errNoPortRoom(report *PortReport) error {
+	return fmt.Errorf("no node has room for host ports %s; scale with dynamic ports instead", strings.Join(report.Ports, ", "))
+}
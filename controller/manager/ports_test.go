@@ -0,0 +1,84 @@
+package manager
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/samalba/dockerclient"
+)
+
+func TestParsePortStrategy(t *testing.T) {
+	for name, expected := range map[string]PortStrategy{
+		"":         PortsAffinity,
+		"affinity": PortsAffinity,
+		"dynamic":  PortsDynamic,
+	} {
+		s, err := ParsePortStrategy(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s != expected {
+			t.Fatalf("expected %s for %q; received %s", expected, name, s)
+		}
+	}
+
+	if _, err := ParsePortStrategy("random"); err == nil {
+		t.Fatal("expected error for unknown strategy")
+	}
+}
+
+func TestFixedHostPorts(t *testing.T) {
+	hostConfig := &dockerclient.HostConfig{
+		PortBindings: map[string][]dockerclient.PortBinding{
+			"80/tcp":  {{HostPort: "8080"}, {HostIp: "127.0.0.1", HostPort: "8080"}},
+			"443/tcp": {{HostPort: ""}},
+			"53/udp":  {{HostPort: "53"}},
+			"22/tcp":  {{HostPort: "0"}},
+		},
+	}
+
+	expected := []string{"53/udp", "8080/tcp"}
+	if ports := fixedHostPorts(hostConfig); !reflect.DeepEqual(ports, expected) {
+		t.Fatalf("expected %v; received %v", expected, ports)
+	}
+}
+
+func TestWithDynamicPorts(t *testing.T) {
+	hostConfig := dockerclient.HostConfig{
+		PortBindings: map[string][]dockerclient.PortBinding{
+			"80/tcp": {{HostIp: "127.0.0.1", HostPort: "8080"}},
+		},
+	}
+
+	dynamic := withDynamicPorts(hostConfig)
+	if len(fixedHostPorts(&dynamic)) != 0 {
+		t.Fatalf("expected no fixed host ports; received %v", dynamic.PortBindings)
+	}
+
+	if dynamic.PortBindings["80/tcp"][0].HostIp != "127.0.0.1" {
+		t.Fatal("expected host ip to be kept")
+	}
+
+	if hostConfig.PortBindings["80/tcp"][0].HostPort != "8080" {
+		t.Fatal("expected original host config not to be modified")
+	}
+}
+
+func TestPortRoom(t *testing.T) {
+	containers := []dockerclient.Container{
+		{Names: []string{"/node-1/web"}, Ports: []dockerclient.Port{{PrivatePort: 80, PublicPort: 8080, Type: "tcp"}}},
+		{Names: []string{"/node-2/dns"}, Ports: []dockerclient.Port{{PrivatePort: 53, PublicPort: 53, Type: "udp"}}},
+		{Names: []string{"/node-3/app"}, Ports: []dockerclient.Port{{PrivatePort: 80, Type: "tcp"}}},
+	}
+
+	available, conflicts := portRoom([]string{"node-3", "node-2", "node-1"}, []string{"8080/tcp"}, usedHostPorts(containers))
+
+	if !reflect.DeepEqual(available, []string{"node-2", "node-3"}) {
+		t.Fatalf("expected node-2 and node-3 to have room; received %v", available)
+	}
+
+	if !reflect.DeepEqual(conflicts, map[string][]string{"node-1": {"8080/tcp"}}) {
+		t.Fatalf("expected conflict on node-1; received %v", conflicts)
+	}
+}
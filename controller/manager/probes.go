@@ -0,0 +1,320 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+var (
+	// probeTimeout limits a single health probe
+	probeTimeout = 5 * time.Second
+)
+
+// containerPortKey returns the port with the protocol (i.e. "80/tcp")
+func containerPortKey(port string) string {
+	if !strings.Contains(port, "/") {
+		return port + "/tcp"
+	}
+
+	return port
+}
+
+func validateProbe(probe *shipyard.HealthProbe) error {
+	switch probe.Type {
+	case shipyard.HealthProbeHTTP, shipyard.HealthProbeTCP:
+		if probe.Port == "" {
+			return fmt.Errorf("health probe port is required")
+		}
+	case shipyard.HealthProbeExec:
+		if strings.TrimSpace(probe.Command) == "" {
+			return fmt.Errorf("health probe command is required")
+		}
+	default:
+		return fmt.Errorf("unknown health probe type: %s", probe.Type)
+	}
+
+	switch probe.Action {
+	case "", shipyard.HealthActionRestart, shipyard.HealthActionReplace:
+	default:
+		return fmt.Errorf("unknown health action: %s", probe.Action)
+	}
+
+	if probe.Interval < 0 || probe.Threshold < 0 {
+		return fmt.Errorf("health probe interval and threshold must not be negative")
+	}
+
+	return nil
+}
+
+// probeFromLabels returns the health probe configured by the container
+// labels or nil if the container has none
+func probeFromLabels(labels map[string]string) (*shipyard.HealthProbe, error) {
+	if labels[shipyard.HealthTypeLabel] == "" {
+		return nil, nil
+	}
+
+	probe := &shipyard.HealthProbe{
+		Type:    labels[shipyard.HealthTypeLabel],
+		Port:    labels[shipyard.HealthPortLabel],
+		Path:    labels[shipyard.HealthPathLabel],
+		Command: labels[shipyard.HealthCommandLabel],
+		Action:  labels[shipyard.HealthActionLabel],
+	}
+
+	for label, v := range map[string]*int{
+		shipyard.HealthIntervalLabel:  &probe.Interval,
+		shipyard.HealthThresholdLabel: &probe.Threshold,
+	} {
+		if labels[label] == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(labels[label])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s label: %s", label, labels[label])
+		}
+		*v = n
+	}
+
+	if err := validateProbe(probe); err != nil {
+		return nil, err
+	}
+
+	return probe, nil
+}
+
+// probeLabels returns the labels configuring the health probe
+func probeLabels(probe *shipyard.HealthProbe) map[string]string {
+	labels := map[string]string{
+		shipyard.HealthTypeLabel: probe.Type,
+	}
+
+	for label, v := range map[string]string{
+		shipyard.HealthPortLabel:    probe.Port,
+		shipyard.HealthPathLabel:    probe.Path,
+		shipyard.HealthCommandLabel: probe.Command,
+		shipyard.HealthActionLabel:  probe.Action,
+	} {
+		if v != "" {
+			labels[label] = v
+		}
+	}
+
+	if probe.Interval > 0 {
+		labels[shipyard.HealthIntervalLabel] = strconv.Itoa(probe.Interval)
+	}
+	if probe.Threshold > 0 {
+		labels[shipyard.HealthThresholdLabel] = strconv.Itoa(probe.Threshold)
+	}
+
+	return labels
+}
+
+// probeAddress returns the address the container port is reachable at.
+// Ports published on all interfaces are reached through the host of the
+// node running the container.
+func probeAddress(info *dockerclient.ContainerInfo, port, nodeHost string) (string, error) {
+	key := containerPortKey(port)
+
+	for _, b := range info.NetworkSettings.Ports[key] {
+		if b.HostPort == "" {
+			continue
+		}
+
+		host := b.HostIp
+		if host == "" || host == "0.0.0.0" {
+			host = nodeHost
+		}
+
+		if host != "" {
+			return net.JoinHostPort(host, b.HostPort), nil
+		}
+	}
+
+	containerPort := strings.SplitN(key, "/", 2)[0]
+	if ip := info.NetworkSettings.IPAddress; ip != "" {
+		return net.JoinHostPort(ip, containerPort), nil
+	}
+
+	for _, n := range info.NetworkSettings.Networks {
+		if n != nil && n.IPAddress != "" {
+			return net.JoinHostPort(n.IPAddress, containerPort), nil
+		}
+	}
+
+	return "", fmt.Errorf("port %s is not reachable", port)
+}
+
+// runProbe checks the address with the health probe
+func runProbe(probe *shipyard.HealthProbe, addr string) error {
+	switch probe.Type {
+	case shipyard.HealthProbeTCP:
+		conn, err := net.DialTimeout("tcp", addr, probeTimeout)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	case shipyard.HealthProbeHTTP:
+		path := probe.Path
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+
+		client := &http.Client{Timeout: probeTimeout}
+		resp, err := client.Get("http://" + addr + path)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+			return fmt.Errorf("unexpected status: %s", resp.Status)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unknown health probe type: %s", probe.Type)
+}
+
+// checkProbe runs the health probe against the running container
+func (m DefaultManager) checkProbe(info *dockerclient.ContainerInfo, probe *shipyard.HealthProbe, nodeHost string) error {
+	if probe.Type == shipyard.HealthProbeExec {
+		return m.execProbe(info.Id, probe.Command)
+	}
+
+	addr, err := probeAddress(info, probe.Port, nodeHost)
+	if err != nil {
+		return err
+	}
+
+	return runProbe(probe, addr)
+}
+
+// execProbe runs the command in the container and expects it to exit with 0
+func (m DefaultManager) execProbe(id, command string) error {
+	config := &dockerclient.ExecConfig{
+		Container:    id,
+		Cmd:          []string{"sh", "-c", command},
+		AttachStdout: true,
+		AttachStderr: true,
+	}
+
+	execID, err := m.client.ExecCreate(config)
+	if err != nil {
+		return err
+	}
+
+	if err := m.execStart(execID, config, probeTimeout); err != nil {
+		if e, ok := err.(net.Error); ok && e.Timeout() {
+			return fmt.Errorf("command did not finish within %s", probeTimeout)
+		}
+		return err
+	}
+
+	code, err := m.execExitCode(execID)
+	if err != nil {
+		return err
+	}
+
+	if code != 0 {
+		return fmt.Errorf("command exited with code %d", code)
+	}
+
+	return nil
+}
+
+// execStart runs the exec instance until its command exits.  The request
+// is cancelled at the timeout so that hanging commands do not keep it open.
+func (m DefaultManager) execStart(execID string, config *dockerclient.ExecConfig, timeout time.Duration) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("%s/%s/exec/%s/start", m.client.URL.String(), dockerclient.APIVersion, execID)
+	req, err := http.NewRequest("POST", uri, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := *m.client.HTTPClient
+	client.Timeout = timeout
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("error starting exec: %s", strings.TrimSpace(string(msg)))
+	}
+
+	// the output is not needed but the command runs until it is read
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	return err
+}
+
+// execExitCode returns the exit code of a finished exec.  The vendored
+// client does not support inspecting execs.
+func (m DefaultManager) execExitCode(execID string) (int, error) {
+	uri := fmt.Sprintf("%s/%s/exec/%s/json", m.client.URL.String(), dockerclient.APIVersion, execID)
+	resp, err := m.client.HTTPClient.Get(uri)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("error inspecting exec: %s", resp.Status)
+	}
+
+	var inspect struct {
+		ExitCode int
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&inspect); err != nil {
+		return 0, err
+	}
+
+	return inspect.ExitCode, nil
+}
+
+// nodeHosts returns the host of every node.  A single engine is returned as
+// a node without a name.
+func (m DefaultManager) nodeHosts() map[string]string {
+	hosts := map[string]string{}
+
+	nodes, err := m.Nodes()
+	if err == nil {
+		for _, n := range nodes {
+			host, _, err := net.SplitHostPort(n.Addr)
+			if err != nil {
+				host = n.Addr
+			}
+			hosts[n.Name] = host
+		}
+	}
+
+	if len(hosts) == 0 && m.client.URL != nil {
+		if host, _, err := net.SplitHostPort(m.client.URL.Host); err == nil {
+			hosts[""] = host
+		}
+	}
+
+	return hosts
+}
@@ -0,0 +1,113 @@
+package manager
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+func TestProbeAddress(t *testing.T) {
+	info := &dockerclient.ContainerInfo{}
+	info.NetworkSettings.IPAddress = "172.17.0.2"
+	info.NetworkSettings.Ports = map[string][]dockerclient.PortBinding{
+		"80/tcp":  {{HostIp: "0.0.0.0", HostPort: "32768"}},
+		"443/tcp": {{HostIp: "10.0.0.5", HostPort: "8443"}},
+	}
+
+	for port, expected := range map[string]string{
+		"80":      "192.168.1.10:32768",
+		"443/tcp": "10.0.0.5:8443",
+		"8080":    "172.17.0.2:8080",
+	} {
+		addr, err := probeAddress(info, port, "192.168.1.10")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if addr != expected {
+			t.Fatalf("expected %s for port %s; received %s", expected, port, addr)
+		}
+	}
+}
+
+func TestRunProbeHTTP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	addr := strings.TrimPrefix(ts.URL, "http://")
+
+	if err := runProbe(&shipyard.HealthProbe{Type: shipyard.HealthProbeHTTP, Path: "health"}, addr); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runProbe(&shipyard.HealthProbe{Type: shipyard.HealthProbeHTTP, Path: "/"}, addr); err == nil {
+		t.Fatal("expected error for unhealthy response")
+	}
+}
+
+func TestRunProbeTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := l.Addr().String()
+	if err := runProbe(&shipyard.HealthProbe{Type: shipyard.HealthProbeTCP}, addr); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Close()
+	if err := runProbe(&shipyard.HealthProbe{Type: shipyard.HealthProbeTCP}, addr); err == nil {
+		t.Fatal("expected error for closed port")
+	}
+}
+
+func TestExecProbeTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { probeTimeout = timeout }(probeTimeout)
+	probeTimeout = 50 * time.Millisecond
+
+	cancelled := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1.15/containers/c1/exec":
+			fmt.Fprint(w, `{"Id": "e1"}`)
+		case "/v1.15/exec/e1/start":
+			// the command never finishes
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			close(cancelled)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := dockerclient.NewDockerClient(ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := DefaultManager{client: client}
+
+	err = m.execProbe("c1", "sleep 3600")
+	if err == nil || !strings.Contains(err.Error(), "did not finish") {
+		t.Fatalf("expected timeout error; received %v", err)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("expected the exec request to be cancelled")
+	}
+}
@@ -0,0 +1,337 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+const (
+	// swarm reports the containers of unreachable nodes with this status
+	hostDownStatus = "Host Down"
+)
+
+var (
+	ErrReconcilePaused = errors.New("reconciliation is paused for the application")
+
+	reconcileInterval = 30 * time.Second
+
+	// applicationOperations tracks the applications changed by a running
+	// operation.  The reconciler leaves them alone until it ended.
+	applicationOperations = &operationSet{active: map[string]int{}}
+
+	// applicationDrifts are the drifts last reported by the reconciler
+	applicationDrifts = &driftSet{last: map[string]string{}}
+)
+
+type operationSet struct {
+	sync.Mutex
+	active map[string]int
+}
+
+// begin marks an operation on the application as running.  The returned
+// function ends it.
+func (o *operationSet) begin(name string) func() {
+	o.Lock()
+	o.active[name]++
+	o.Unlock()
+
+	return o.end(name)
+}
+
+// tryBegin begins an operation unless one is already running
+func (o *operationSet) tryBegin(name string) (func(), bool) {
+	o.Lock()
+	defer o.Unlock()
+
+	if o.active[name] > 0 {
+		return nil, false
+	}
+	o.active[name]++
+
+	return o.end(name), true
+}
+
+func (o *operationSet) end(name string) func() {
+	return func() {
+		o.Lock()
+		if o.active[name]--; o.active[name] <= 0 {
+			delete(o.active, name)
+		}
+		o.Unlock()
+	}
+}
+
+// driftSet remembers the last drift reported for each application so that
+// drift which persists is recorded only once
+type driftSet struct {
+	sync.Mutex
+	last map[string]string
+}
+
+// changed records the drift of the application and reports whether it
+// differs from the last one.  An empty drift clears the application.
+func (d *driftSet) changed(name, drift string) bool {
+	d.Lock()
+	defer d.Unlock()
+
+	if drift == "" {
+		delete(d.last, name)
+		return false
+	}
+
+	if d.last[name] == drift {
+		return false
+	}
+	d.last[name] = drift
+
+	return true
+}
+
+// beginContainerOperation marks the application of the container as changed
+func beginContainerOperation(info *dockerclient.ContainerInfo) func() {
+	if name := info.Config.Labels[shipyard.ApplicationLabel]; name != "" {
+		return applicationOperations.begin(name)
+	}
+
+	return func() {}
+}
+
+// beginContainersOperation marks the applications of the containers as
+// changed
+func beginContainersOperation(containers []dockerclient.Container) func() {
+	ends := []func(){}
+	seen := map[string]bool{}
+	for _, c := range containers {
+		name := c.Labels[shipyard.ApplicationLabel]
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		ends = append(ends, applicationOperations.begin(name))
+	}
+
+	return func() {
+		for _, end := range ends {
+			end()
+		}
+	}
+}
+
+// computeDrift returns the desired containers which do not exist, the
+// existing containers which are not desired and the containers on
+// unreachable nodes.  Unreachable containers can be neither removed nor
+// replaced under their name so they count as present until swarm drops
+// them.
+func computeDrift(desired []*shipyard.RevisionContainer, actual []dockerclient.Container) ([]*shipyard.RevisionContainer, []dockerclient.Container, []dockerclient.Container) {
+	wanted := map[string]bool{}
+	for _, c := range desired {
+		wanted[c.Name] = true
+	}
+
+	present := map[string]bool{}
+	extra := []dockerclient.Container{}
+	unreachable := []dockerclient.Container{}
+	for _, c := range actual {
+		name := containerName(c)
+		if c.Status == hostDownStatus {
+			unreachable = append(unreachable, c)
+			present[name] = true
+			continue
+		}
+
+		if !wanted[name] || present[name] {
+			extra = append(extra, c)
+			continue
+		}
+		present[name] = true
+	}
+
+	missing := []*shipyard.RevisionContainer{}
+	for _, c := range desired {
+		if !present[c.Name] {
+			missing = append(missing, c)
+		}
+	}
+
+	sort.Sort(containersByName(extra))
+	sort.Sort(containersByName(unreachable))
+
+	return missing, extra, unreachable
+}
+
+// SetReconcilePaused pauses or resumes the reconciliation of the application
+func (m DefaultManager) SetReconcilePaused(app *shipyard.Application, paused bool, username string) error {
+	if app.ID == "" {
+		return ErrApplicationNotDeployed
+	}
+
+	if _, err := r.Table(tblNameApplications).Get(app.ID).Update(map[string]interface{}{
+		"reconcile_paused": paused,
+	}).RunWrite(m.session); err != nil {
+		return err
+	}
+
+	app.ReconcilePaused = paused
+
+	m.logUserEvent("reconcile-application", fmt.Sprintf("name=%s paused=%t", app.Name, paused), username, []string{"applications"})
+
+	return nil
+}
+
+// ReconcileApplication brings the containers of the application back to its
+// latest revision
+func (m DefaultManager) ReconcileApplication(app *shipyard.Application, username string) (*shipyard.ApplicationDrift, error) {
+	if app.ID == "" {
+		return nil, ErrApplicationNotDeployed
+	}
+
+	if app.ReconcilePaused {
+		return nil, ErrReconcilePaused
+	}
+
+	end, ok := applicationOperations.tryBegin(app.Name)
+	if !ok {
+		return nil, fmt.Errorf("application %s is being changed", app.Name)
+	}
+	defer end()
+
+	rev, err := m.latestRevision(shipyard.RevisionApplication, app.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	actual, err := m.applicationContainers(app.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	missing, extra, unreachable := computeDrift(rev.Containers, actual)
+
+	drift := &shipyard.ApplicationDrift{
+		Application: app.Name,
+		Revision:    rev.Number,
+		Missing:     []string{},
+		Extra:       []string{},
+		Unreachable: []string{},
+		Created:     []string{},
+		Removed:     []string{},
+		Errors:      []string{},
+		Time:        time.Now(),
+	}
+
+	for _, c := range missing {
+		drift.Missing = append(drift.Missing, c.Name)
+	}
+	for _, c := range extra {
+		drift.Extra = append(drift.Extra, containerName(c))
+	}
+	for _, c := range unreachable {
+		drift.Unreachable = append(drift.Unreachable, containerName(c))
+	}
+
+	if len(unreachable) > 0 {
+		log.Warnf("application has containers on unreachable nodes: name=%s containers=%s", app.Name, strings.Join(drift.Unreachable, ","))
+	}
+
+	if len(missing) == 0 && len(extra) == 0 {
+		applicationDrifts.changed(app.Name, "")
+		return drift, nil
+	}
+
+	// drift which cannot be corrected is found again on every run
+	details := fmt.Sprintf("name=%s revision=%d missing=%s extra=%s", app.Name, rev.Number, strings.Join(drift.Missing, ","), strings.Join(drift.Extra, ","))
+	report := applicationDrifts.changed(app.Name, details)
+	if report {
+		m.logUserEvent("application-drift", details, username, []string{"applications", "drift"})
+	}
+
+	// remove first so that names and host ports are free again
+	for _, c := range extra {
+		if err := m.client.RemoveContainer(c.Id, true, false); err != nil {
+			drift.Errors = append(drift.Errors, fmt.Sprintf("%s: %s", containerName(c), strings.TrimSpace(err.Error())))
+			continue
+		}
+		drift.Removed = append(drift.Removed, containerName(c))
+	}
+
+	nodes := map[string]bool{}
+	for _, n := range m.nodeNames() {
+		nodes[n] = true
+	}
+
+	for _, c := range missing {
+		rc := *c
+		// the node the container ran on is gone
+		if len(nodes) > 0 && !nodes[rc.Node] {
+			rc.Node = ""
+		}
+
+		if err := m.recreateContainer(&rc, username); err != nil {
+			drift.Errors = append(drift.Errors, fmt.Sprintf("%s: %s", c.Name, strings.TrimSpace(err.Error())))
+			continue
+		}
+		drift.Created = append(drift.Created, c.Name)
+	}
+
+	details = fmt.Sprintf("name=%s revision=%d created=%d removed=%d errors=%d", app.Name, rev.Number, len(drift.Created), len(drift.Removed), len(drift.Errors))
+	if report || len(drift.Errors) == 0 {
+		m.logUserEvent("reconcile-application", details, username, []string{"applications", "drift"})
+	} else {
+		log.Debugf("application not reconciled again: %s", details)
+	}
+
+	// corrected drift is reported again if it comes back
+	if len(drift.Errors) == 0 {
+		applicationDrifts.changed(app.Name, "")
+	}
+
+	return drift, nil
+}
+
+// reconciler periodically reconciles every stored application
+func (m DefaultManager) reconciler() {
+	t := time.NewTicker(reconcileInterval).C
+	for range t {
+		m.reconcileApplications()
+	}
+}
+
+func (m DefaultManager) reconcileApplications() {
+	res, err := r.Table(tblNameApplications).Run(m.session)
+	if err != nil {
+		log.Errorf("error loading applications: %s", err)
+		return
+	}
+
+	apps := []*shipyard.Application{}
+	if err := res.All(&apps); err != nil {
+		log.Errorf("error loading applications: %s", err)
+		return
+	}
+
+	for _, app := range apps {
+		if app.ReconcilePaused {
+			continue
+		}
+
+		drift, err := m.ReconcileApplication(app, "")
+		if err != nil {
+			log.Debugf("application not reconciled: name=%s err=%s", app.Name, err)
+			continue
+		}
+
+		if len(drift.Errors) > 0 {
+			log.Errorf("error reconciling application: name=%s err=%s", app.Name, strings.Join(drift.Errors, "; "))
+		}
+	}
+}
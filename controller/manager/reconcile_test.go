@@ -0,0 +1,102 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+func TestComputeDrift(t *testing.T) {
+	desired := []*shipyard.RevisionContainer{
+		{Name: "app_web_1"},
+		{Name: "app_web_2"},
+		{Name: "app_db_1"},
+	}
+
+	actual := []dockerclient.Container{
+		{Id: "1", Names: []string{"/node-1/app_web_1"}, Status: "Up 1 hour"},
+		{Id: "2", Names: []string{"/node-2/app_db_1"}, Status: hostDownStatus},
+		{Id: "3", Names: []string{"/node-1/app_web_3"}, Status: "Up 1 hour"},
+	}
+
+	missing, extra, unreachable := computeDrift(desired, actual)
+
+	names := []string{}
+	for _, c := range missing {
+		names = append(names, c.Name)
+	}
+	if len(names) != 1 || names[0] != "app_web_2" {
+		t.Fatalf("expected app_web_2 to be missing; received %v", names)
+	}
+
+	if len(extra) != 1 || extra[0].Id != "3" {
+		t.Fatalf("expected container 3 to be extra; received %v", extra)
+	}
+
+	if len(unreachable) != 1 || unreachable[0].Id != "2" {
+		t.Fatalf("expected container 2 to be unreachable; received %v", unreachable)
+	}
+}
+
+func TestDriftSet(t *testing.T) {
+	drifts := &driftSet{last: map[string]string{}}
+
+	if !drifts.changed("app", "missing=web_1") {
+		t.Fatal("expected new drift to be reported")
+	}
+
+	if drifts.changed("app", "missing=web_1") {
+		t.Fatal("expected persisting drift not to be reported again")
+	}
+
+	if !drifts.changed("app", "missing=web_2") {
+		t.Fatal("expected changed drift to be reported")
+	}
+
+	drifts.changed("app", "")
+	if !drifts.changed("app", "missing=web_2") {
+		t.Fatal("expected drift to be reported again after it was cleared")
+	}
+}
+
+func TestOperationSet(t *testing.T) {
+	ops := &operationSet{active: map[string]int{}}
+
+	end := ops.begin("app")
+	if _, ok := ops.tryBegin("app"); ok {
+		t.Fatal("expected running operation to block")
+	}
+
+	end()
+	end, ok := ops.tryBegin("app")
+	if !ok {
+		t.Fatal("expected operation to begin")
+	}
+	end()
+
+	if len(ops.active) != 0 {
+		t.Fatalf("expected no active operations; received %v", ops.active)
+	}
+}
+
+func TestBeginContainersOperation(t *testing.T) {
+	defer func(ops *operationSet) { applicationOperations = ops }(applicationOperations)
+	applicationOperations = &operationSet{active: map[string]int{}}
+
+	end := beginContainersOperation([]dockerclient.Container{
+		{Labels: map[string]string{shipyard.ApplicationLabel: "app"}},
+		{Labels: map[string]string{shipyard.ApplicationLabel: "app"}},
+		{Labels: map[string]string{}},
+	})
+
+	if _, ok := applicationOperations.tryBegin("app"); ok {
+		t.Fatal("expected the application of the members to be blocked")
+	}
+
+	end()
+
+	if len(applicationOperations.active) != 0 {
+		t.Fatalf("expected no active operations; received %v", applicationOperations.active)
+	}
+}
@@ -0,0 +1,358 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/compose"
+)
+
+var tagRegexp = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+
+// repositoryName returns the namespaced repository name used by the
+// registry api (i.e. "app" is stored as "library/app")
+func repositoryName(repo string) string {
+	if !strings.Contains(repo, "/") {
+		return "library/" + repo
+	}
+
+	return repo
+}
+
+// matchRepository reports whether the repository matches the glob pattern.
+// An empty pattern matches all repositories.
+func matchRepository(pattern, repo string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	repo = repositoryName(repo)
+	if ok, _ := path.Match(repositoryName(pattern), repo); ok {
+		return true
+	}
+
+	ok, _ := path.Match(pattern, repo)
+	return ok
+}
+
+// registryImageUsage returns the running containers using images from the
+// registry keyed by "namespace/repository:tag"
+func (m DefaultManager) registryImageUsage(reg *shipyard.Registry) (map[string][]dockerclient.Container, error) {
+	containers, err := m.client.ListContainers(false, false, "")
+	if err != nil {
+		return nil, err
+	}
+
+	usage := map[string][]dockerclient.Container{}
+	for _, c := range containers {
+		host, repo, tag := parseImageName(c.Image)
+		if host == "" || host != reg.Host() {
+			continue
+		}
+
+		key := repositoryName(repo) + ":" + tag
+		usage[key] = append(usage[key], c)
+	}
+
+	return usage, nil
+}
+
+// registryRepositories returns the names of all repositories in the
+// registry matching the pattern
+func registryRepositories(reg *shipyard.Registry, pattern string) ([]string, error) {
+	names := []string{}
+	for page := 1; ; page++ {
+		res, err := reg.Repositories("", page, 100, false)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, repo := range res.Results {
+			if matchRepository(pattern, repo.Name) {
+				names = append(names, repo.Name)
+			}
+		}
+
+		if len(res.Results) < 100 || (res.NumberOfPages > 0 && page >= res.NumberOfPages) {
+			break
+		}
+	}
+
+	return names, nil
+}
+
+// RepositoryUsage returns the tags of the repository and the running
+// containers using any of them
+func (m DefaultManager) RepositoryUsage(reg *shipyard.Registry, repo string) (*shipyard.RepositoryUsage, error) {
+	tags, err := reg.Tags(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := m.registryImageUsage(reg)
+	if err != nil {
+		return nil, err
+	}
+
+	containers := []dockerclient.Container{}
+	prefix := repositoryName(repo) + ":"
+	for image, c := range usage {
+		if strings.HasPrefix(image, prefix) {
+			containers = append(containers, c...)
+		}
+	}
+
+	return &shipyard.RepositoryUsage{
+		Registry:   reg.Name,
+		Repository: repositoryName(repo),
+		Tags:       tags,
+		Containers: containers,
+	}, nil
+}
+
+// DeleteRepository removes the repository from the registry.  Unless force
+// is set ErrRepositoryInUse is returned if running containers use the
+// repository.  The deletion is recorded as an event.
+func (m DefaultManager) DeleteRepository(reg *shipyard.Registry, repo, username string, force bool) (*shipyard.RepositoryDeletion, error) {
+	usage, err := m.RepositoryUsage(reg, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(usage.Containers) > 0 && !force {
+		return nil, ErrRepositoryInUse
+	}
+
+	if err := reg.DeleteRepository(repo); err != nil {
+		return nil, err
+	}
+
+	deletion := &shipyard.RepositoryDeletion{
+		Registry:   reg.Name,
+		Repository: usage.Repository,
+		Tags:       usage.Tags,
+		Containers: []string{},
+		Forced:     force,
+		Username:   username,
+		Time:       time.Now(),
+	}
+
+	for _, c := range usage.Containers {
+		deletion.Containers = append(deletion.Containers, c.Id)
+	}
+
+	removed := []string{}
+	for _, t := range usage.Tags {
+		removed = append(removed, fmt.Sprintf("%s@%s", t.Name, t.ID))
+	}
+
+	evt := &shipyard.Event{
+		Type: "delete-repository",
+		Time: deletion.Time,
+		Message: fmt.Sprintf("registry=%s repository=%s force=%v tags=%s containers=%s",
+			reg.Name, deletion.Repository, force, strings.Join(removed, ","), strings.Join(deletion.Containers, ",")),
+		Username: username,
+		Tags:     []string{"registry"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		log.Errorf("error logging event: %s", err)
+	}
+
+	return deletion, nil
+}
+
+// PromoteImage copies a tagged image between registries without pulling it
+// through an engine.  The account must be allowed to use the credentials of
+// both registries.
+func (m DefaultManager) PromoteImage(promotion *shipyard.Promotion, username string) (*shipyard.Promotion, error) {
+	if promotion.SourceRepository == "" {
+		return nil, errors.New("source repository is required")
+	}
+
+	p := *promotion
+	if p.SourceTag == "" {
+		p.SourceTag = "latest"
+	}
+	if p.TargetRegistry == "" {
+		p.TargetRegistry = p.SourceRegistry
+	}
+	if p.TargetRepository == "" {
+		p.TargetRepository = p.SourceRepository
+	}
+	if p.TargetTag == "" {
+		p.TargetTag = p.SourceTag
+	}
+
+	if p.SourceRegistry == p.TargetRegistry && repositoryName(p.SourceRepository) == repositoryName(p.TargetRepository) && p.SourceTag == p.TargetTag {
+		return nil, errors.New("source and target are the same")
+	}
+
+	src, err := m.Registry(p.SourceRegistry)
+	if err != nil {
+		return nil, err
+	}
+
+	dst, err := m.Registry(p.TargetRegistry)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := m.registryRoles(username)
+	if err != nil {
+		return nil, err
+	}
+
+	if !src.CanUseCredentials(roles) || !dst.CanUseCredentials(roles) {
+		return nil, ErrRegistryAccessDenied
+	}
+
+	res, err := dst.CopyImage(src, p.SourceRepository, p.SourceTag, p.TargetRepository, p.TargetTag)
+	if err != nil {
+		return nil, err
+	}
+
+	p.ImageID = res.ImageID
+	p.CopiedLayers = res.Copied
+	p.SkippedLayers = res.Skipped
+	p.Username = username
+	p.Time = time.Now()
+
+	evt := &shipyard.Event{
+		Type: "promote-image",
+		Time: p.Time,
+		Message: fmt.Sprintf("source=%s/%s:%s target=%s/%s:%s image=%s copied=%d skipped=%d",
+			src.Name, repositoryName(p.SourceRepository), p.SourceTag,
+			dst.Name, repositoryName(p.TargetRepository), p.TargetTag,
+			p.ImageID, len(p.CopiedLayers), len(p.SkippedLayers)),
+		Username: username,
+		Tags:     []string{"registry"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		log.Errorf("error logging event: %s", err)
+	}
+
+	return &p, nil
+}
+
+// repositoryContainerConfig returns the configuration of a container
+// running the image with the resources and ports of the deployment
+func repositoryContainerConfig(image string, d *shipyard.RepositoryDeployment) (*dockerclient.ContainerConfig, error) {
+	config := &dockerclient.ContainerConfig{
+		Image:        image,
+		Env:          d.Env,
+		ExposedPorts: map[string]struct{}{},
+		HostConfig: dockerclient.HostConfig{
+			Memory:          d.Memory,
+			CpuShares:       d.CpuShares,
+			PublishAllPorts: d.PublishAllPorts,
+			PortBindings:    map[string][]dockerclient.PortBinding{},
+		},
+	}
+
+	for _, port := range d.Ports {
+		key, binding, err := compose.ParsePort(port)
+		if err != nil {
+			return nil, err
+		}
+
+		config.ExposedPorts[key] = struct{}{}
+		config.HostConfig.PortBindings[key] = append(config.HostConfig.PortBindings[key], binding)
+	}
+
+	return config, nil
+}
+
+// DeployRepository pulls a tagged image of the registry repository with the
+// stored credentials and starts a container from it.  The account must be
+// allowed to use the registry credentials.  The container is removed if it
+// cannot be started or its revision cannot be recorded.
+func (m DefaultManager) DeployRepository(reg *shipyard.Registry, repo string, deployment *shipyard.RepositoryDeployment, username string) (*shipyard.RepositoryDeployment, error) {
+	if repo == "" {
+		return nil, &DeploymentError{errors.New("repository is required")}
+	}
+
+	d := *deployment
+	if d.Tag == "" {
+		d.Tag = "latest"
+	}
+
+	if !tagRegexp.MatchString(d.Tag) {
+		return nil, &DeploymentError{fmt.Errorf("invalid tag: %s", d.Tag)}
+	}
+
+	if d.Memory < 0 || d.CpuShares < 0 {
+		return nil, &DeploymentError{errors.New("memory and cpu shares must not be negative")}
+	}
+
+	d.Registry = reg.Name
+	d.Repository = repo
+	d.Image = reg.Host() + "/" + repo + ":" + d.Tag
+
+	config, err := repositoryContainerConfig(d.Image, &d)
+	if err != nil {
+		return nil, &DeploymentError{err}
+	}
+
+	var authConfig *dockerclient.AuthConfig
+	if reg.HasCredentials() {
+		roles, err := m.registryRoles(username)
+		if err != nil {
+			return nil, err
+		}
+
+		if !reg.CanUseCredentials(roles) {
+			return nil, ErrRegistryAccessDenied
+		}
+
+		authConfig = reg.AuthConfig()
+	}
+
+	// always pull so that a moved tag deploys the current image
+	log.Debugf("pulling image: image=%s", d.Image)
+	if err := m.client.PullImage(d.Image, authConfig); err != nil {
+		return nil, err
+	}
+
+	id, err := m.createContainer(config, d.Name, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.client.StartContainer(id, &config.HostConfig); err != nil {
+		if rerr := m.client.RemoveContainer(id, true, false); rerr != nil {
+			log.Errorf("error removing container: id=%s err=%s", id, rerr)
+		}
+		return nil, err
+	}
+
+	if err := m.recordDeployRevision("", id, username); err != nil {
+		if rerr := m.client.RemoveContainer(id, true, false); rerr != nil {
+			log.Errorf("error removing container: id=%s err=%s", id, rerr)
+		}
+		return nil, err
+	}
+
+	d.ContainerID = id
+	d.Username = username
+	d.Time = time.Now()
+
+	m.logUserEvent("deploy-repository", fmt.Sprintf("registry=%s image=%s container=%s", reg.Name, d.Image, id), username, []string{"registry", "containers"})
+
+	return &d, nil
+}
+
+// DeploymentError is returned for invalid deployment inputs
+type DeploymentError struct {
+	Err error
+}
+
+func (e *DeploymentError) Error() string {
+	return e.Err.Error()
+}
@@ -0,0 +1,41 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/shipyard/shipyard"
+)
+
+func TestRepositoryContainerConfig(t *testing.T) {
+	config, err := repositoryContainerConfig("localhost:5000/library/app:1.0", &shipyard.RepositoryDeployment{
+		Memory:    64 << 20,
+		CpuShares: 512,
+		Ports:     []string{"8080:80", "53/udp"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Image != "localhost:5000/library/app:1.0" {
+		t.Fatalf("unexpected image: %s", config.Image)
+	}
+
+	if config.HostConfig.Memory != 64<<20 || config.HostConfig.CpuShares != 512 {
+		t.Fatalf("unexpected resources: memory=%d cpu_shares=%d", config.HostConfig.Memory, config.HostConfig.CpuShares)
+	}
+
+	bindings := config.HostConfig.PortBindings
+	if len(bindings["80/tcp"]) != 1 || bindings["80/tcp"][0].HostPort != "8080" {
+		t.Fatalf("expected 80/tcp to be published on 8080; received %v", bindings)
+	}
+
+	if _, ok := config.ExposedPorts["53/udp"]; !ok {
+		t.Fatalf("expected 53/udp to be exposed; received %v", config.ExposedPorts)
+	}
+}
+
+func TestRepositoryContainerConfigInvalidPort(t *testing.T) {
+	if _, err := repositoryContainerConfig("app", &shipyard.RepositoryDeployment{Ports: []string{"http"}}); err == nil {
+		t.Fatal("expected error for invalid port")
+	}
+}
@@ -0,0 +1,464 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+	registry "github.com/shipyard/shipyard/registry/v1"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+const (
+	retentionCheckInterval = 1 * time.Minute
+)
+
+// trimImageID returns the image id without the digest algorithm of engines
+// using content addressed ids
+func trimImageID(id string) string {
+	return strings.TrimPrefix(id, "sha256:")
+}
+
+// runningImageIDs returns the ids of the images of the running containers.
+// Containers may reference the image by any of its tags, its digest or
+// (a prefix of) its id.
+func (m DefaultManager) runningImageIDs() (map[string]bool, error) {
+	containers, err := m.client.ListContainers(false, false, "")
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := m.client.ListImages(false)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[string]bool{}
+	for _, c := range containers {
+		for _, img := range images {
+			if imageReferenced(c.Image, img) {
+				ids[trimImageID(img.Id)] = true
+				break
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+var imageIDPattern = regexp.MustCompile(`^(sha256:)?[0-9a-f]+$`)
+
+// imageReferenced reports whether the reference of a container names the
+// image
+func imageReferenced(ref string, img *dockerclient.Image) bool {
+	if imageIDPattern.MatchString(ref) && strings.HasPrefix(trimImageID(img.Id), trimImageID(ref)) {
+		return true
+	}
+
+	for _, name := range append(img.RepoTags, img.RepoDigests...) {
+		if name == ref {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseRetentionAge parses a duration which in addition to the units
+// supported by time.ParseDuration accepts days (i.e. "30d")
+func parseRetentionAge(age string) (time.Duration, error) {
+	if strings.HasSuffix(age, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(age, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: %s", age)
+		}
+
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(age)
+}
+
+func validateRetentionPolicy(policy *shipyard.RetentionPolicy) error {
+	if policy.Registry == "" {
+		return errors.New("retention policy registry is required")
+	}
+
+	if policy.KeepLast < 0 {
+		return errors.New("keep_last must not be negative")
+	}
+
+	if policy.KeepLast == 0 && policy.MaxAge == "" {
+		return errors.New("retention policy must set keep_last or max_age")
+	}
+
+	if policy.MaxAge != "" {
+		if _, err := parseRetentionAge(policy.MaxAge); err != nil {
+			return fmt.Errorf("invalid max_age: %s", err)
+		}
+	}
+
+	if policy.Schedule != "" {
+		interval, err := parseRetentionAge(policy.Schedule)
+		if err != nil {
+			return fmt.Errorf("invalid schedule: %s", err)
+		}
+
+		if interval < retentionCheckInterval {
+			return fmt.Errorf("schedule must be at least %s", retentionCheckInterval)
+		}
+	}
+
+	if _, err := regexp.Compile(policy.KeepPattern); err != nil {
+		return fmt.Errorf("invalid keep_pattern: %s", err)
+	}
+
+	if _, err := path.Match(policy.Repository, ""); err != nil {
+		return fmt.Errorf("invalid repository pattern: %s", err)
+	}
+
+	return nil
+}
+
+type tagsByCreated []registry.Tag
+
+func (t tagsByCreated) Len() int      { return len(t) }
+func (t tagsByCreated) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+
+// newest first; tags without a creation time are considered the newest
+func (t tagsByCreated) Less(i, j int) bool {
+	if t[i].Created == nil {
+		return t[j].Created != nil
+	}
+	if t[j].Created == nil {
+		return false
+	}
+
+	return t[i].Created.After(*t[j].Created)
+}
+
+// planRetention splits the tags of the repository into the tags to delete
+// and the tags to keep.  inUse contains the tags used by running containers.
+func planRetention(policy *shipyard.RetentionPolicy, repo string, tags []registry.Tag, inUse map[string]bool, now time.Time) ([]*shipyard.RetentionTag, []*shipyard.RetentionTag, error) {
+	var (
+		maxAge  time.Duration
+		keep    *regexp.Regexp
+		deleted = []*shipyard.RetentionTag{}
+		kept    = []*shipyard.RetentionTag{}
+	)
+
+	if policy.MaxAge != "" {
+		d, err := parseRetentionAge(policy.MaxAge)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		maxAge = d
+	}
+
+	if policy.KeepPattern != "" {
+		re, err := regexp.Compile(policy.KeepPattern)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		keep = re
+	}
+
+	// tags of an image in use are kept whichever name the container uses
+	used := map[string]bool{}
+	for _, tag := range tags {
+		if tag.ID != "" && (inUse[repositoryName(repo)+":"+tag.Name] || inUse[trimImageID(tag.ID)]) {
+			used[tag.ID] = true
+		}
+	}
+
+	sorted := make([]registry.Tag, len(tags))
+	copy(sorted, tags)
+	sort.Stable(tagsByCreated(sorted))
+
+	for i, tag := range sorted {
+		t := &shipyard.RetentionTag{
+			Repository: repo,
+			Tag:        tag.Name,
+			ImageID:    tag.ID,
+			Created:    tag.Created,
+		}
+
+		remove := false
+		switch {
+		case keep != nil && keep.MatchString(tag.Name):
+			t.Reason = "matches keep pattern"
+		case inUse[repositoryName(repo)+":"+tag.Name] || used[tag.ID]:
+			t.Reason = "used by running container"
+		case i < policy.KeepLast:
+			t.Reason = fmt.Sprintf("within the last %d tags", policy.KeepLast)
+		case maxAge > 0 && tag.Created == nil:
+			t.Reason = "unknown creation time"
+		case maxAge > 0 && now.Sub(*tag.Created) <= maxAge:
+			t.Reason = fmt.Sprintf("newer than %s", policy.MaxAge)
+		case maxAge > 0:
+			t.Reason = fmt.Sprintf("older than %s", policy.MaxAge)
+			remove = true
+		default:
+			t.Reason = fmt.Sprintf("not within the last %d tags", policy.KeepLast)
+			remove = true
+		}
+
+		if remove {
+			deleted = append(deleted, t)
+		} else {
+			kept = append(kept, t)
+		}
+	}
+
+	return deleted, kept, nil
+}
+
+func (m DefaultManager) RetentionPolicies() ([]*shipyard.RetentionPolicy, error) {
+	res, err := r.Table(tblNameRetentionPolicies).OrderBy(r.Asc("name")).Run(m.session)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := []*shipyard.RetentionPolicy{}
+	if err := res.All(&policies); err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+func (m DefaultManager) RetentionPolicy(id string) (*shipyard.RetentionPolicy, error) {
+	res, err := r.Table(tblNameRetentionPolicies).Get(id).Run(m.session)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.IsNil() {
+		return nil, ErrRetentionPolicyDoesNotExist
+	}
+
+	var policy *shipyard.RetentionPolicy
+	if err := res.One(&policy); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+func (m DefaultManager) SaveRetentionPolicy(policy *shipyard.RetentionPolicy) error {
+	if err := validateRetentionPolicy(policy); err != nil {
+		return err
+	}
+
+	if _, err := m.Registry(policy.Registry); err != nil {
+		return err
+	}
+
+	eventType := "update-retention-policy"
+	if policy.ID == "" {
+		res, err := r.Table(tblNameRetentionPolicies).Insert(policy).RunWrite(m.session)
+		if err != nil {
+			return err
+		}
+
+		if len(res.GeneratedKeys) > 0 {
+			policy.ID = res.GeneratedKeys[0]
+		}
+
+		eventType = "add-retention-policy"
+	} else {
+		if _, err := r.Table(tblNameRetentionPolicies).Get(policy.ID).Replace(policy).RunWrite(m.session); err != nil {
+			return err
+		}
+	}
+
+	m.logEvent(eventType, fmt.Sprintf("name=%s registry=%s repository=%s", policy.Name, policy.Registry, policy.Repository), []string{"registry"})
+
+	return nil
+}
+
+func (m DefaultManager) RemoveRetentionPolicy(policy *shipyard.RetentionPolicy) error {
+	res, err := r.Table(tblNameRetentionPolicies).Get(policy.ID).Run(m.session)
+	if err != nil {
+		return err
+	}
+
+	if res.IsNil() {
+		return ErrRetentionPolicyDoesNotExist
+	}
+
+	if _, err := r.Table(tblNameRetentionPolicies).Get(policy.ID).Delete().RunWrite(m.session); err != nil {
+		return err
+	}
+
+	m.logEvent("delete-retention-policy", fmt.Sprintf("name=%s registry=%s", policy.Name, policy.Registry), []string{"registry"})
+
+	return nil
+}
+
+// ApplyRetentionPolicy deletes the tags selected by the policy.  With dryRun
+// the report lists the tags that would be deleted without deleting them.
+func (m DefaultManager) ApplyRetentionPolicy(policy *shipyard.RetentionPolicy, dryRun bool) (*shipyard.RetentionReport, error) {
+	report := &shipyard.RetentionReport{
+		PolicyID: policy.ID,
+		Registry: policy.Registry,
+		DryRun:   dryRun,
+		Time:     time.Now(),
+		Deleted:  []*shipyard.RetentionTag{},
+		Kept:     []*shipyard.RetentionTag{},
+		Errors:   []string{},
+	}
+
+	reg, err := m.Registry(policy.Registry)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := m.registryImageUsage(reg)
+	if err != nil {
+		return nil, err
+	}
+
+	inUse, err := m.runningImageIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	for k := range usage {
+		inUse[k] = true
+	}
+
+	repos, err := registryRepositories(reg, policy.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	planned := []*shipyard.RetentionTag{}
+	for _, repo := range repos {
+		tags, err := reg.Tags(repo)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %s", repo, err))
+			continue
+		}
+
+		deleted, kept, err := planRetention(policy, repo, tags, inUse, report.Time)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Kept = append(report.Kept, kept...)
+		planned = append(planned, deleted...)
+	}
+
+	// the storage is collected before deleting the tags whose layers are
+	// only reclaimed if not shared with kept tags or other repositories
+	var (
+		images []storageImage
+		sizes  map[string]int64
+	)
+	if len(planned) > 0 {
+		var errs []string
+		images, sizes, errs, err = collectStorage(reg, "")
+		if err != nil {
+			return nil, err
+		}
+
+		report.Errors = append(report.Errors, errs...)
+	}
+
+	for _, t := range planned {
+		if !dryRun {
+			if err := reg.DeleteTag(t.Repository, t.Tag); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s:%s: %s", t.Repository, t.Tag, err))
+				continue
+			}
+		}
+
+		report.Deleted = append(report.Deleted, t)
+	}
+
+	if len(report.Deleted) > 0 {
+		names := map[string]bool{}
+		for _, t := range report.Deleted {
+			names[repositoryName(t.Repository)+":"+t.Tag] = true
+		}
+
+		report.ReclaimedSize = reclaimableSize(images, sizes, names)
+	}
+
+	if !dryRun {
+		if _, err := r.Table(tblNameRetentionPolicies).Get(policy.ID).Update(map[string]interface{}{"last_run": report.Time}).RunWrite(m.session); err != nil {
+			return nil, err
+		}
+		policy.LastRun = report.Time
+
+		tags := []string{}
+		for _, t := range report.Deleted {
+			tags = append(tags, t.Repository+":"+t.Tag)
+		}
+
+		m.logEvent("apply-retention-policy", fmt.Sprintf("name=%s registry=%s deleted=%s", policy.Name, policy.Registry, strings.Join(tags, ",")), []string{"registry"})
+	}
+
+	return report, nil
+}
+
+func (m DefaultManager) DeleteTag(reg *shipyard.Registry, repo, tag string) error {
+	if err := reg.DeleteTag(repo, tag); err != nil {
+		return err
+	}
+
+	m.logEvent("delete-tag", fmt.Sprintf("registry=%s repository=%s tag=%s", reg.Name, repo, tag), []string{"registry"})
+
+	return nil
+}
+
+// retentionScheduler applies retention policies with a schedule when due
+func (m DefaultManager) retentionScheduler() {
+	t := time.NewTicker(retentionCheckInterval).C
+	for range t {
+		m.applyScheduledRetention(time.Now())
+	}
+}
+
+func (m DefaultManager) applyScheduledRetention(now time.Time) {
+	policies, err := m.RetentionPolicies()
+	if err != nil {
+		log.Errorf("error loading retention policies: %s", err)
+		return
+	}
+
+	for _, policy := range policies {
+		if policy.Schedule == "" {
+			continue
+		}
+
+		interval, err := parseRetentionAge(policy.Schedule)
+		if err != nil {
+			log.Errorf("invalid retention schedule: policy=%s err=%s", policy.Name, err)
+			continue
+		}
+
+		if now.Sub(policy.LastRun) < interval {
+			continue
+		}
+
+		report, err := m.ApplyRetentionPolicy(policy, false)
+		if err != nil {
+			log.Errorf("error applying retention policy: policy=%s err=%s", policy.Name, err)
+			continue
+		}
+
+		log.Infof("applied retention policy: policy=%s deleted=%d errors=%d", policy.Name, len(report.Deleted), len(report.Errors))
+	}
+}
@@ -0,0 +1,183 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+	registry "github.com/shipyard/shipyard/registry/v1"
+)
+
+func testTags(now time.Time, ages map[string]int) []registry.Tag {
+	tags := []registry.Tag{}
+	for name, days := range ages {
+		created := now.Add(-time.Duration(days) * 24 * time.Hour)
+		tags = append(tags, registry.Tag{
+			ID:      name + "-id",
+			Name:    name,
+			Created: &created,
+		})
+	}
+
+	return tags
+}
+
+func retentionTagNames(tags []*shipyard.RetentionTag) map[string]bool {
+	names := map[string]bool{}
+	for _, t := range tags {
+		names[t.Tag] = true
+	}
+
+	return names
+}
+
+func TestPlanRetentionKeepLast(t *testing.T) {
+	now := time.Now()
+	tags := testTags(now, map[string]int{"1": 40, "2": 30, "3": 20, "4": 10, "latest": 1})
+	policy := &shipyard.RetentionPolicy{
+		KeepLast: 2,
+	}
+
+	deleted, kept, err := planRetention(policy, "app", tags, nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deleted) != 3 || len(kept) != 2 {
+		t.Fatalf("expected 3 deleted and 2 kept; received %d and %d", len(deleted), len(kept))
+	}
+
+	names := retentionTagNames(kept)
+	if !names["latest"] || !names["4"] {
+		t.Fatalf("expected newest tags to be kept; received %v", names)
+	}
+}
+
+func TestPlanRetentionMaxAge(t *testing.T) {
+	now := time.Now()
+	tags := testTags(now, map[string]int{"1": 40, "2": 30, "3": 20, "latest": 1})
+	policy := &shipyard.RetentionPolicy{
+		KeepLast: 1,
+		MaxAge:   "25d",
+	}
+
+	deleted, _, err := planRetention(policy, "app", tags, nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := retentionTagNames(deleted)
+	if len(names) != 2 || !names["1"] || !names["2"] {
+		t.Fatalf("expected tags older than 25d to be deleted; received %v", names)
+	}
+}
+
+func TestPlanRetentionProtectedTags(t *testing.T) {
+	now := time.Now()
+	tags := testTags(now, map[string]int{"v1.0": 40, "2": 30, "3": 20, "latest": 1})
+	policy := &shipyard.RetentionPolicy{
+		KeepLast:    1,
+		KeepPattern: "^v[0-9]",
+	}
+	inUse := map[string]bool{
+		"library/app:3": true,
+	}
+
+	deleted, kept, err := planRetention(policy, "app", tags, inUse, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := retentionTagNames(deleted)
+	if len(names) != 1 || !names["2"] {
+		t.Fatalf("expected only tag 2 to be deleted; received %v", names)
+	}
+
+	for _, k := range kept {
+		switch k.Tag {
+		case "v1.0":
+			if k.Reason != "matches keep pattern" {
+				t.Fatalf("unexpected reason for v1.0: %s", k.Reason)
+			}
+		case "3":
+			if k.Reason != "used by running container" {
+				t.Fatalf("unexpected reason for 3: %s", k.Reason)
+			}
+		}
+	}
+}
+
+func TestValidateRetentionPolicy(t *testing.T) {
+	invalid := []*shipyard.RetentionPolicy{
+		{KeepLast: 1},
+		{Registry: "local"},
+		{Registry: "local", KeepLast: 1, KeepPattern: "("},
+		{Registry: "local", MaxAge: "forever"},
+		{Registry: "local", KeepLast: 1, Schedule: "1s"},
+	}
+
+	for _, p := range invalid {
+		if err := validateRetentionPolicy(p); err == nil {
+			t.Fatalf("expected error for policy %+v", p)
+		}
+	}
+
+	valid := &shipyard.RetentionPolicy{
+		Registry: "local",
+		KeepLast: 10,
+		MaxAge:   "30d",
+		Schedule: "24h",
+	}
+	if err := validateRetentionPolicy(valid); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlanRetentionImageInUse(t *testing.T) {
+	now := time.Now()
+	tags := testTags(now, map[string]int{"1": 40, "2": 30, "3": 20})
+	// tag 2 is another name of the image of tag 1
+	for i := range tags {
+		if tags[i].Name == "2" {
+			tags[i].ID = "1-id"
+		}
+	}
+
+	policy := &shipyard.RetentionPolicy{MaxAge: "1d"}
+	inUse := map[string]bool{
+		"1-id": true,
+	}
+
+	deleted, _, err := planRetention(policy, "app", tags, inUse, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := retentionTagNames(deleted)
+	if len(names) != 1 || !names["3"] {
+		t.Fatalf("expected only tag 3 to be deleted; received %v", names)
+	}
+}
+
+func TestImageReferenced(t *testing.T) {
+	img := &dockerclient.Image{
+		Id:          "sha256:4f2a9c0d1e",
+		RepoTags:    []string{"localhost:5000/library/app:1.0"},
+		RepoDigests: []string{"localhost:5000/library/app@sha256:beef"},
+	}
+
+	for ref, expected := range map[string]bool{
+		"localhost:5000/library/app:1.0":         true,
+		"localhost:5000/library/app@sha256:beef": true,
+		"sha256:4f2a9c0d1e":                      true,
+		"4f2a9c":                                 true,
+		"localhost:5000/library/app:2.0":         false,
+		"4f2b":                                   false,
+		"app":                                    false,
+	} {
+		if imageReferenced(ref, img) != expected {
+			t.Fatalf("expected reference %s to match %v", ref, expected)
+		}
+	}
+}
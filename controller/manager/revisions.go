@@ -0,0 +1,484 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/compose"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+// revisionRecordAttempts is the number of times a revision is recorded
+// before giving up on concurrent operations taking its number
+const revisionRecordAttempts = 5
+
+// revisionID returns the id of the revision of the target
+func revisionID(kind, target string, number int) string {
+	return fmt.Sprintf("%s/%s/%d", kind, target, number)
+}
+
+// revisionContainers snapshots the configuration of the containers
+func (m DefaultManager) revisionContainers(containers []dockerclient.Container) ([]*shipyard.RevisionContainer, error) {
+	snapshot := []*shipyard.RevisionContainer{}
+	for _, c := range containers {
+		info, err := m.client.InspectContainer(c.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot = append(snapshot, &shipyard.RevisionContainer{
+			Name:        containerName(c),
+			Service:     c.Labels[shipyard.ServiceLabel],
+			Node:        containerNode(c),
+			Image:       info.Config.Image,
+			ImageDigest: info.Image,
+			Config:      info.Config,
+			HostConfig:  info.HostConfig,
+		})
+	}
+
+	return snapshot, nil
+}
+
+// recordRevision stores the containers as the next revision of the target.
+// The reconciler restores the latest revision so changes whose revision
+// is not recorded must fail.
+func (m DefaultManager) recordRevision(kind, target, action, definition, username string, containers []dockerclient.Container) error {
+	snapshot, err := m.revisionContainers(containers)
+	if err != nil {
+		return fmt.Errorf("recording revision: %s", err)
+	}
+
+	// the id is unique per revision number so that concurrent operations
+	// cannot record the same number; the loser reads the latest again
+	var number int
+	for attempt := 1; ; attempt++ {
+		number = 1
+		if latest, err := m.latestRevision(kind, target); err == nil {
+			number = latest.Number + 1
+		} else if err != ErrRevisionDoesNotExist {
+			return fmt.Errorf("recording revision: %s", err)
+		}
+
+		rev := &shipyard.Revision{
+			ID:         revisionID(kind, target, number),
+			Kind:       kind,
+			Target:     target,
+			Number:     number,
+			Action:     action,
+			Definition: definition,
+			Containers: snapshot,
+			Username:   username,
+			Time:       time.Now(),
+		}
+
+		_, err := r.Table(tblNameRevisions).Insert(rev).RunWrite(m.session)
+		if err == nil {
+			break
+		}
+
+		if !strings.Contains(err.Error(), "Duplicate primary key") || attempt == revisionRecordAttempts {
+			return fmt.Errorf("recording revision: %s", err)
+		}
+	}
+
+	log.Debugf("recorded revision: %s=%s revision=%d action=%s", kind, target, number, action)
+
+	return nil
+}
+
+func (m DefaultManager) recordApplicationRevision(app *shipyard.Application, action, username string) error {
+	containers, err := m.serviceContainers(app)
+	if err != nil {
+		return fmt.Errorf("recording revision: %s", err)
+	}
+
+	return m.recordRevision(shipyard.RevisionApplication, app.Name, action, app.Definition, username, containers)
+}
+
+func (m DefaultManager) recordGroupRevision(group, action, username string) error {
+	containers, err := m.groupContainers(group)
+	if err != nil {
+		return fmt.Errorf("recording revision: %s", err)
+	}
+
+	return m.recordRevision(shipyard.RevisionGroup, group, action, "", username, containers)
+}
+
+// recordDeployRevision records a revision of the group the container was
+// deployed to.  A container deployed without a group is a group of its own
+// named after the container (see containerGroup).
+func (m DefaultManager) recordDeployRevision(group, id, username string) error {
+	if group != "" {
+		return m.recordGroupRevision(group, "deploy", username)
+	}
+
+	filters, _ := json.Marshal(map[string][]string{"id": {id}})
+	containers, err := m.client.ListContainers(true, false, string(filters))
+	if err != nil {
+		return fmt.Errorf("recording revision: %s", err)
+	}
+
+	if len(containers) == 0 {
+		return fmt.Errorf("recording revision: container %s does not exist", id)
+	}
+
+	return m.recordRevision(shipyard.RevisionGroup, containerName(containers[0]), "deploy", "", username, containers)
+}
+
+// recordScaleRevisions records a revision of the scaled group and of the
+// application the containers belong to
+func (m DefaultManager) recordScaleRevisions(group string, info *dockerclient.ContainerInfo, username string) error {
+	if err := m.recordGroupRevision(group, "scale", username); err != nil {
+		return err
+	}
+
+	if name := info.Config.Labels[shipyard.ApplicationLabel]; name != "" {
+		app, err := m.Application(name)
+		if err != nil {
+			return fmt.Errorf("recording revision: %s", err)
+		}
+
+		return m.recordApplicationRevision(app, "scale", username)
+	}
+
+	return nil
+}
+
+func (m DefaultManager) latestRevision(kind, target string) (*shipyard.Revision, error) {
+	res, err := r.Table(tblNameRevisions).Filter(map[string]string{"kind": kind, "target": target}).OrderBy(r.Desc("number")).Limit(1).Run(m.session)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.IsNil() {
+		return nil, ErrRevisionDoesNotExist
+	}
+
+	var rev *shipyard.Revision
+	if err := res.One(&rev); err != nil {
+		return nil, err
+	}
+
+	return rev, nil
+}
+
+// Revisions returns the revisions of the application or group, newest first
+func (m DefaultManager) Revisions(kind, target string) ([]*shipyard.Revision, error) {
+	res, err := r.Table(tblNameRevisions).Filter(map[string]string{"kind": kind, "target": target}).OrderBy(r.Desc("number")).Run(m.session)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := []*shipyard.Revision{}
+	if err := res.All(&revisions); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// Revision returns a revision of the application or group
+func (m DefaultManager) Revision(kind, target string, number int) (*shipyard.Revision, error) {
+	res, err := r.Table(tblNameRevisions).Filter(map[string]interface{}{"kind": kind, "target": target, "number": number}).Run(m.session)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.IsNil() {
+		return nil, ErrRevisionDoesNotExist
+	}
+
+	var rev *shipyard.Revision
+	if err := res.One(&rev); err != nil {
+		return nil, err
+	}
+
+	return rev, nil
+}
+
+// DiffRevisions compares two revisions.  A zero to selects the latest
+// revision and a zero from the revision before to.
+func (m DefaultManager) DiffRevisions(kind, target string, from, to int) (*shipyard.RevisionDiff, error) {
+	var (
+		toRev *shipyard.Revision
+		err   error
+	)
+
+	if to == 0 {
+		toRev, err = m.latestRevision(kind, target)
+	} else {
+		toRev, err = m.Revision(kind, target, to)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if from == 0 {
+		from = toRev.Number - 1
+	}
+
+	fromRev, err := m.Revision(kind, target, from)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffRevisions(fromRev, toRev), nil
+}
+
+// RollbackApplication recreates the containers of the application from the
+// revision.  The compose definition of the revision is restored as well.
+func (m DefaultManager) RollbackApplication(app *shipyard.Application, number int, username string) (ApplicationResult, error) {
+	rev, err := m.Revision(shipyard.RevisionApplication, app.Name, number)
+	if err != nil {
+		return ApplicationResult{}, err
+	}
+
+	// check the revision before anything is removed
+	for _, c := range rev.Containers {
+		if c.Config == nil || c.HostConfig == nil {
+			return ApplicationResult{}, fmt.Errorf("revision has no configuration for %s", c.Name)
+		}
+	}
+
+	var services []string
+	if app.ID != "" && rev.Definition != "" {
+		project, err := compose.Load([]byte(rev.Definition))
+		if err != nil {
+			return ApplicationResult{}, err
+		}
+
+		services, err = project.Order()
+		if err != nil {
+			return ApplicationResult{}, err
+		}
+	}
+
+	defer applicationOperations.begin(app.Name)()
+
+	if result := m.removeApplicationContainers(app); len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	if services != nil {
+		app.Definition = rev.Definition
+		app.Services = services
+		app.Updated = time.Now()
+
+		if _, err := r.Table(tblNameApplications).Get(app.ID).Update(map[string]interface{}{
+			"definition": app.Definition,
+			"services":   app.Services,
+			"updated":    app.Updated,
+		}).RunWrite(m.session); err != nil {
+			return ApplicationResult{}, err
+		}
+	}
+
+	result := ApplicationResult{Containers: []string{}, Errors: []string{}}
+	for _, c := range rev.Containers {
+		if err := m.recreateContainer(c, username); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", c.Name, strings.TrimSpace(err.Error())))
+			continue
+		}
+
+		result.Containers = append(result.Containers, c.Name)
+	}
+
+	if err := m.recordApplicationRevision(app, "rollback", username); err != nil {
+		return result, err
+	}
+
+	m.logUserEvent("rollback-application", fmt.Sprintf("name=%s revision=%d errors=%d", app.Name, number, len(result.Errors)), username, []string{"applications"})
+
+	return result, nil
+}
+
+// recreateContainer creates and starts the container of a revision on the
+// node it ran on.  The container is created from the image it ran with
+// since the tag may point to another image by now.
+func (m DefaultManager) recreateContainer(c *shipyard.RevisionContainer, username string) error {
+	if c.Config == nil || c.HostConfig == nil {
+		return fmt.Errorf("revision has no configuration for %s", c.Name)
+	}
+
+	config := *c.Config
+	config.HostConfig = *c.HostConfig
+	if c.Node != "" {
+		withNodeConstraint(&config, c.Node)
+	}
+	// drops the node of the revision if it is in maintenance
+	if _, err := m.PlaceContainer(&config); err != nil {
+		return err
+	}
+
+	if c.ImageDigest == "" {
+		id, err := m.createContainer(&config, c.Name, username)
+		if err != nil {
+			return err
+		}
+
+		return m.client.StartContainer(id, &config.HostConfig)
+	}
+
+	config.Image = c.ImageDigest
+	id, err := m.client.CreateContainer(&config, c.Name, nil)
+	if err == dockerclient.ErrImageNotFound {
+		// the image may have been removed from the node; pulling the tag
+		// brings it back unless the tag was moved
+		authConfig, aerr := m.RegistryAuthConfig(username, c.Config.Image)
+		if aerr != nil {
+			return aerr
+		}
+
+		log.Debugf("pulling image: image=%s", c.Config.Image)
+		if err := m.client.PullImage(withDefaultTag(c.Config.Image), authConfig); err != nil {
+			return err
+		}
+
+		id, err = m.client.CreateContainer(&config, c.Name, nil)
+		if err == dockerclient.ErrImageNotFound {
+			return fmt.Errorf("image %s (%s) is no longer available", c.Config.Image, c.ImageDigest)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return m.client.StartContainer(id, &config.HostConfig)
+}
+
+// revisionFields flattens the configuration of a container for comparison
+func revisionFields(c *shipyard.RevisionContainer) map[string]string {
+	fields := map[string]string{
+		"image":        c.Image,
+		"image_digest": c.ImageDigest,
+		"node":         c.Node,
+	}
+
+	if config := c.Config; config != nil {
+		fields["cmd"] = jsonString(config.Cmd)
+		fields["entrypoint"] = jsonString(config.Entrypoint)
+		fields["user"] = config.User
+		fields["working_dir"] = config.WorkingDir
+		fields["exposed_ports"] = jsonString(config.ExposedPorts)
+
+		for _, e := range config.Env {
+			parts := strings.SplitN(e, "=", 2)
+			if len(parts) == 2 {
+				fields["env."+parts[0]] = parts[1]
+			} else {
+				fields["env."+parts[0]] = ""
+			}
+		}
+
+		for k, v := range config.Labels {
+			fields["label."+k] = v
+		}
+	}
+
+	if hostConfig := c.HostConfig; hostConfig != nil {
+		fields["port_bindings"] = jsonString(hostConfig.PortBindings)
+		fields["binds"] = jsonString(hostConfig.Binds)
+		fields["links"] = jsonString(hostConfig.Links)
+		fields["volumes_from"] = jsonString(hostConfig.VolumesFrom)
+		fields["network_mode"] = hostConfig.NetworkMode
+		fields["restart_policy"] = jsonString(hostConfig.RestartPolicy)
+		fields["privileged"] = strconv.FormatBool(hostConfig.Privileged)
+		fields["memory"] = strconv.FormatInt(hostConfig.Memory, 10)
+		fields["cpu_shares"] = strconv.FormatInt(hostConfig.CpuShares, 10)
+	}
+
+	return fields
+}
+
+func jsonString(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+
+	return string(data)
+}
+
+// diffRevisions returns the changes from one revision to another.  Added
+// and removed containers are reported as a change of the container field
+// from or to "present".
+func diffRevisions(from, to *shipyard.Revision) *shipyard.RevisionDiff {
+	diff := &shipyard.RevisionDiff{
+		Kind:    to.Kind,
+		Target:  to.Target,
+		From:    from.Number,
+		To:      to.Number,
+		Changes: []*shipyard.RevisionChange{},
+	}
+
+	if from.Definition != to.Definition {
+		diff.Changes = append(diff.Changes, &shipyard.RevisionChange{
+			Field: "definition",
+			From:  from.Definition,
+			To:    to.Definition,
+		})
+	}
+
+	containers := map[string][2]*shipyard.RevisionContainer{}
+	for _, c := range from.Containers {
+		pair := containers[c.Name]
+		pair[0] = c
+		containers[c.Name] = pair
+	}
+	for _, c := range to.Containers {
+		pair := containers[c.Name]
+		pair[1] = c
+		containers[c.Name] = pair
+	}
+
+	names := []string{}
+	for name := range containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		pair := containers[name]
+		switch {
+		case pair[0] == nil:
+			diff.Changes = append(diff.Changes, &shipyard.RevisionChange{Container: name, Field: "container", To: "present"})
+			continue
+		case pair[1] == nil:
+			diff.Changes = append(diff.Changes, &shipyard.RevisionChange{Container: name, Field: "container", From: "present"})
+			continue
+		}
+
+		fromFields, toFields := revisionFields(pair[0]), revisionFields(pair[1])
+		keys := []string{}
+		for k := range fromFields {
+			keys = append(keys, k)
+		}
+		for k := range toFields {
+			if _, ok := fromFields[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if fromFields[k] != toFields[k] {
+				diff.Changes = append(diff.Changes, &shipyard.RevisionChange{
+					Container: name,
+					Field:     k,
+					From:      fromFields[k],
+					To:        toFields[k],
+				})
+			}
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,81 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+func TestDiffRevisions(t *testing.T) {
+	from := &shipyard.Revision{
+		Number:     1,
+		Definition: "web:\n  image: nginx:1.10\n",
+		Containers: []*shipyard.RevisionContainer{
+			{
+				Name:        "app_web_1",
+				Image:       "nginx:1.10",
+				ImageDigest: "sha256:aaa",
+				Config:      &dockerclient.ContainerConfig{Image: "nginx:1.10", Env: []string{"MODE=prod", "DEBUG=0"}},
+				HostConfig:  &dockerclient.HostConfig{Memory: 128},
+			},
+			{Name: "app_worker_1", Image: "worker"},
+		},
+	}
+
+	to := &shipyard.Revision{
+		Number:     2,
+		Definition: "web:\n  image: nginx:1.11\n",
+		Containers: []*shipyard.RevisionContainer{
+			{
+				Name:        "app_web_1",
+				Image:       "nginx:1.11",
+				ImageDigest: "sha256:bbb",
+				Config:      &dockerclient.ContainerConfig{Image: "nginx:1.11", Env: []string{"MODE=prod", "WORKERS=4"}},
+				HostConfig:  &dockerclient.HostConfig{Memory: 128},
+			},
+			{Name: "app_cache_1", Image: "redis"},
+		},
+	}
+
+	diff := diffRevisions(from, to)
+
+	expected := []shipyard.RevisionChange{
+		{Field: "definition", From: from.Definition, To: to.Definition},
+		{Container: "app_cache_1", Field: "container", To: "present"},
+		{Container: "app_web_1", Field: "env.DEBUG", From: "0"},
+		{Container: "app_web_1", Field: "env.WORKERS", To: "4"},
+		{Container: "app_web_1", Field: "image", From: "nginx:1.10", To: "nginx:1.11"},
+		{Container: "app_web_1", Field: "image_digest", From: "sha256:aaa", To: "sha256:bbb"},
+		{Container: "app_worker_1", Field: "container", From: "present"},
+	}
+
+	if len(diff.Changes) != len(expected) {
+		for _, c := range diff.Changes {
+			t.Logf("%+v", c)
+		}
+		t.Fatalf("expected %d changes; received %d", len(expected), len(diff.Changes))
+	}
+
+	for i, c := range diff.Changes {
+		if *c != expected[i] {
+			t.Errorf("expected change %+v; received %+v", expected[i], *c)
+		}
+	}
+
+	if diff.From != 1 || diff.To != 2 {
+		t.Fatalf("expected diff from 1 to 2; received %d to %d", diff.From, diff.To)
+	}
+}
+
+func TestDiffRevisionsUnchanged(t *testing.T) {
+	rev := &shipyard.Revision{
+		Containers: []*shipyard.RevisionContainer{
+			{Name: "web", Config: &dockerclient.ContainerConfig{Labels: map[string]string{"a": "b"}}},
+		},
+	}
+
+	if diff := diffRevisions(rev, rev); len(diff.Changes) != 0 {
+		t.Fatalf("expected no changes; received %d", len(diff.Changes))
+	}
+}
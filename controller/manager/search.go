@@ -0,0 +1,160 @@
+package manager
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shipyard/shipyard"
+	registry "github.com/shipyard/shipyard/registry/v1"
+)
+
+const (
+	// maximum number of repositories requested from each registry
+	searchResultsPerRegistry = 50
+)
+
+// scoreRepository ranks how well the repository matches the query.  Matches
+// on the repository name rank above matches in the namespace or description.
+func scoreRepository(query string, repo *registry.Repository) int {
+	q := strings.ToLower(query)
+	name := strings.ToLower(repo.Repository)
+
+	switch {
+	case q == "":
+		return 0
+	case name == q:
+		return 4
+	case strings.HasPrefix(name, q):
+		return 3
+	case strings.Contains(name, q):
+		return 2
+	case strings.Contains(strings.ToLower(repo.Name), q),
+		strings.Contains(strings.ToLower(repo.Description), q):
+		return 1
+	}
+
+	return 0
+}
+
+type searchResults []*shipyard.ImageSearchResult
+
+func (s searchResults) Len() int      { return len(s) }
+func (s searchResults) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s searchResults) Less(i, j int) bool {
+	if s[i].Score != s[j].Score {
+		return s[i].Score > s[j].Score
+	}
+	if s[i].Repository != s[j].Repository {
+		return s[i].Repository < s[j].Repository
+	}
+
+	return s[i].Registry < s[j].Registry
+}
+
+// searchRegistry returns the repositories of the registry matching the
+// query or an error if the registry does not answer within the timeout
+func searchRegistry(reg *shipyard.Registry, query string, timeout time.Duration) ([]*shipyard.ImageSearchResult, error) {
+	type response struct {
+		res *registry.SearchResult
+		err error
+	}
+
+	// the deadline cancels the requests of a registry which timed out
+	reg.SetDeadline(time.Now().Add(timeout))
+
+	c := make(chan response, 1)
+	go func() {
+		res, err := reg.Repositories(query, 1, searchResultsPerRegistry, false)
+		c <- response{res, err}
+	}()
+
+	var resp response
+	select {
+	case resp = <-c:
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("timed out after %s", timeout)
+	}
+
+	if resp.err != nil {
+		return nil, resp.err
+	}
+
+	results := []*shipyard.ImageSearchResult{}
+	for _, repo := range resp.res.Results {
+		tags := repo.Tags
+		if tags == nil {
+			tags = []registry.Tag{}
+		}
+
+		results = append(results, &shipyard.ImageSearchResult{
+			Registry:    reg.Name,
+			Image:       path.Join(reg.Host(), repo.Name),
+			Repository:  repo.Name,
+			Description: repo.Description,
+			Tags:        tags,
+			Score:       scoreRepository(query, repo),
+		})
+	}
+
+	return results, nil
+}
+
+// SearchImages searches all registries the account may use in parallel.
+// Registries which fail or do not answer within the timeout are reported
+// in the errors of the search instead of failing it.
+func (m DefaultManager) SearchImages(query, username string, timeout time.Duration) (*shipyard.ImageSearch, error) {
+	registries, err := m.Registries()
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := m.registryRoles(username)
+	if err != nil {
+		return nil, err
+	}
+
+	search := &shipyard.ImageSearch{
+		Query:   query,
+		Results: []*shipyard.ImageSearchResult{},
+		Errors:  map[string]string{},
+	}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+
+	for _, reg := range registries {
+		if !reg.CanUseCredentials(roles) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(reg *shipyard.Registry) {
+			defer wg.Done()
+
+			results, err := searchRegistry(reg, query, timeout)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				search.Errors[reg.Name] = err.Error()
+				return
+			}
+
+			search.Results = append(search.Results, results...)
+		}(reg)
+	}
+
+	wg.Wait()
+
+	sort.Sort(searchResults(search.Results))
+
+	return search, nil
+}
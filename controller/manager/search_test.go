@@ -0,0 +1,69 @@
+package manager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shipyard/shipyard"
+	registry "github.com/shipyard/shipyard/registry/v1"
+)
+
+func TestScoreRepository(t *testing.T) {
+	tests := []struct {
+		repo  *registry.Repository
+		score int
+	}{
+		{&registry.Repository{Name: "library/redis", Repository: "redis"}, 4},
+		{&registry.Repository{Name: "library/redis-cluster", Repository: "redis-cluster"}, 3},
+		{&registry.Repository{Name: "library/myredis", Repository: "myredis"}, 2},
+		{&registry.Repository{Name: "redis/tools", Repository: "tools"}, 1},
+		{&registry.Repository{Name: "library/cache", Repository: "cache", Description: "Redis compatible cache"}, 1},
+		{&registry.Repository{Name: "library/nginx", Repository: "nginx"}, 0},
+	}
+
+	for _, test := range tests {
+		if s := scoreRepository("Redis", test.repo); s != test.score {
+			t.Errorf("expected score %d for %s; received %d", test.score, test.repo.Name, s)
+		}
+	}
+}
+
+func TestSortSearchResults(t *testing.T) {
+	results := []*shipyard.ImageSearchResult{
+		{Registry: "b", Repository: "library/redis", Score: 4},
+		{Registry: "a", Repository: "library/myredis", Score: 2},
+		{Registry: "a", Repository: "library/redis", Score: 4},
+	}
+
+	sort.Sort(searchResults(results))
+
+	if results[0].Registry != "a" || results[0].Score != 4 {
+		t.Fatalf("expected exact match from registry a first; received %s/%s", results[0].Registry, results[0].Repository)
+	}
+
+	if results[2].Score != 2 {
+		t.Fatalf("expected lowest score last; received %d", results[2].Score)
+	}
+}
+
+func TestSearchRegistryTimeout(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	reg, err := shipyard.NewRegistry("0", "slow", ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := searchRegistry(reg, "redis", 10*time.Millisecond); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout error; received %v", err)
+	}
+}
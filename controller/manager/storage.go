@@ -0,0 +1,356 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shipyard/shipyard"
+	registry "github.com/shipyard/shipyard/registry/v1"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+const (
+	storageSnapshotInterval = 6 * time.Hour
+)
+
+// storageImage is a tagged image with the ids of all of its layers
+type storageImage struct {
+	repo     string
+	tag      string
+	id       string
+	ancestry []string
+}
+
+func (i storageImage) name() string {
+	return repositoryName(i.repo) + ":" + i.tag
+}
+
+// collectStorage returns the tagged images of the registry and the size of
+// the layers used by the repository or by every repository if it is empty.
+// The ancestry of all images is needed to tell exclusive from shared
+// layers.  Repositories which cannot be read are returned as errors.
+func collectStorage(reg *shipyard.Registry, repository string) ([]storageImage, map[string]int64, []string, error) {
+	repos, err := registryRepositories(reg, "")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var (
+		mu     sync.Mutex
+		images = []storageImage{}
+		errs   = []string{}
+	)
+
+	registry.ForEach(len(repos), func(i int) error {
+		repo := repos[i]
+
+		tags, err := reg.Tags(repo)
+		if err != nil {
+			mu.Lock()
+			errs = append(errs, fmt.Sprintf("%s: %s", repo, err))
+			mu.Unlock()
+			return nil
+		}
+
+		for _, t := range tags {
+			ancestry, err := reg.Ancestry(t.ID)
+			mu.Lock()
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s:%s: %s", repo, t.Name, err))
+			} else {
+				images = append(images, storageImage{repo: repo, tag: t.Name, id: t.ID, ancestry: ancestry})
+			}
+			mu.Unlock()
+		}
+
+		return nil
+	})
+
+	sizes := map[string]int64{}
+	for _, img := range images {
+		if repository != "" && repositoryName(img.repo) != repositoryName(repository) {
+			continue
+		}
+
+		for _, id := range img.ancestry {
+			sizes[id] = 0
+		}
+	}
+
+	ids := make([]string, 0, len(sizes))
+	for id := range sizes {
+		ids = append(ids, id)
+	}
+
+	registry.ForEach(len(ids), func(i int) error {
+		layer, err := reg.Layer(ids[i])
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("layer %s: %s", ids[i], err))
+			return nil
+		}
+
+		sizes[ids[i]] = layer.Size
+		return nil
+	})
+
+	sort.Strings(errs)
+
+	return images, sizes, errs, nil
+}
+
+// buildStorageReport counts every layer once.  Layers used by a single tag
+// are exclusive to the tag and layers used by a single repository are
+// exclusive to the repository.
+func buildStorageReport(registry string, images []storageImage, sizes map[string]int64) *shipyard.StorageReport {
+	layerImages := map[string]map[string]bool{}
+	layerRepos := map[string]map[string]bool{}
+	repoLayers := map[string]map[string]bool{}
+
+	for _, img := range images {
+		repo := repositoryName(img.repo)
+		if repoLayers[repo] == nil {
+			repoLayers[repo] = map[string]bool{}
+		}
+
+		for _, id := range img.ancestry {
+			if layerImages[id] == nil {
+				layerImages[id] = map[string]bool{}
+				layerRepos[id] = map[string]bool{}
+			}
+
+			layerImages[id][img.name()] = true
+			layerRepos[id][repo] = true
+			repoLayers[repo][id] = true
+		}
+	}
+
+	report := &shipyard.StorageReport{
+		Registry:     registry,
+		Layers:       len(layerImages),
+		Repositories: []*shipyard.RepositoryStorage{},
+		Errors:       []string{},
+	}
+
+	for id := range layerImages {
+		report.Size += sizes[id]
+	}
+
+	repos := map[string]*shipyard.RepositoryStorage{}
+	for repo, layers := range repoLayers {
+		rs := &shipyard.RepositoryStorage{
+			Repository: repo,
+			Tags:       []*shipyard.TagStorage{},
+		}
+
+		for id := range layers {
+			rs.Size += sizes[id]
+			if len(layerRepos[id]) == 1 {
+				rs.ExclusiveSize += sizes[id]
+			}
+		}
+
+		rs.SharedSize = rs.Size - rs.ExclusiveSize
+		repos[repo] = rs
+		report.Repositories = append(report.Repositories, rs)
+	}
+
+	for _, img := range images {
+		ts := &shipyard.TagStorage{
+			Tag:     img.tag,
+			ImageID: img.id,
+		}
+
+		for _, id := range img.ancestry {
+			ts.Size += sizes[id]
+			if len(layerImages[id]) == 1 {
+				ts.ExclusiveSize += sizes[id]
+			}
+		}
+
+		ts.SharedSize = ts.Size - ts.ExclusiveSize
+		rs := repos[repositoryName(img.repo)]
+		rs.Tags = append(rs.Tags, ts)
+	}
+
+	sort.Sort(repositoryStorageByName(report.Repositories))
+	for _, rs := range report.Repositories {
+		sort.Sort(tagStorageByName(rs.Tags))
+	}
+
+	return report
+}
+
+// reclaimableSize returns the size of the layers used only by the deleted
+// images.  Deleted images are keyed by "namespace/repository:tag".
+func reclaimableSize(images []storageImage, sizes map[string]int64, deleted map[string]bool) int64 {
+	kept := map[string]bool{}
+	for _, img := range images {
+		if deleted[img.name()] {
+			continue
+		}
+
+		for _, id := range img.ancestry {
+			kept[id] = true
+		}
+	}
+
+	size := int64(0)
+	counted := map[string]bool{}
+	for _, img := range images {
+		if !deleted[img.name()] {
+			continue
+		}
+
+		for _, id := range img.ancestry {
+			if !kept[id] && !counted[id] {
+				counted[id] = true
+				size += sizes[id]
+			}
+		}
+	}
+
+	return size
+}
+
+type repositoryStorageByName []*shipyard.RepositoryStorage
+
+func (s repositoryStorageByName) Len() int           { return len(s) }
+func (s repositoryStorageByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s repositoryStorageByName) Less(i, j int) bool { return s[i].Repository < s[j].Repository }
+
+type tagStorageByName []*shipyard.TagStorage
+
+func (s tagStorageByName) Len() int           { return len(s) }
+func (s tagStorageByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s tagStorageByName) Less(i, j int) bool { return s[i].Tag < s[j].Tag }
+
+// StorageReport computes the storage used by the registry.  Growth is
+// relative to the last snapshot recorded by the storage recorder.
+func (m DefaultManager) StorageReport(reg *shipyard.Registry) (*shipyard.StorageReport, error) {
+	images, sizes, errs, err := collectStorage(reg, "")
+	if err != nil {
+		return nil, err
+	}
+
+	report := buildStorageReport(reg.Name, images, sizes)
+	report.Time = time.Now()
+	report.Errors = errs
+
+	last, err := m.lastStorageSnapshot(reg)
+	if err != nil {
+		return nil, err
+	}
+
+	if last != nil {
+		report.Growth = report.Size - last.Size
+	}
+
+	return report, nil
+}
+
+// lastStorageSnapshot returns the latest snapshot of the registry or nil if
+// none has been recorded
+func (m DefaultManager) lastStorageSnapshot(reg *shipyard.Registry) (*shipyard.StorageSnapshot, error) {
+	res, err := r.Table(tblNameStorageSnapshots).Filter(map[string]string{"registry": reg.Name}).OrderBy(r.Desc("time")).Limit(1).Run(m.session)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.IsNil() {
+		return nil, nil
+	}
+
+	var snapshot *shipyard.StorageSnapshot
+	if err := res.One(&snapshot); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// RepositoryStorage computes the storage used by the repository.  Only the
+// sizes of the layers of the repository are requested from the registry.
+func (m DefaultManager) RepositoryStorage(reg *shipyard.Registry, repo string) (*shipyard.RepositoryStorage, error) {
+	images, sizes, _, err := collectStorage(reg, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rs := range buildStorageReport(reg.Name, images, sizes).Repositories {
+		if rs.Repository == repositoryName(repo) {
+			return rs, nil
+		}
+	}
+
+	return nil, ErrRepositoryDoesNotExist
+}
+
+// recordStorageSnapshot records the storage used by the registry for the
+// storage history
+func (m DefaultManager) recordStorageSnapshot(reg *shipyard.Registry) error {
+	report, err := m.StorageReport(reg)
+	if err != nil {
+		return err
+	}
+
+	snapshot := &shipyard.StorageSnapshot{
+		Registry:     reg.Name,
+		Time:         report.Time,
+		Size:         report.Size,
+		Layers:       report.Layers,
+		Repositories: map[string]int64{},
+	}
+
+	for _, rs := range report.Repositories {
+		snapshot.Repositories[rs.Repository] = rs.Size
+	}
+
+	if _, err := r.Table(tblNameStorageSnapshots).Insert(snapshot).RunWrite(m.session); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// StorageHistory returns the storage snapshots of the registry taken after
+// since ordered by time
+func (m DefaultManager) StorageHistory(reg *shipyard.Registry, since time.Time) ([]*shipyard.StorageSnapshot, error) {
+	res, err := r.Table(tblNameStorageSnapshots).Filter(func(s r.Term) r.Term {
+		return s.Field("registry").Eq(reg.Name).And(s.Field("time").Gt(since))
+	}).OrderBy(r.Asc("time")).Run(m.session)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []*shipyard.StorageSnapshot{}
+	if err := res.All(&snapshots); err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+// storageRecorder periodically records the storage used by every registry
+func (m DefaultManager) storageRecorder() {
+	t := time.NewTicker(storageSnapshotInterval).C
+	for range t {
+		registries, err := m.Registries()
+		if err != nil {
+			log.Errorf("error loading registries: %s", err)
+			continue
+		}
+
+		for _, reg := range registries {
+			if err := m.recordStorageSnapshot(reg); err != nil {
+				log.Errorf("error recording registry storage: registry=%s err=%s", reg.Name, err)
+			}
+		}
+	}
+}
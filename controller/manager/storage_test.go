@@ -0,0 +1,129 @@
+package manager
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shipyard/shipyard"
+)
+
+func testStorageImages() ([]storageImage, map[string]int64) {
+	images := []storageImage{
+		{repo: "app", tag: "1.0", id: "aaa", ancestry: []string{"aaa", "base"}},
+		{repo: "app", tag: "latest", id: "bbb", ancestry: []string{"bbb", "base"}},
+		{repo: "app", tag: "stable", id: "bbb", ancestry: []string{"bbb", "base"}},
+		{repo: "team/worker", tag: "latest", id: "ccc", ancestry: []string{"ccc", "base"}},
+	}
+	sizes := map[string]int64{
+		"aaa":  10,
+		"bbb":  20,
+		"ccc":  30,
+		"base": 100,
+	}
+
+	return images, sizes
+}
+
+func TestBuildStorageReport(t *testing.T) {
+	images, sizes := testStorageImages()
+	report := buildStorageReport("test", images, sizes)
+
+	if report.Size != 160 {
+		t.Fatalf("expected unique size 160; received %d", report.Size)
+	}
+
+	if report.Layers != 4 {
+		t.Fatalf("expected 4 layers; received %d", report.Layers)
+	}
+
+	if len(report.Repositories) != 2 {
+		t.Fatalf("expected 2 repositories; received %d", len(report.Repositories))
+	}
+
+	app := report.Repositories[0]
+	if app.Repository != "library/app" {
+		t.Fatalf("expected library/app first; received %s", app.Repository)
+	}
+
+	if app.Size != 130 || app.ExclusiveSize != 30 || app.SharedSize != 100 {
+		t.Fatalf("unexpected repository sizes: size=%d exclusive=%d shared=%d", app.Size, app.ExclusiveSize, app.SharedSize)
+	}
+
+	if len(app.Tags) != 3 {
+		t.Fatalf("expected 3 tags; received %d", len(app.Tags))
+	}
+
+	tag := app.Tags[0]
+	if tag.Tag != "1.0" || tag.Size != 110 || tag.ExclusiveSize != 10 || tag.SharedSize != 100 {
+		t.Fatalf("unexpected tag sizes for %s: size=%d exclusive=%d shared=%d", tag.Tag, tag.Size, tag.ExclusiveSize, tag.SharedSize)
+	}
+
+	// latest and stable point at the same image so neither is exclusive
+	if app.Tags[1].ExclusiveSize != 0 {
+		t.Fatalf("expected no exclusive bytes for latest; received %d", app.Tags[1].ExclusiveSize)
+	}
+}
+
+func TestReclaimableSize(t *testing.T) {
+	images, sizes := testStorageImages()
+
+	if n := reclaimableSize(images, sizes, map[string]bool{"library/app:1.0": true}); n != 10 {
+		t.Fatalf("expected 10 reclaimable bytes; received %d", n)
+	}
+
+	if n := reclaimableSize(images, sizes, map[string]bool{"library/app:latest": true}); n != 0 {
+		t.Fatalf("expected no reclaimable bytes for shared image; received %d", n)
+	}
+
+	all := map[string]bool{
+		"library/app:1.0":    true,
+		"library/app:latest": true,
+		"library/app:stable": true,
+		"team/worker:latest": true,
+	}
+	if n := reclaimableSize(images, sizes, all); n != 160 {
+		t.Fatalf("expected 160 reclaimable bytes; received %d", n)
+	}
+}
+
+func TestCollectStorageRepository(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(r.URL.Path, "/")
+		switch {
+		case r.URL.Path == "/v1/search":
+			fmt.Fprint(w, `{"results": [{"name": "library/app"}, {"name": "team/worker"}]}`)
+		case r.URL.Path == "/v1/repositories/library/app/tags":
+			fmt.Fprint(w, `{"latest": "aaa"}`)
+		case r.URL.Path == "/v1/repositories/team/worker/tags":
+			fmt.Fprint(w, `{"latest": "ccc"}`)
+		case strings.HasSuffix(r.URL.Path, "/ancestry"):
+			fmt.Fprintf(w, `[%q, "base"]`, parts[3])
+		case strings.HasSuffix(r.URL.Path, "/json"):
+			fmt.Fprintf(w, `{"id": %q, "Size": 10}`, parts[3])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	reg, err := shipyard.NewRegistry("0", "test", ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	images, sizes, errs, err := collectStorage(reg, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(images) != 2 || len(errs) != 0 {
+		t.Fatalf("expected the images of every repository; received %d images and errors %v", len(images), errs)
+	}
+
+	if _, ok := sizes["ccc"]; ok || len(sizes) != 2 {
+		t.Fatalf("expected only the layers of app to be sized; received %v", sizes)
+	}
+}
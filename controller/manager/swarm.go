@@ -0,0 +1,305 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shipyard/shipyard"
+)
+
+var (
+	ErrServiceDoesNotExist = errors.New("service does not exist")
+	ErrTaskDoesNotExist    = errors.New("task does not exist")
+	ErrSecretDoesNotExist  = errors.New("secret does not exist")
+)
+
+// SwarmError is an error returned by the swarm mode api of the engine.
+// StatusCode is the http status of the response (i.e. 503 if the engine
+// is not a swarm manager).
+type SwarmError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *SwarmError) Error() string {
+	return e.Message
+}
+
+// notFound replaces a not found error of the engine with err
+func notFound(swarmErr error, err error) error {
+	if e, ok := swarmErr.(*SwarmError); ok && e.StatusCode == http.StatusNotFound {
+		return err
+	}
+
+	return swarmErr
+}
+
+// swarmRequest sends a request to the swarm mode api of the engine and
+// decodes the response into result if it is not nil
+func (m DefaultManager) swarmRequest(method, path string, body, result interface{}) error {
+	var data []byte
+	if body != nil {
+		d, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		data = d
+	}
+
+	uri := fmt.Sprintf("%s/%s%s", m.client.URL.String(), shipyard.SwarmAPIVersion, path)
+	req, err := http.NewRequest(method, uri, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		swarmErr := &SwarmError{
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(msg)),
+		}
+
+		// newer engines return the message as json
+		var e struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(msg, &e); err == nil && e.Message != "" {
+			swarmErr.Message = e.Message
+		}
+
+		return swarmErr
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// swarmFilter returns the query string filtering by the key and value
+func swarmFilter(key, value string) string {
+	if value == "" {
+		return ""
+	}
+
+	filters, _ := json.Marshal(map[string][]string{
+		key: {value},
+	})
+
+	return "?filters=" + url.QueryEscape(string(filters))
+}
+
+// Services returns the services of the swarm mode cluster
+func (m DefaultManager) Services() ([]*shipyard.SwarmService, error) {
+	services := []*shipyard.SwarmService{}
+	if err := m.swarmRequest("GET", "/services", nil, &services); err != nil {
+		return nil, err
+	}
+
+	return services, nil
+}
+
+// Service returns the service by id or name
+func (m DefaultManager) Service(id string) (*shipyard.SwarmService, error) {
+	service := &shipyard.SwarmService{}
+	if err := m.swarmRequest("GET", "/services/"+id, nil, service); err != nil {
+		return nil, notFound(err, ErrServiceDoesNotExist)
+	}
+
+	return service, nil
+}
+
+// CreateService creates the service and returns its id
+func (m DefaultManager) CreateService(spec *shipyard.SwarmServiceSpec, username string) (string, error) {
+	var created struct {
+		ID string
+	}
+	if err := m.swarmRequest("POST", "/services/create", spec, &created); err != nil {
+		return "", err
+	}
+
+	m.logUserEvent("create-service", fmt.Sprintf("id=%s name=%s", created.ID, spec.Name), username, []string{"services"})
+
+	return created.ID, nil
+}
+
+// UpdateService replaces the spec of the service.  The version must be the
+// current version of the service.
+func (m DefaultManager) UpdateService(id string, version uint64, spec *shipyard.SwarmServiceSpec, username string) error {
+	path := fmt.Sprintf("/services/%s/update?version=%d", id, version)
+	if err := m.swarmRequest("POST", path, spec, nil); err != nil {
+		return notFound(err, ErrServiceDoesNotExist)
+	}
+
+	m.logUserEvent("update-service", fmt.Sprintf("id=%s name=%s version=%d", id, spec.Name, version), username, []string{"services"})
+
+	return nil
+}
+
+func (m DefaultManager) RemoveService(id, username string) error {
+	if err := m.swarmRequest("DELETE", "/services/"+id, nil, nil); err != nil {
+		return notFound(err, ErrServiceDoesNotExist)
+	}
+
+	m.logUserEvent("remove-service", fmt.Sprintf("id=%s", id), username, []string{"services"})
+
+	return nil
+}
+
+// Tasks returns the tasks of the service or all tasks if service is empty
+func (m DefaultManager) Tasks(service string) ([]*shipyard.SwarmTask, error) {
+	tasks := []*shipyard.SwarmTask{}
+	if err := m.swarmRequest("GET", "/tasks"+swarmFilter("service", service), nil, &tasks); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+func (m DefaultManager) Task(id string) (*shipyard.SwarmTask, error) {
+	task := &shipyard.SwarmTask{}
+	if err := m.swarmRequest("GET", "/tasks/"+id, nil, task); err != nil {
+		return nil, notFound(err, ErrTaskDoesNotExist)
+	}
+
+	return task, nil
+}
+
+// SwarmNodes returns the nodes as listed by the swarm mode api
+func (m DefaultManager) SwarmNodes() ([]*shipyard.SwarmNode, error) {
+	nodes := []*shipyard.SwarmNode{}
+	if err := m.swarmRequest("GET", "/nodes", nil, &nodes); err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+// Secrets returns the secrets of the swarm mode cluster without their data
+func (m DefaultManager) Secrets() ([]*shipyard.SwarmSecret, error) {
+	secrets := []*shipyard.SwarmSecret{}
+	if err := m.swarmRequest("GET", "/secrets", nil, &secrets); err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
+}
+
+func (m DefaultManager) Secret(id string) (*shipyard.SwarmSecret, error) {
+	secret := &shipyard.SwarmSecret{}
+	if err := m.swarmRequest("GET", "/secrets/"+id, nil, secret); err != nil {
+		return nil, notFound(err, ErrSecretDoesNotExist)
+	}
+
+	return secret, nil
+}
+
+// CreateSecret creates the secret and returns its id.  The data is not
+// included in the event.
+func (m DefaultManager) CreateSecret(spec *shipyard.SwarmSecretSpec, username string) (string, error) {
+	var created struct {
+		ID string
+	}
+	if err := m.swarmRequest("POST", "/secrets/create", spec, &created); err != nil {
+		return "", err
+	}
+
+	m.logUserEvent("create-secret", fmt.Sprintf("id=%s name=%s", created.ID, spec.Name), username, []string{"secrets"})
+
+	return created.ID, nil
+}
+
+func (m DefaultManager) RemoveSecret(id, username string) error {
+	if err := m.swarmRequest("DELETE", "/secrets/"+id, nil, nil); err != nil {
+		return notFound(err, ErrSecretDoesNotExist)
+	}
+
+	m.logUserEvent("remove-secret", fmt.Sprintf("id=%s", id), username, []string{"secrets"})
+
+	return nil
+}
+
+// swarmModeNode converts a node of a swarm mode cluster.  Containers is the
+// number of running tasks on the node.
+func swarmModeNode(n *shipyard.SwarmNode, running int) *shipyard.Node {
+	labels := []string{}
+	for k, v := range n.Description.Engine.Labels {
+		labels = append(labels, k+"="+v)
+	}
+	for k, v := range n.Spec.Labels {
+		labels = append(labels, k+"="+v)
+	}
+	sort.Strings(labels)
+
+	health := NodeHealthDown
+	if n.Status.State == "ready" {
+		health = NodeHealthUp
+	}
+
+	return &shipyard.Node{
+		ID:            n.ID,
+		Name:          n.Description.Hostname,
+		Addr:          n.Status.Addr,
+		Containers:    fmt.Sprintf("%d", running),
+		Labels:        labels,
+		Status:        n.Status.State,
+		Role:          n.Spec.Role,
+		Availability:  n.Spec.Availability,
+		ServerVersion: n.Description.Engine.EngineVersion,
+		Error:         n.Status.Message,
+		UpdatedAt:     n.UpdatedAt,
+		CPUsTotal:     float64(n.Description.Resources.NanoCPUs) / 1e9,
+		MemoryTotal:   n.Description.Resources.MemoryBytes,
+		Health:        health,
+	}
+}
+
+// swarmModeNodes returns the nodes of a swarm mode cluster
+func (m DefaultManager) swarmModeNodes() ([]*shipyard.Node, error) {
+	swarmNodes, err := m.SwarmNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	running := map[string]int{}
+	tasks, err := m.Tasks("")
+	if err != nil {
+		log.Warnf("error listing swarm tasks: %s", err)
+	}
+	for _, t := range tasks {
+		if t.Status.State == "running" {
+			running[t.NodeID]++
+		}
+	}
+
+	nodes := []*shipyard.Node{}
+	for _, n := range swarmNodes {
+		nodes = append(nodes, swarmModeNode(n, running[n.ID]))
+	}
+
+	sort.Sort(nodesByName(nodes))
+
+	return nodes, nil
+}
+
+type nodesByName []*shipyard.Node
+
+func (n nodesByName) Len() int           { return len(n) }
+func (n nodesByName) Swap(i, j int)      { n[i], n[j] = n[j], n[i] }
+func (n nodesByName) Less(i, j int) bool { return n[i].Name < n[j].Name }
@@ -0,0 +1,139 @@
+package manager
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+// swarmModeEngine is a stand-in engine which is a swarm mode manager
+func swarmModeEngine(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1.15/info":
+			fmt.Fprint(w, `{"Containers": 2, "DriverStatus": [["Root Dir", "/var/lib/docker/aufs"], ["Dirs", "10"]]}`)
+		case "/v1.25/nodes":
+			fmt.Fprint(w, `[
+				{"ID": "n2", "Spec": {"Role": "worker", "Availability": "drain", "Labels": {"zone": "b"}},
+				 "Description": {"Hostname": "worker1", "Resources": {"NanoCPUs": 2000000000, "MemoryBytes": 2048},
+				  "Engine": {"EngineVersion": "1.13.0", "Labels": {"storage": "ssd"}}},
+				 "Status": {"State": "down", "Message": "heartbeat failure", "Addr": "10.0.0.2"}},
+				{"ID": "n1", "Spec": {"Role": "manager", "Availability": "active"},
+				 "Description": {"Hostname": "manager1", "Resources": {"NanoCPUs": 4000000000, "MemoryBytes": 4096},
+				  "Engine": {"EngineVersion": "1.13.0"}},
+				 "Status": {"State": "ready", "Addr": "10.0.0.1"},
+				 "ManagerStatus": {"Leader": true, "Reachability": "reachable", "Addr": "10.0.0.1:2377"}}
+			]`)
+		case "/v1.25/tasks":
+			fmt.Fprint(w, `[
+				{"ID": "t1", "NodeID": "n1", "Status": {"State": "running"}},
+				{"ID": "t2", "NodeID": "n1", "Status": {"State": "running"}},
+				{"ID": "t3", "NodeID": "n2", "Status": {"State": "shutdown"}}
+			]`)
+		case "/v1.25/services/web":
+			fmt.Fprint(w, `{"ID": "s1", "Version": {"Index": 12}, "Spec": {"Name": "web", "Mode": {"Replicated": {"Replicas": 3}}, "TaskTemplate": {"ContainerSpec": {"Image": "nginx"}}}}`)
+		case "/v1.25/secrets":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"message": "This node is not a swarm manager."}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "service missing not found"}`)
+		}
+	}))
+}
+
+func getSwarmModeManager(t *testing.T, url string) DefaultManager {
+	client, err := dockerclient.NewDockerClient(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return DefaultManager{client: client}
+}
+
+func TestNodesSwarmMode(t *testing.T) {
+	ts := swarmModeEngine(t)
+	defer ts.Close()
+
+	nodes, err := getSwarmModeManager(t, ts.URL).Nodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nodes) != 2 {
+		t.Fatalf("expected 2 nodes; received %d", len(nodes))
+	}
+
+	manager := nodes[0]
+	if manager.Name != "manager1" || manager.Role != "manager" || manager.Addr != "10.0.0.1" {
+		t.Fatalf("unexpected manager node: %+v", manager)
+	}
+
+	if manager.Containers != "2" || manager.CPUsTotal != 4 || manager.MemoryTotal != 4096 || manager.Health != NodeHealthUp {
+		t.Fatalf("unexpected manager resources: %+v", manager)
+	}
+
+	worker := nodes[1]
+	if worker.Availability != "drain" || worker.Health != NodeHealthDown || worker.Error != "heartbeat failure" || worker.Containers != "0" {
+		t.Fatalf("unexpected worker node: %+v", worker)
+	}
+
+	if fmt.Sprint(worker.Labels) != "[storage=ssd zone=b]" {
+		t.Fatalf("expected engine and node labels; received %v", worker.Labels)
+	}
+}
+
+func TestSwarmRequestErrors(t *testing.T) {
+	ts := swarmModeEngine(t)
+	defer ts.Close()
+
+	m := getSwarmModeManager(t, ts.URL)
+
+	service, err := m.Service("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if service.Version.Index != 12 || service.Spec.Mode.Replicated == nil || *service.Spec.Mode.Replicated.Replicas != 3 {
+		t.Fatalf("unexpected service: %+v", service)
+	}
+
+	if string(service.Spec.TaskTemplate) != `{"ContainerSpec": {"Image": "nginx"}}` {
+		t.Fatalf("expected task template to be kept; received %s", service.Spec.TaskTemplate)
+	}
+
+	if _, err := m.Service("missing"); err != ErrServiceDoesNotExist {
+		t.Fatalf("expected ErrServiceDoesNotExist; received %v", err)
+	}
+
+	_, err = m.Secrets()
+	swarmErr, ok := err.(*SwarmError)
+	if !ok || swarmErr.StatusCode != http.StatusServiceUnavailable || swarmErr.Message != "This node is not a swarm manager." {
+		t.Fatalf("expected engine error; received %v", err)
+	}
+}
+
+func TestSwarmFilter(t *testing.T) {
+	if f := swarmFilter("service", ""); f != "" {
+		t.Fatalf("expected no filter; received %s", f)
+	}
+
+	if f := swarmFilter("service", "web"); f != "?filters=%7B%22service%22%3A%5B%22web%22%5D%7D" {
+		t.Fatalf("unexpected filter: %s", f)
+	}
+}
+
+func TestSwarmModeNode(t *testing.T) {
+	n := &shipyard.SwarmNode{ID: "n1"}
+	n.Description.Hostname = "node1"
+	n.Status.State = "unknown"
+
+	node := swarmModeNode(n, 0)
+	if node.Health != NodeHealthDown || node.Name != "node1" || len(node.Labels) != 0 {
+		t.Fatalf("unexpected node: %+v", node)
+	}
+}
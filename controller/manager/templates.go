@@ -0,0 +1,382 @@
+package manager
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/auth"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+const (
+	maxTemplateDeployCount = 100
+)
+
+// serviceAccount is the account of requests without a user.  Service keys
+// are not subject to role checks by the access middleware and internal
+// callers act for the system, so both have the rights of an admin.
+var serviceAccount = &auth.Account{Roles: []string{"admin"}}
+
+// userAccount returns the account of the user or the service account
+// for requests without a user
+func (m DefaultManager) userAccount(username string) (*auth.Account, error) {
+	if username == "" {
+		return serviceAccount, nil
+	}
+
+	return m.Account(username)
+}
+
+func hasRole(acct *auth.Account, role string) bool {
+	if acct == nil {
+		return false
+	}
+
+	for _, name := range acct.Roles {
+		if name == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isAdmin(acct *auth.Account) bool {
+	return hasRole(acct, "admin")
+}
+
+// canDeployTemplate reports whether the account may create containers from
+// templates.  Templates set the host config of their containers so
+// deploying them needs the same rights as creating containers.
+func canDeployTemplate(acct *auth.Account) bool {
+	return isAdmin(acct) || hasRole(acct, "containers:rw")
+}
+
+// canUseTemplate reports whether the account owns the template or is in one
+// of the teams it is shared with
+func canUseTemplate(t *shipyard.Template, acct *auth.Account) bool {
+	if isAdmin(acct) || t.Owner == acct.Username {
+		return true
+	}
+
+	for _, team := range t.Teams {
+		for _, role := range acct.Roles {
+			if team == role {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func canEditTemplate(t *shipyard.Template, acct *auth.Account) bool {
+	return isAdmin(acct) || t.Owner == acct.Username
+}
+
+// templateVersions returns the versions of the template, newest first
+func (m DefaultManager) templateVersions(name string) ([]*shipyard.Template, error) {
+	res, err := r.Table(tblNameTemplates).Filter(map[string]string{"name": name}).OrderBy(r.Desc("version")).Run(m.session)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []*shipyard.Template{}
+	if err := res.All(&versions); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// Templates returns the latest version of every template the user may use
+func (m DefaultManager) Templates(username string) ([]*shipyard.Template, error) {
+	acct, err := m.userAccount(username)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.Table(tblNameTemplates).OrderBy(r.Asc("name"), r.Desc("version")).Run(m.session)
+	if err != nil {
+		return nil, err
+	}
+
+	all := []*shipyard.Template{}
+	if err := res.All(&all); err != nil {
+		return nil, err
+	}
+
+	templates := []*shipyard.Template{}
+	seen := map[string]bool{}
+	for _, t := range all {
+		if seen[t.Name] {
+			continue
+		}
+		seen[t.Name] = true
+
+		if canUseTemplate(t, acct) {
+			templates = append(templates, t)
+		}
+	}
+
+	return templates, nil
+}
+
+// Template returns a version of the template.  A zero version selects the
+// latest version.
+func (m DefaultManager) Template(name string, version int, username string) (*shipyard.Template, error) {
+	acct, err := m.userAccount(username)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := m.templateVersions(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(versions) == 0 {
+		return nil, ErrTemplateDoesNotExist
+	}
+
+	// sharing is controlled by the latest version
+	if !canUseTemplate(versions[0], acct) {
+		return nil, ErrTemplateAccessDenied
+	}
+
+	if version == 0 {
+		return versions[0], nil
+	}
+
+	for _, t := range versions {
+		if t.Version == version {
+			return t, nil
+		}
+	}
+
+	return nil, ErrTemplateDoesNotExist
+}
+
+// TemplateVersions returns all versions of the template, newest first
+func (m DefaultManager) TemplateVersions(name, username string) ([]*shipyard.Template, error) {
+	if _, err := m.Template(name, 0, username); err != nil {
+		return nil, err
+	}
+
+	return m.templateVersions(name)
+}
+
+// SaveTemplate stores a new version of the template.  Only the owner of an
+// existing template or an admin may save new versions.
+func (m DefaultManager) SaveTemplate(t *shipyard.Template, username string) error {
+	if !applicationNameRegexp.MatchString(t.Name) {
+		return fmt.Errorf("invalid template name: %s", t.Name)
+	}
+
+	if err := validateVariables(t.Variables); err != nil {
+		return err
+	}
+
+	if t.HealthProbe != nil {
+		if err := validateProbe(t.HealthProbe); err != nil {
+			return err
+		}
+	}
+
+	// optional variables render to their zero value
+	sample := map[string]interface{}{}
+	for _, v := range t.Variables {
+		if v.Required && v.Default == nil {
+			sample[v.Name] = zeroVariable(v.Type)
+		}
+	}
+	if _, err := renderTemplate(t, sample); err != nil {
+		return fmt.Errorf("invalid template: %s", err)
+	}
+
+	acct, err := m.userAccount(username)
+	if err != nil {
+		return err
+	}
+
+	versions, err := m.templateVersions(t.Name)
+	if err != nil {
+		return err
+	}
+
+	t.ID = ""
+	t.Version = 1
+	t.Owner = username
+	t.Created = time.Now()
+	if t.Variables == nil {
+		t.Variables = []*shipyard.TemplateVariable{}
+	}
+	if t.Teams == nil {
+		t.Teams = []string{}
+	}
+
+	if len(versions) > 0 {
+		if !canEditTemplate(versions[0], acct) {
+			return ErrTemplateAccessDenied
+		}
+
+		t.Version = versions[0].Version + 1
+		t.Owner = versions[0].Owner
+	}
+
+	res, err := r.Table(tblNameTemplates).Insert(t).RunWrite(m.session)
+	if err != nil {
+		return err
+	}
+
+	if len(res.GeneratedKeys) > 0 {
+		t.ID = res.GeneratedKeys[0]
+	}
+
+	m.logUserEvent("save-template", fmt.Sprintf("name=%s version=%d", t.Name, t.Version), username, []string{"templates"})
+
+	return nil
+}
+
+// RemoveTemplate removes all versions of the template
+func (m DefaultManager) RemoveTemplate(name, username string) error {
+	acct, err := m.userAccount(username)
+	if err != nil {
+		return err
+	}
+
+	t, err := m.Template(name, 0, username)
+	if err != nil {
+		return err
+	}
+
+	if !canEditTemplate(t, acct) {
+		return ErrTemplateAccessDenied
+	}
+
+	if _, err := r.Table(tblNameTemplates).Filter(map[string]string{"name": name}).Delete().RunWrite(m.session); err != nil {
+		return err
+	}
+
+	m.logUserEvent("remove-template", fmt.Sprintf("name=%s", name), username, []string{"templates"})
+
+	return nil
+}
+
+// RenderTemplate validates the inputs and returns the rendered config
+func (m DefaultManager) RenderTemplate(name string, deployment *shipyard.TemplateDeployment, username string) (*dockerclient.ContainerConfig, error) {
+	t, err := m.Template(name, deployment.Version, username)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := renderTemplate(t, deployment.Variables)
+	if err != nil {
+		return nil, &TemplateError{err}
+	}
+
+	if config.Labels == nil {
+		config.Labels = map[string]string{}
+	}
+	config.Labels[shipyard.TemplateLabel] = t.Name
+	config.Labels[shipyard.TemplateVersionLabel] = strconv.Itoa(t.Version)
+	if t.HealthProbe != nil {
+		for k, v := range probeLabels(t.HealthProbe) {
+			config.Labels[k] = v
+		}
+	}
+
+	return config, nil
+}
+
+// DeployTemplate creates and starts containers from the template.  With a
+// name the containers are named name (or name_n for several containers) and
+// form a group of that name.  A revision is recorded for the group or for
+// each container deployed without a name.
+func (m DefaultManager) DeployTemplate(name string, deployment *shipyard.TemplateDeployment, username string) (ApplicationResult, error) {
+	count := deployment.Count
+	if count == 0 {
+		count = 1
+	}
+
+	if count < 0 || count > maxTemplateDeployCount {
+		return ApplicationResult{}, &TemplateError{fmt.Errorf("count must be between 1 and %d", maxTemplateDeployCount)}
+	}
+
+	if deployment.Name != "" && !applicationNameRegexp.MatchString(deployment.Name) {
+		return ApplicationResult{}, &TemplateError{fmt.Errorf("invalid container name: %s", deployment.Name)}
+	}
+
+	acct, err := m.userAccount(username)
+	if err != nil {
+		return ApplicationResult{}, err
+	}
+
+	if !canDeployTemplate(acct) {
+		return ApplicationResult{}, ErrTemplateDeployDenied
+	}
+
+	config, err := m.RenderTemplate(name, deployment, username)
+	if err != nil {
+		return ApplicationResult{}, err
+	}
+
+	if deployment.Name != "" {
+		config.Labels[shipyard.GroupLabel] = deployment.Name
+	}
+
+	result := ApplicationResult{Containers: []string{}, Errors: []string{}}
+	for i := 1; i <= count; i++ {
+		containerName := deployment.Name
+		if containerName != "" && count > 1 {
+			containerName = fmt.Sprintf("%s_%d", deployment.Name, i)
+		}
+
+		c := *config
+		id, err := m.createContainer(&c, containerName, username)
+		if err != nil {
+			result.Errors = append(result.Errors, strings.TrimSpace(err.Error()))
+			continue
+		}
+
+		if err := m.client.StartContainer(id, &c.HostConfig); err != nil {
+			if rerr := m.client.RemoveContainer(id, true, false); rerr != nil {
+				log.Errorf("error removing container: id=%s err=%s", id, rerr)
+			}
+			result.Errors = append(result.Errors, strings.TrimSpace(err.Error()))
+			continue
+		}
+
+		result.Containers = append(result.Containers, id)
+
+		if deployment.Name == "" {
+			if err := m.recordDeployRevision("", id, username); err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			}
+		}
+	}
+
+	if deployment.Name != "" && len(result.Containers) > 0 {
+		if err := m.recordDeployRevision(deployment.Name, "", username); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+	}
+
+	m.logUserEvent("deploy-template", fmt.Sprintf("name=%s version=%s containers=%d errors=%d", name, config.Labels[shipyard.TemplateVersionLabel], len(result.Containers), len(result.Errors)), username, []string{"templates", "containers"})
+
+	return result, nil
+}
+
+// TemplateError is returned for invalid template inputs
+type TemplateError struct {
+	Err error
+}
+
+func (e *TemplateError) Error() string {
+	return e.Err.Error()
+}
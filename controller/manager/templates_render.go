@@ -0,0 +1,319 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"text/template"
+
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+var (
+	templateVariableRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// a value consisting of a single variable (i.e. "{{.Env}}")
+	singleVariableRegexp = regexp.MustCompile(`^\{\{\s*\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}$`)
+)
+
+// coerceVariable converts a decoded JSON value to the type of the variable
+func coerceVariable(typ string, v interface{}) (interface{}, error) {
+	switch typ {
+	case shipyard.TemplateVariableString:
+		switch s := v.(type) {
+		case string:
+			return s, nil
+		case float64:
+			return strconv.FormatFloat(s, 'f', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(s), nil
+		}
+	case shipyard.TemplateVariableInt:
+		switch n := v.(type) {
+		case float64:
+			if n == math.Trunc(n) {
+				return int64(n), nil
+			}
+		case int:
+			return int64(n), nil
+		case int64:
+			return n, nil
+		case string:
+			if i, err := strconv.ParseInt(n, 10, 64); err == nil {
+				return i, nil
+			}
+		}
+	case shipyard.TemplateVariableBool:
+		switch b := v.(type) {
+		case bool:
+			return b, nil
+		case string:
+			if parsed, err := strconv.ParseBool(b); err == nil {
+				return parsed, nil
+			}
+		}
+	case shipyard.TemplateVariableList:
+		switch l := v.(type) {
+		case []string:
+			return l, nil
+		case []interface{}:
+			list := []string{}
+			for _, item := range l {
+				s, err := coerceVariable(shipyard.TemplateVariableString, item)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, s.(string))
+			}
+			return list, nil
+		}
+	case shipyard.TemplateVariableMap:
+		switch m := v.(type) {
+		case map[string]string:
+			return m, nil
+		case map[string]interface{}:
+			mapping := map[string]string{}
+			for k, item := range m {
+				s, err := coerceVariable(shipyard.TemplateVariableString, item)
+				if err != nil {
+					return nil, err
+				}
+				mapping[k] = s.(string)
+			}
+			return mapping, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown type: %s", typ)
+	}
+
+	return nil, fmt.Errorf("expected a %s; received %v", typ, v)
+}
+
+func zeroVariable(typ string) interface{} {
+	switch typ {
+	case shipyard.TemplateVariableInt:
+		return int64(0)
+	case shipyard.TemplateVariableBool:
+		return false
+	case shipyard.TemplateVariableList:
+		return []string{}
+	case shipyard.TemplateVariableMap:
+		return map[string]string{}
+	}
+
+	return ""
+}
+
+// validateVariables checks the names, types and defaults of the variables
+func validateVariables(vars []*shipyard.TemplateVariable) error {
+	seen := map[string]bool{}
+	for _, v := range vars {
+		if !templateVariableRegexp.MatchString(v.Name) {
+			return fmt.Errorf("invalid variable name: %s", v.Name)
+		}
+
+		if seen[v.Name] {
+			return fmt.Errorf("duplicate variable: %s", v.Name)
+		}
+		seen[v.Name] = true
+
+		if v.Type == "" {
+			v.Type = shipyard.TemplateVariableString
+		}
+
+		if _, err := coerceVariable(v.Type, zeroVariable(v.Type)); err != nil {
+			return fmt.Errorf("variable %s: %s", v.Name, err)
+		}
+
+		if v.Default != nil {
+			if _, err := coerceVariable(v.Type, v.Default); err != nil {
+				return fmt.Errorf("variable %s: invalid default: %s", v.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// templateValues checks the inputs against the variables and applies the
+// defaults.  Optional variables without a default are set to their zero
+// value.
+func templateValues(vars []*shipyard.TemplateVariable, inputs map[string]interface{}) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	known := map[string]bool{}
+
+	for _, v := range vars {
+		known[v.Name] = true
+
+		in, ok := inputs[v.Name]
+		if !ok || in == nil {
+			in = v.Default
+		}
+
+		if in == nil {
+			if v.Required {
+				return nil, fmt.Errorf("variable %s is required", v.Name)
+			}
+			values[v.Name] = zeroVariable(v.Type)
+			continue
+		}
+
+		val, err := coerceVariable(v.Type, in)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %s", v.Name, err)
+		}
+		values[v.Name] = val
+	}
+
+	for name := range inputs {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown variable: %s", name)
+		}
+	}
+
+	return values, nil
+}
+
+// expandValue returns the strings a list or map value expands to in a list
+func expandValue(v interface{}) ([]interface{}, bool) {
+	items := []interface{}{}
+	switch t := v.(type) {
+	case []string:
+		for _, s := range t {
+			items = append(items, s)
+		}
+	case map[string]string:
+		keys := []string{}
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			items = append(items, k+"="+t[k])
+		}
+	default:
+		return nil, false
+	}
+
+	return items, true
+}
+
+func renderString(s string, values map[string]interface{}) (interface{}, error) {
+	if m := singleVariableRegexp.FindStringSubmatch(s); m != nil {
+		v, ok := values[m[1]]
+		if !ok {
+			return nil, fmt.Errorf("unknown variable: %s", m[1])
+		}
+		return v, nil
+	}
+
+	t, err := template.New("").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, values); err != nil {
+		return nil, err
+	}
+
+	return buf.String(), nil
+}
+
+// renderValue renders the strings of a decoded JSON value
+func renderValue(v interface{}, values map[string]interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case string:
+		return renderString(t, values)
+	case []interface{}:
+		list := []interface{}{}
+		for _, item := range t {
+			rendered, err := renderValue(item, values)
+			if err != nil {
+				return nil, err
+			}
+
+			// list and map variables are spliced into the list
+			if s, ok := item.(string); ok && singleVariableRegexp.MatchString(s) {
+				if items, ok := expandValue(rendered); ok {
+					list = append(list, items...)
+					continue
+				}
+			}
+
+			list = append(list, rendered)
+		}
+		return list, nil
+	case map[string]interface{}:
+		mapping := map[string]interface{}{}
+		for k, item := range t {
+			key, err := renderString(k, values)
+			if err != nil {
+				return nil, err
+			}
+
+			keyString, ok := key.(string)
+			if !ok {
+				keyString = fmt.Sprint(key)
+			}
+
+			rendered, err := renderValue(item, values)
+			if err != nil {
+				return nil, err
+			}
+			mapping[keyString] = rendered
+		}
+		return mapping, nil
+	}
+
+	return v, nil
+}
+
+func renderInto(blueprint map[string]interface{}, values map[string]interface{}, dst interface{}) error {
+	if blueprint == nil {
+		return nil
+	}
+
+	rendered, err := renderValue(blueprint, values)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(rendered)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, dst)
+}
+
+// renderTemplate returns the container config of the template for the inputs
+func renderTemplate(t *shipyard.Template, inputs map[string]interface{}) (*dockerclient.ContainerConfig, error) {
+	values, err := templateValues(t.Variables, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &dockerclient.ContainerConfig{}
+	if err := renderInto(t.Config, values, config); err != nil {
+		return nil, fmt.Errorf("config: %s", err)
+	}
+
+	hostConfig := dockerclient.HostConfig{}
+	if err := renderInto(t.HostConfig, values, &hostConfig); err != nil {
+		return nil, fmt.Errorf("host config: %s", err)
+	}
+	config.HostConfig = hostConfig
+
+	if config.Image == "" {
+		return nil, errors.New("the rendered config has no image")
+	}
+
+	return config, nil
+}
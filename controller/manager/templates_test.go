@@ -0,0 +1,128 @@
+package manager
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/auth"
+)
+
+func getTestTemplate() *shipyard.Template {
+	return &shipyard.Template{
+		Name: "web",
+		Variables: []*shipyard.TemplateVariable{
+			{Name: "Version", Type: shipyard.TemplateVariableString, Required: true},
+			{Name: "Env", Type: shipyard.TemplateVariableMap},
+			{Name: "Memory", Type: shipyard.TemplateVariableInt, Default: float64(64 << 20)},
+			{Name: "Port", Type: shipyard.TemplateVariableString, Default: "8080"},
+		},
+		Config: map[string]interface{}{
+			"Image":        "example/web:{{.Version}}",
+			"Env":          []interface{}{"MODE=prod", "{{.Env}}"},
+			"ExposedPorts": map[string]interface{}{"{{.Port}}/tcp": map[string]interface{}{}},
+		},
+		HostConfig: map[string]interface{}{
+			"Memory": "{{.Memory}}",
+			"PortBindings": map[string]interface{}{
+				"{{.Port}}/tcp": []interface{}{map[string]interface{}{"HostPort": ""}},
+			},
+		},
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	config, err := renderTemplate(getTestTemplate(), map[string]interface{}{
+		"Version": "1.2",
+		"Env":     map[string]interface{}{"B": "2", "A": float64(1)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Image != "example/web:1.2" {
+		t.Fatalf("expected image example/web:1.2; received %s", config.Image)
+	}
+
+	if expected := []string{"MODE=prod", "A=1", "B=2"}; !reflect.DeepEqual(config.Env, expected) {
+		t.Fatalf("expected env %v; received %v", expected, config.Env)
+	}
+
+	if _, ok := config.ExposedPorts["8080/tcp"]; !ok {
+		t.Fatalf("expected port 8080/tcp to be exposed; received %v", config.ExposedPorts)
+	}
+
+	if config.HostConfig.Memory != 64<<20 {
+		t.Fatalf("expected memory %d; received %d", 64<<20, config.HostConfig.Memory)
+	}
+
+	if _, ok := config.HostConfig.PortBindings["8080/tcp"]; !ok {
+		t.Fatalf("expected port binding for 8080/tcp; received %v", config.HostConfig.PortBindings)
+	}
+}
+
+func TestRenderTemplateInvalidInputs(t *testing.T) {
+	for _, inputs := range []map[string]interface{}{
+		{},
+		{"Version": "1.2", "Unknown": "x"},
+		{"Version": "1.2", "Memory": 1.5},
+		{"Version": "1.2", "Env": []interface{}{"A=1"}},
+	} {
+		if _, err := renderTemplate(getTestTemplate(), inputs); err == nil {
+			t.Fatalf("expected error for %v", inputs)
+		}
+	}
+}
+
+func TestValidateVariables(t *testing.T) {
+	for _, vars := range [][]*shipyard.TemplateVariable{
+		{{Name: "1st"}},
+		{{Name: "A"}, {Name: "A"}},
+		{{Name: "A", Type: "float"}},
+		{{Name: "A", Type: shipyard.TemplateVariableBool, Default: "maybe"}},
+	} {
+		if err := validateVariables(vars); err == nil {
+			t.Fatalf("expected error for %v", vars[len(vars)-1])
+		}
+	}
+}
+
+func TestCanUseTemplate(t *testing.T) {
+	tmpl := &shipyard.Template{Owner: "alice", Teams: []string{"team-web"}}
+
+	for _, test := range []struct {
+		acct *auth.Account
+		use  bool
+		edit bool
+	}{
+		{&auth.Account{Username: "alice"}, true, true},
+		{&auth.Account{Username: "bob", Roles: []string{"team-web"}}, true, false},
+		{&auth.Account{Username: "carol", Roles: []string{"team-db"}}, false, false},
+		{&auth.Account{Username: "root", Roles: []string{"admin"}}, true, true},
+	} {
+		if canUseTemplate(tmpl, test.acct) != test.use {
+			t.Errorf("expected use=%t for %s", test.use, test.acct.Username)
+		}
+
+		if canEditTemplate(tmpl, test.acct) != test.edit {
+			t.Errorf("expected edit=%t for %s", test.edit, test.acct.Username)
+		}
+	}
+}
+
+func TestCanDeployTemplate(t *testing.T) {
+	for _, test := range []struct {
+		acct   *auth.Account
+		deploy bool
+	}{
+		{&auth.Account{Username: "alice", Roles: []string{"templates:rw"}}, false},
+		{&auth.Account{Username: "bob", Roles: []string{"templates:rw", "containers:rw"}}, true},
+		{&auth.Account{Username: "root", Roles: []string{"admin"}}, true},
+		{serviceAccount, true},
+		{nil, false},
+	} {
+		if canDeployTemplate(test.acct) != test.deploy {
+			t.Errorf("expected deploy=%t for %+v", test.deploy, test.acct)
+		}
+	}
+}
@@ -0,0 +1,435 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+const (
+	defaultUpdateTimeout = 60
+	// seconds to wait for a replaced container to stop
+	updateStopTimeout = 10
+)
+
+var (
+	ErrNothingToUpdate = errors.New("no containers to update")
+
+	// updatePollInterval is the interval new containers are checked at
+	updatePollInterval = time.Second
+
+	// updateEventStages are the stages of a rolling update that are
+	// recorded as events
+	updateEventStages = map[string]bool{
+		"start":       true,
+		"complete":    true,
+		"aborted":     true,
+		"rolled-back": true,
+	}
+)
+
+// replacement is a container replaced by a rolling update.  The previous
+// container is kept stopped until the update completes.
+type replacement struct {
+	previous   *dockerclient.ContainerInfo
+	wasRunning bool
+	name       string
+	id         string
+}
+
+// previousName is the name of a replaced container until the update
+// completes
+func previousName(name string) string {
+	return name + "_previous"
+}
+
+// updateBatches splits the containers into batches of size containers
+func updateBatches(containers []dockerclient.Container, size int) [][]dockerclient.Container {
+	batches := [][]dockerclient.Container{}
+	for i := 0; i < len(containers); i += size {
+		end := i + size
+		if end > len(containers) {
+			end = len(containers)
+		}
+		batches = append(batches, containers[i:end])
+	}
+
+	return batches
+}
+
+// validateUpdate checks the update and applies the defaults
+func validateUpdate(update *shipyard.RollingUpdate, containers []dockerclient.Container) (shipyard.RollingUpdate, error) {
+	u := *update
+
+	if u.BatchSize < 0 || u.Delay < 0 || u.Timeout < 0 || u.FailureThreshold < 0 {
+		return u, errors.New("batch size, delay, timeout and failure threshold must not be negative")
+	}
+
+	if u.BatchSize == 0 {
+		u.BatchSize = 1
+	}
+
+	if u.Timeout == 0 {
+		u.Timeout = defaultUpdateTimeout
+	}
+
+	if u.FailureThreshold == 0 {
+		u.FailureThreshold = 1
+	}
+
+	if u.HealthProbe != nil {
+		if err := validateProbe(u.HealthProbe); err != nil {
+			return u, err
+		}
+	}
+
+	if len(containers) == 0 {
+		return u, ErrNothingToUpdate
+	}
+
+	if u.Image != "" {
+		images := map[string]bool{}
+		for _, c := range containers {
+			images[c.Image] = true
+		}
+
+		if len(images) > 1 {
+			return u, errors.New("the containers run different images; select a service to update")
+		}
+	}
+
+	return u, nil
+}
+
+// UpdateApplication replaces the containers of the application (or of one
+// of its services) in a rolling update
+func (m DefaultManager) UpdateApplication(app *shipyard.Application, update *shipyard.RollingUpdate, username string, progress func(*shipyard.UpdateProgress)) (*shipyard.UpdateResult, error) {
+	defer applicationOperations.begin(app.Name)()
+
+	containers := []dockerclient.Container{}
+	for _, c := range app.Containers {
+		if update.Service == "" || c.Labels[shipyard.ServiceLabel] == update.Service {
+			containers = append(containers, c)
+		}
+	}
+
+	return m.rollingUpdate(app.Name, containers, update, username, progress, func() error {
+		return m.recordApplicationRevision(app, "update", username)
+	})
+}
+
+// UpdateGroup replaces the containers of the group in a rolling update
+func (m DefaultManager) UpdateGroup(group string, update *shipyard.RollingUpdate, username string, progress func(*shipyard.UpdateProgress)) (*shipyard.UpdateResult, error) {
+	containers, err := m.groupContainers(group)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(containers) == 0 {
+		return nil, ErrGroupDoesNotExist
+	}
+
+	defer beginContainersOperation(containers)()
+
+	return m.rollingUpdate(group, containers, update, username, progress, func() error {
+		return m.recordGroupRevision(group, "update", username)
+	})
+}
+
+// rollingUpdate replaces the containers in batches.  Errors are only
+// returned before the update started; afterwards they are reported in the
+// result.  The update is rolled back if its revision cannot be recorded.
+func (m DefaultManager) rollingUpdate(target string, containers []dockerclient.Container, update *shipyard.RollingUpdate, username string, progress func(*shipyard.UpdateProgress), record func() error) (*shipyard.UpdateResult, error) {
+	u, err := validateUpdate(update, containers)
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := append([]dockerclient.Container{}, containers...)
+	sort.Sort(containersByName(sorted))
+
+	var (
+		result = &shipyard.UpdateResult{
+			Target:   target,
+			Image:    u.Image,
+			Replaced: []string{},
+			Failed:   []string{},
+			Errors:   []string{},
+		}
+		replaced = []*replacement{}
+		failures = 0
+	)
+
+	emit := func(stage, container, message string, res *shipyard.UpdateResult) {
+		p := &shipyard.UpdateProgress{
+			Target:    target,
+			Stage:     stage,
+			Container: container,
+			Message:   message,
+			Replaced:  len(replaced),
+			Failures:  failures,
+			Total:     len(sorted),
+			Time:      time.Now(),
+			Result:    res,
+		}
+
+		// only the outcome is recorded as event; the steps would flood the
+		// event log of large updates
+		details := fmt.Sprintf("target=%s stage=%s container=%s message=%s", target, stage, container, message)
+		if updateEventStages[stage] {
+			m.logUserEvent("rolling-update", details, username, []string{"containers", "update"})
+		} else {
+			log.Infof("rolling update: %s", details)
+		}
+
+		if progress != nil {
+			progress(p)
+		}
+	}
+
+	rollback := func(message string) *shipyard.UpdateResult {
+		emit("rollback", "", message, nil)
+
+		for k := len(replaced) - 1; k >= 0; k-- {
+			if err := m.restoreContainer(replaced[k]); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: rollback: %s", replaced[k].name, strings.TrimSpace(err.Error())))
+			}
+		}
+
+		result.Replaced = []string{}
+		result.RolledBack = true
+		replaced = replaced[:0]
+		emit("rolled-back", "", "", result)
+
+		return result
+	}
+
+	emit("start", "", fmt.Sprintf("updating %d containers in batches of %d", len(sorted), u.BatchSize), nil)
+
+	// pull first so that the containers are recreated from the latest image
+	if err := m.pullUpdateImages(sorted, u.Image, username); err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		emit("aborted", "", err.Error(), result)
+		return result, nil
+	}
+
+	hosts := m.nodeHosts()
+
+	for i, batch := range updateBatches(sorted, u.BatchSize) {
+		if i > 0 && u.Delay > 0 {
+			time.Sleep(time.Duration(u.Delay) * time.Second)
+		}
+
+		reps := make([]*replacement, len(batch))
+		errs := make([]error, len(batch))
+
+		var wg sync.WaitGroup
+		for j, c := range batch {
+			emit("replacing", containerName(c), "", nil)
+
+			wg.Add(1)
+			go func(j int, c dockerclient.Container) {
+				defer wg.Done()
+				reps[j], errs[j] = m.replaceContainer(c, &u, username, hosts[containerNode(c)])
+			}(j, c)
+		}
+		wg.Wait()
+
+		for j, c := range batch {
+			name := containerName(c)
+			if errs[j] != nil {
+				failures++
+				msg := strings.TrimSpace(errs[j].Error())
+				log.Errorf("error updating container: name=%s err=%s", name, msg)
+				result.Failed = append(result.Failed, name)
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", name, msg))
+				emit("failed", name, msg, nil)
+				continue
+			}
+
+			replaced = append(replaced, reps[j])
+			result.Replaced = append(result.Replaced, reps[j].id)
+			emit("replaced", name, "", nil)
+		}
+
+		if failures >= u.FailureThreshold {
+			return rollback(fmt.Sprintf("%d containers failed; rolling back %d replaced containers", failures, len(replaced))), nil
+		}
+	}
+
+	if len(replaced) > 0 {
+		if err := record(); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			return rollback(fmt.Sprintf("%s; rolling back %d replaced containers", err, len(replaced))), nil
+		}
+	}
+
+	// the update succeeded so the previous containers are no longer needed
+	for _, rep := range replaced {
+		if err := m.client.RemoveContainer(rep.previous.Id, true, false); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: removing previous container: %s", rep.name, strings.TrimSpace(err.Error())))
+		}
+	}
+
+	emit("complete", "", fmt.Sprintf("replaced %d of %d containers", len(replaced), len(sorted)), result)
+
+	return result, nil
+}
+
+func (m DefaultManager) pullUpdateImages(containers []dockerclient.Container, image, username string) error {
+	images := map[string]bool{}
+	if image != "" {
+		images[image] = true
+	} else {
+		for _, c := range containers {
+			images[c.Image] = true
+		}
+	}
+
+	for img := range images {
+		authConfig, err := m.RegistryAuthConfig(username, img)
+		if err != nil {
+			return err
+		}
+
+		log.Debugf("pulling image: image=%s", img)
+		if err := m.client.PullImage(withDefaultTag(img), authConfig); err != nil {
+			return fmt.Errorf("pulling %s: %s", img, strings.TrimSpace(err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// replaceContainer stops the container and creates its replacement on the
+// same node.  The container is restored if the replacement does not become
+// healthy.
+func (m DefaultManager) replaceContainer(c dockerclient.Container, update *shipyard.RollingUpdate, username, nodeHost string) (*replacement, error) {
+	info, err := m.client.InspectContainer(c.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &replacement{
+		previous:   info,
+		wasRunning: info.State.Running,
+		name:       containerName(c),
+	}
+
+	config := *info.Config
+	// clear hostname to get a newly generated
+	config.Hostname = ""
+	if update.Image != "" {
+		config.Image = update.Image
+	}
+	// keep the container on its node where its volumes and ports are
+	if node := containerNode(c); node != "" {
+		withNodeConstraint(&config, node)
+	}
+	// nodes in maintenance give up their containers
+	if _, err := m.PlaceContainer(&config); err != nil {
+		return nil, err
+	}
+	config.HostConfig = *info.HostConfig
+
+	if rep.wasRunning {
+		if err := m.client.StopContainer(info.Id, updateStopTimeout); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := m.client.RenameContainer(info.Id, previousName(rep.name)); err != nil {
+		if rep.wasRunning {
+			m.client.StartContainer(info.Id, nil)
+		}
+		return nil, err
+	}
+
+	fail := func(err error) (*replacement, error) {
+		if rerr := m.restoreContainer(rep); rerr != nil {
+			return nil, fmt.Errorf("%s (restoring previous container: %s)", err, rerr)
+		}
+		return nil, err
+	}
+
+	id, err := m.createContainer(&config, rep.name, username)
+	if err != nil {
+		return fail(err)
+	}
+	rep.id = id
+
+	if !rep.wasRunning {
+		return rep, nil
+	}
+
+	if err := m.client.StartContainer(id, &config.HostConfig); err != nil {
+		return fail(err)
+	}
+
+	if err := m.waitHealthy(id, update.HealthProbe, nodeHost, time.Duration(update.Timeout)*time.Second); err != nil {
+		return fail(err)
+	}
+
+	return rep, nil
+}
+
+// restoreContainer removes the replacement and restores the previous
+// container
+func (m DefaultManager) restoreContainer(rep *replacement) error {
+	if rep.id != "" {
+		if err := m.client.RemoveContainer(rep.id, true, false); err != nil {
+			return err
+		}
+	}
+
+	if err := m.client.RenameContainer(rep.previous.Id, rep.name); err != nil {
+		return err
+	}
+
+	if rep.wasRunning {
+		return m.client.StartContainer(rep.previous.Id, nil)
+	}
+
+	return nil
+}
+
+// waitHealthy waits for the container to run and pass the health probe
+func (m DefaultManager) waitHealthy(id string, probe *shipyard.HealthProbe, nodeHost string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	lastErr := errors.New("container is not running")
+
+	for {
+		info, err := m.client.InspectContainer(id)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case info.State.Running:
+			if probe == nil {
+				return nil
+			}
+
+			err := m.checkProbe(info, probe, nodeHost)
+			if err == nil {
+				return nil
+			}
+			lastErr = fmt.Errorf("health probe failed: %s", err)
+		case !info.State.Restarting && !info.State.StartedAt.IsZero():
+			return fmt.Errorf("container exited with code %d", info.State.ExitCode)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("not healthy after %s: %s", timeout, lastErr)
+		}
+
+		time.Sleep(updatePollInterval)
+	}
+}
@@ -0,0 +1,51 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+func TestUpdateBatches(t *testing.T) {
+	containers := make([]dockerclient.Container, 5)
+
+	batches := updateBatches(containers, 2)
+	if len(batches) != 3 {
+		t.Fatalf("expected 3 batches; received %d", len(batches))
+	}
+
+	if len(batches[2]) != 1 {
+		t.Fatalf("expected last batch to have 1 container; received %d", len(batches[2]))
+	}
+}
+
+func TestValidateUpdateDefaults(t *testing.T) {
+	u, err := validateUpdate(&shipyard.RollingUpdate{}, []dockerclient.Container{{Image: "nginx"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u.BatchSize != 1 || u.Timeout != defaultUpdateTimeout || u.FailureThreshold != 1 {
+		t.Fatalf("expected defaults to be applied; received %+v", u)
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	containers := []dockerclient.Container{{Image: "nginx"}, {Image: "redis"}}
+
+	for _, update := range []*shipyard.RollingUpdate{
+		{BatchSize: -1},
+		{Image: "nginx:1.11"},
+		{HealthProbe: &shipyard.HealthProbe{Type: "udp", Port: "53"}},
+		{HealthProbe: &shipyard.HealthProbe{Type: shipyard.HealthProbeHTTP}},
+	} {
+		if _, err := validateUpdate(update, containers); err == nil {
+			t.Fatalf("expected error for %+v", update)
+		}
+	}
+
+	if _, err := validateUpdate(&shipyard.RollingUpdate{}, nil); err != ErrNothingToUpdate {
+		t.Fatalf("expected ErrNothingToUpdate; received %v", err)
+	}
+}
@@ -5,9 +5,13 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
 	"strings"
 	"time"
 
+	log "github.com/Sirupsen/logrus"
 	"github.com/shipyard/shipyard"
 )
 
@@ -36,70 +40,235 @@ func generateId(n int) string {
 	return mdStr[:n]
 }
 
-func parseClusterNodes(driverStatus [][]string) ([]*shipyard.Node, error) {
+// parseImageName splits an image reference into the registry host,
+// repository and tag.  The host is empty for Docker Hub images and the
+// tag defaults to "latest" unless the image is referenced by digest.
+func parseImageName(image string) (host, repo, tag string) {
+	name := image
+	if i := strings.Index(name, "@"); i > -1 {
+		name = name[:i]
+	} else {
+		tag = "latest"
+		if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+			tag = name[i+1:]
+			name = name[:i]
+		}
+	}
+
+	if i := strings.Index(name, "/"); i > -1 {
+		first := name[:i]
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			host = first
+			name = name[i+1:]
+		}
+	}
+
+	return host, name, tag
+}
+
+// clusterInfoKeys are the swarm driver status entries describing the
+// cluster rather than a node
+var clusterInfoKeys = map[string]bool{
+	"Role":     true,
+	"Primary":  true,
+	"Strategy": true,
+	"Filters":  true,
+	"Nodes":    true,
+}
+
+// classicSwarm reports whether the driver status is of a classic swarm
+// manager
+func classicSwarm(driverStatus [][]string) bool {
+	for _, l := range driverStatus {
+		if len(l) == 2 && strings.TrimSpace(strings.Replace(l[0], "\u0008", "", -1)) == "Strategy" {
+			return true
+		}
+	}
+
+	return false
+}
+
+var byteUnits = map[string]float64{
+	"b":   1,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+}
+
+// parseByteSize parses sizes like "8.083GiB" or "0 B".  A value without
+// a unit uses the default unit.
+func parseByteSize(s, defaultUnit string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+
+	num, unit := s, defaultUnit
+	if i != -1 {
+		num, unit = strings.TrimSpace(s[:i]), strings.TrimSpace(s[i:])
+	}
+
+	multiplier, ok := byteUnits[strings.ToLower(unit)]
+	if !ok {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+
+	return int64(n * multiplier), nil
+}
+
+// splitReserved splits a "reserved / total" value
+func splitReserved(s string) (string, string, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid reservation: %s", s)
+	}
+
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), nil
+}
+
+// parseReservedCPUs parses values like "1 / 4"
+func parseReservedCPUs(s string) (float64, float64, error) {
+	reserved, total, err := splitReserved(s)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	r, err := strconv.ParseFloat(reserved, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid reserved cpus: %s", s)
+	}
+
+	t, err := strconv.ParseFloat(total, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid reserved cpus: %s", s)
+	}
+
+	return r, t, nil
+}
+
+// parseReservedMemory parses values like "2 / 8.083GiB" or
+// "512 MiB / 1.021 GiB".  A reservation without a unit uses the unit of the
+// total.
+func parseReservedMemory(s string) (int64, int64, error) {
+	reserved, total, err := splitReserved(s)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	t, err := parseByteSize(total, "B")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	unit := strings.TrimLeft(total, "0123456789. ")
+	r, err := parseByteSize(reserved, unit)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return r, t, nil
+}
+
+// setNodeField sets the node field of a swarm driver status entry.
+// Unknown fields are ignored so that newer swarm versions can be parsed.
+func setNodeField(node *shipyard.Node, key, value string) error {
+	switch key {
+	case "ID":
+		node.ID = value
+	case "Status":
+		node.Status = value
+	case "Containers":
+		// newer versions append the container states (i.e. "3 (2 Running, ...)")
+		node.Containers = strings.SplitN(value, " ", 2)[0]
+	case "Reserved CPUs":
+		node.ReservedCPUs = value
+		reserved, total, err := parseReservedCPUs(value)
+		if err != nil {
+			return err
+		}
+		node.CPUsReserved, node.CPUsTotal = reserved, total
+	case "Reserved Memory":
+		node.ReservedMemory = value
+		reserved, total, err := parseReservedMemory(value)
+		if err != nil {
+			return err
+		}
+		node.MemoryReserved, node.MemoryTotal = reserved, total
+	case "Labels":
+		node.Labels = []string{}
+		for _, l := range strings.Split(value, ",") {
+			if l = strings.TrimSpace(l); l != "" {
+				node.Labels = append(node.Labels, l)
+			}
+		}
+	case "Error":
+		if value != "(none)" {
+			node.Error = value
+		}
+	case "UpdatedAt":
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("invalid update time: %s", value)
+		}
+		node.UpdatedAt = t
+	case "ServerVersion":
+		node.ServerVersion = value
+	}
+
+	return nil
+}
+
+// parseClusterNodes parses the nodes from the swarm driver status.  Every
+// entry which is neither a node field ("└ Field") nor cluster info starts a
+// node with its name and address.  Malformed fields are logged and skipped.
+func parseClusterNodes(driverStatus [][]string) []*shipyard.Node {
 	nodes := []*shipyard.Node{}
 	var node *shipyard.Node
-	nodeComplete := false
-	name := ""
-	addr := ""
-	containers := ""
-	reservedCPUs := ""
-	reservedMemory := ""
-	labels := []string{}
+
 	for _, l := range driverStatus {
 		if len(l) != 2 {
 			continue
 		}
-		label := l[0]
-		data := l[1]
 
-		// cluster info label i.e. "Filters" or "Strategy"
-		if strings.Index(label, "\u0008") > -1 {
-			continue
-		}
+		// swarm 1.0 prefixes cluster info with a backspace (i.e. "\bStrategy")
+		label := strings.TrimSpace(strings.Replace(l[0], "\u0008", "", -1))
+		data := strings.TrimSpace(l[1])
 
-		if strings.Index(label, " └") == -1 {
-			name = label
-			addr = data
+		if i := strings.Index(label, "└"); i != -1 {
+			if node == nil {
+				continue
+			}
+
+			key := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(label[i+len("└"):]), ":"))
+			if err := setNodeField(node, key, data); err != nil {
+				log.Warnf("error parsing node field: node=%s field=%s err=%s", node.Name, key, err)
+			}
+			continue
 		}
 
-		// node info like "Containers"
-		switch label {
-		case " └ Containers":
-			containers = data
-		case " └ Reserved CPUs":
-			reservedCPUs = data
-		case " └ Reserved Memory":
-			reservedMemory = data
-		case " └ Labels":
-			lbls := strings.Split(data, ",")
-			labels = lbls
-			nodeComplete = true
-		default:
+		// unknown cluster info does not have a node address
+		if _, _, err := net.SplitHostPort(data); err != nil || label == "" || clusterInfoKeys[label] {
+			node = nil
 			continue
 		}
 
-		if nodeComplete {
-			node = &shipyard.Node{
-				Name:           name,
-				Addr:           addr,
-				Containers:     containers,
-				ReservedCPUs:   reservedCPUs,
-				ReservedMemory: reservedMemory,
-				Labels:         labels,
-			}
-			nodes = append(nodes, node)
-
-			// reset info
-			name = ""
-			addr = ""
-			containers = ""
-			reservedCPUs = ""
-			reservedMemory = ""
-			labels = []string{}
-			nodeComplete = false
+		node = &shipyard.Node{
+			Name:   label,
+			Addr:   data,
+			Labels: []string{},
 		}
+		nodes = append(nodes, node)
 	}
 
-	return nodes, nil
+	return nodes
 }
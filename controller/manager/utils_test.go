@@ -2,6 +2,7 @@ package manager
 
 import (
 	"testing"
+	"time"
 )
 
 func TestParseDriverStatus(t *testing.T) {
@@ -21,10 +22,7 @@ func TestParseDriverStatus(t *testing.T) {
 		[]string{" └ Labels", "executiondriver=native-0.2, kernelversion=3.16.0-4-amd64, operatingsystem=Debian GNU/Linux 8 (jessie), storagedriver=aufs"},
 	}
 
-	nodes, err := parseClusterNodes(driverStatus)
-	if err != nil {
-		t.Fatal(err)
-	}
+	nodes := parseClusterNodes(driverStatus)
 
 	if len(nodes) != 2 {
 		t.Fatalf("expected 2 nodes; received %d", len(nodes))
@@ -66,3 +64,179 @@ func TestParseDriverStatus(t *testing.T) {
 	}
 
 }
+
+// driver status fixtures of swarm 1.1 and 1.2
+var (
+	swarm11DriverStatus = [][]string{
+		{"\bRole", "primary"},
+		{"\bStrategy", "spread"},
+		{"\bFilters", "health, port, dependency, affinity, constraint"},
+		{"\bNodes", "2"},
+		{"node-1", "10.0.0.1:2376"},
+		{" └ Status", "Healthy"},
+		{" └ Containers", "4"},
+		{" └ Reserved CPUs", "1 / 2"},
+		{" └ Reserved Memory", "512 MiB / 2.052 GiB"},
+		{" └ Labels", "executiondriver=native-0.2, kernelversion=4.1.17-boot2docker, operatingsystem=Boot2Docker 1.10.0, provider=virtualbox, storagedriver=aufs"},
+		{" └ Error", "(none)"},
+		{" └ UpdatedAt", "2016-02-10T09:12:44Z"},
+		{"node-2", "10.0.0.2:2376"},
+		{" └ Status", "Pending"},
+		{" └ Containers", "0"},
+		{" └ Reserved CPUs", "0 / 0"},
+		{" └ Reserved Memory", "0 B / 0 B"},
+		{" └ Labels", ""},
+		{" └ Error", "Cannot connect to the Docker daemon. Is the docker daemon running on this host?"},
+		{" └ UpdatedAt", "2016-02-10T09:12:40Z"},
+	}
+
+	swarm12DriverStatus = [][]string{
+		{"Role", "replica"},
+		{"Primary", "10.0.0.1:3376"},
+		{"Strategy", "spread"},
+		{"Filters", "health, port, containerslots, dependency, affinity, constraint"},
+		{"Nodes", "1"},
+		{"  node-1", "10.0.0.1:2376"},
+		{"  └ ID", "5GJC:XZBA:MHNR:4FBA:BDGS:OQAK:UXIQ:MMEX:WFHZ:FSXJ:4SUY:YKJQ"},
+		{"  └ Status", "Healthy"},
+		{"  └ Containers", "3 (2 Running, 0 Paused, 1 Stopped)"},
+		{"  └ Reserved CPUs", "0.5 / 1"},
+		{"  └ Reserved Memory", "1 GiB / 3.859 GiB"},
+		{"  └ Labels", "kernelversion=4.4.12-boot2docker, operatingsystem=Boot2Docker 1.11.2, provider=virtualbox, storagedriver=aufs"},
+		{"  └ UpdatedAt", "2016-06-08T18:00:32Z"},
+		{"  └ ServerVersion", "1.11.2"},
+		{"  └ NewField", "ignored"},
+	}
+)
+
+func TestParseDriverStatusSwarm11(t *testing.T) {
+	nodes := parseClusterNodes(swarm11DriverStatus)
+
+	if len(nodes) != 2 {
+		t.Fatalf("expected 2 nodes; received %d", len(nodes))
+	}
+
+	n1, n2 := nodes[0], nodes[1]
+
+	if n1.Status != "Healthy" || n1.Error != "" {
+		t.Fatalf("expected healthy node without error; received status=%q error=%q", n1.Status, n1.Error)
+	}
+
+	if n1.CPUsReserved != 1 || n1.CPUsTotal != 2 {
+		t.Fatalf("expected 1 / 2 cpus; received %v / %v", n1.CPUsReserved, n1.CPUsTotal)
+	}
+
+	if n1.MemoryReserved != 512<<20 || n1.MemoryTotal != 2203318222 {
+		t.Fatalf("unexpected memory: %d / %d", n1.MemoryReserved, n1.MemoryTotal)
+	}
+
+	if len(n1.Labels) != 5 || n1.Labels[1] != "kernelversion=4.1.17-boot2docker" {
+		t.Fatalf("unexpected labels: %v", n1.Labels)
+	}
+
+	if expected := time.Date(2016, 2, 10, 9, 12, 44, 0, time.UTC); !n1.UpdatedAt.Equal(expected) {
+		t.Fatalf("expected update time %s; received %s", expected, n1.UpdatedAt)
+	}
+
+	if n2.Status != "Pending" || n2.Error == "" {
+		t.Fatalf("expected pending node with error; received status=%q error=%q", n2.Status, n2.Error)
+	}
+
+	if n2.MemoryTotal != 0 || len(n2.Labels) != 0 {
+		t.Fatalf("expected node without resources; received memory=%d labels=%v", n2.MemoryTotal, n2.Labels)
+	}
+}
+
+func TestParseDriverStatusSwarm12(t *testing.T) {
+	nodes := parseClusterNodes(swarm12DriverStatus)
+
+	if len(nodes) != 1 {
+		t.Fatalf("expected the primary not to be parsed as a node; received %d nodes", len(nodes))
+	}
+
+	n := nodes[0]
+
+	if n.Name != "node-1" || n.Addr != "10.0.0.1:2376" {
+		t.Fatalf("unexpected node: name=%q addr=%q", n.Name, n.Addr)
+	}
+
+	if n.ID != "5GJC:XZBA:MHNR:4FBA:BDGS:OQAK:UXIQ:MMEX:WFHZ:FSXJ:4SUY:YKJQ" {
+		t.Fatalf("unexpected id: %q", n.ID)
+	}
+
+	if n.ServerVersion != "1.11.2" {
+		t.Fatalf("expected server version 1.11.2; received %q", n.ServerVersion)
+	}
+
+	if n.Containers != "3" {
+		t.Fatalf("expected 3 containers; received %q", n.Containers)
+	}
+
+	if n.CPUsReserved != 0.5 || n.CPUsTotal != 1 {
+		t.Fatalf("expected 0.5 / 1 cpus; received %v / %v", n.CPUsReserved, n.CPUsTotal)
+	}
+
+	if n.MemoryReserved != 1<<30 {
+		t.Fatalf("expected 1 GiB reserved; received %d", n.MemoryReserved)
+	}
+}
+
+func TestParseDriverStatusMalformed(t *testing.T) {
+	nodes := parseClusterNodes([][]string{
+		{" └ Containers", "1"},
+		{"node-1", "10.0.0.1:2375"},
+		{" └ Reserved CPUs", "many"},
+		{" └ Reserved Memory", "2 / 8.083GiB"},
+		{"Scheduler", "spread"},
+		{" └ Containers", "7"},
+		{"short"},
+	})
+
+	if len(nodes) != 1 {
+		t.Fatalf("expected 1 node; received %d", len(nodes))
+	}
+
+	n := nodes[0]
+	if n.CPUsTotal != 0 || n.ReservedCPUs != "many" {
+		t.Fatalf("expected invalid cpus to be kept as text only; received %q %v", n.ReservedCPUs, n.CPUsTotal)
+	}
+
+	if n.MemoryReserved != 2*(1<<30) || n.MemoryTotal != 8679055163 {
+		t.Fatalf("unexpected memory: %d / %d", n.MemoryReserved, n.MemoryTotal)
+	}
+
+	if n.Containers != "" {
+		t.Fatalf("expected fields of unknown cluster info to be ignored; received containers %q", n.Containers)
+	}
+}
+
+func TestParseImageName(t *testing.T) {
+	tests := []struct {
+		image string
+		host  string
+		repo  string
+		tag   string
+	}{
+		{"nginx", "", "nginx", "latest"},
+		{"ehazlett/interlock:1.0", "", "ehazlett/interlock", "1.0"},
+		{"localhost:5000/app", "localhost:5000", "app", "latest"},
+		{"registry.example.com/team/app:v2", "registry.example.com", "team/app", "v2"},
+		{"localhost/app:1", "localhost", "app", "1"},
+		{"registry.example.com:5000/app@sha256:abcd", "registry.example.com:5000", "app", ""},
+	}
+
+	for _, test := range tests {
+		host, repo, tag := parseImageName(test.image)
+		if host != test.host {
+			t.Fatalf("expected host %q for %s; received %q", test.host, test.image, host)
+		}
+
+		if repo != test.repo {
+			t.Fatalf("expected repo %q for %s; received %q", test.repo, test.image, repo)
+		}
+
+		if tag != test.tag {
+			t.Fatalf("expected tag %q for %s; received %q", test.tag, test.image, tag)
+		}
+	}
+}
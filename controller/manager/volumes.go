@@ -0,0 +1,242 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+var (
+	ErrVolumeDoesNotExist = errors.New("volume does not exist")
+	ErrVolumeInUse        = errors.New("volume is used by containers")
+)
+
+// mountedContainer is a container with its mounts as listed by newer
+// engines
+type mountedContainer struct {
+	Id     string
+	Names  []string
+	Mounts []struct {
+		Name string
+	}
+}
+
+// splitVolumeName returns the node and name of a volume.  Swarm prefixes
+// the names of volumes with their node (i.e. "node1/data").
+func splitVolumeName(name string) (string, string) {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) < 2 {
+		return "", name
+	}
+
+	return parts[0], parts[1]
+}
+
+// volumeUsage returns the names of the containers using each volume by
+// volume name.  Names are prefixed with the node of the container if it
+// has one.
+func volumeUsage(containers []mountedContainer) map[string][]string {
+	usage := map[string][]string{}
+	for _, c := range containers {
+		container := dockerclient.Container{Id: c.Id, Names: c.Names}
+		name := containerName(container)
+		node := containerNode(container)
+
+		for _, mnt := range c.Mounts {
+			// bind mounts do not have a name
+			if mnt.Name == "" {
+				continue
+			}
+
+			key := mnt.Name
+			if node != "" {
+				key = node + "/" + mnt.Name
+			}
+			usage[key] = append(usage[key], name)
+		}
+	}
+
+	for _, names := range usage {
+		sort.Strings(names)
+	}
+
+	return usage
+}
+
+// buildVolumes returns the volumes with their usage and size sorted by name
+func buildVolumes(volumes []*dockerclient.Volume, usage map[string][]string, sizes map[string]int64) []*shipyard.Volume {
+	result := []*shipyard.Volume{}
+	for _, v := range volumes {
+		node, _ := splitVolumeName(v.Name)
+
+		containers := usage[v.Name]
+		if containers == nil {
+			containers = []string{}
+		}
+
+		size, ok := sizes[v.Name]
+		if !ok {
+			size = -1
+		}
+
+		result = append(result, &shipyard.Volume{
+			Name:       v.Name,
+			Node:       node,
+			Driver:     v.Driver,
+			Mountpoint: v.Mountpoint,
+			Labels:     v.Labels,
+			Containers: containers,
+			Dangling:   len(containers) == 0,
+			Size:       size,
+		})
+	}
+
+	sort.Sort(volumesByName(result))
+
+	return result
+}
+
+type volumesByName []*shipyard.Volume
+
+func (v volumesByName) Len() int           { return len(v) }
+func (v volumesByName) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
+func (v volumesByName) Less(i, j int) bool { return v[i].Name < v[j].Name }
+
+// volumeSizes returns the disk usage of the volumes reported by the
+// engine.  Volumes whose driver does not report a size are not included.
+func (m DefaultManager) volumeSizes() map[string]int64 {
+	var df struct {
+		Volumes []struct {
+			Name      string
+			UsageData *struct {
+				Size int64
+			}
+		}
+	}
+
+	sizes := map[string]int64{}
+	if err := m.swarmRequest("GET", "/system/df", nil, &df); err != nil {
+		log.Debugf("volume sizes not available: %s", err)
+		return sizes
+	}
+
+	for _, v := range df.Volumes {
+		if v.UsageData != nil && v.UsageData.Size >= 0 {
+			sizes[v.Name] = v.UsageData.Size
+		}
+	}
+
+	return sizes
+}
+
+// Volumes returns the volumes of the cluster sorted by name.  The disk
+// usage of the volumes is expensive for the engine to compute so it is
+// only included if requested.
+func (m DefaultManager) Volumes(usage bool) ([]*shipyard.Volume, error) {
+	volumes, err := m.client.ListVolumes()
+	if err != nil {
+		return nil, err
+	}
+
+	containers := []mountedContainer{}
+	if err := m.swarmRequest("GET", "/containers/json?all=1", nil, &containers); err != nil {
+		return nil, err
+	}
+
+	var sizes map[string]int64
+	if usage {
+		sizes = m.volumeSizes()
+	}
+
+	return buildVolumes(volumes, volumeUsage(containers), sizes), nil
+}
+
+func (m DefaultManager) Volume(name string, usage bool) (*shipyard.Volume, error) {
+	volumes, err := m.Volumes(usage)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range volumes {
+		if v.Name == name {
+			return v, nil
+		}
+	}
+
+	return nil, ErrVolumeDoesNotExist
+}
+
+func (m DefaultManager) CreateVolume(request *dockerclient.VolumeCreateRequest, username string) (*shipyard.Volume, error) {
+	v, err := m.client.CreateVolume(request)
+	if err != nil {
+		return nil, err
+	}
+
+	m.logUserEvent("create-volume", fmt.Sprintf("name=%s driver=%s", v.Name, v.Driver), username, []string{"volumes"})
+
+	return buildVolumes([]*dockerclient.Volume{v}, nil, nil)[0], nil
+}
+
+// RemoveVolume removes the volume unless it is used by a container
+func (m DefaultManager) RemoveVolume(name, username string) error {
+	v, err := m.Volume(name, false)
+	if err != nil {
+		return err
+	}
+
+	if !v.Dangling {
+		return ErrVolumeInUse
+	}
+
+	if err := m.client.RemoveVolume(name); err != nil {
+		return err
+	}
+
+	m.logUserEvent("remove-volume", fmt.Sprintf("name=%s", name), username, []string{"volumes"})
+
+	return nil
+}
+
+// CleanupVolumes removes the dangling volumes if confirmed.  Otherwise the
+// volumes which would be removed are returned.
+func (m DefaultManager) CleanupVolumes(confirm bool, username string) (*shipyard.VolumeCleanup, error) {
+	volumes, err := m.Volumes(true)
+	if err != nil {
+		return nil, err
+	}
+
+	cleanup := &shipyard.VolumeCleanup{
+		Confirmed: confirm,
+		Volumes:   []string{},
+		Errors:    []string{},
+	}
+
+	for _, v := range volumes {
+		if !v.Dangling {
+			continue
+		}
+
+		if confirm {
+			if err := m.client.RemoveVolume(v.Name); err != nil {
+				cleanup.Errors = append(cleanup.Errors, fmt.Sprintf("%s: %s", v.Name, strings.TrimSpace(err.Error())))
+				continue
+			}
+		}
+
+		cleanup.Volumes = append(cleanup.Volumes, v.Name)
+		if v.Size > 0 {
+			cleanup.Size += v.Size
+		}
+	}
+
+	if confirm {
+		m.logUserEvent("cleanup-volumes", fmt.Sprintf("removed=%d size=%d errors=%d", len(cleanup.Volumes), cleanup.Size, len(cleanup.Errors)), username, []string{"volumes"})
+	}
+
+	return cleanup, nil
+}
@@ -0,0 +1,112 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/samalba/dockerclient"
+)
+
+func TestSplitVolumeName(t *testing.T) {
+	if node, name := splitVolumeName("node1/data"); node != "node1" || name != "data" {
+		t.Fatalf("expected node and name; received %q %q", node, name)
+	}
+
+	if node, name := splitVolumeName("data"); node != "" || name != "data" {
+		t.Fatalf("expected name without node; received %q %q", node, name)
+	}
+}
+
+func TestVolumeUsage(t *testing.T) {
+	containers := []mountedContainer{}
+	if err := json.Unmarshal([]byte(`[
+		{"Id": "1", "Names": ["/node1/web"], "Mounts": [{"Name": "data"}, {"Source": "/etc/hosts"}]},
+		{"Id": "2", "Names": ["/node1/backup"], "Mounts": [{"Name": "data"}]},
+		{"Id": "3", "Names": ["/node2/web"], "Mounts": [{"Name": "data"}]},
+		{"Id": "4", "Names": ["/db"], "Mounts": [{"Name": "db"}]}
+	]`), &containers); err != nil {
+		t.Fatal(err)
+	}
+
+	usage := volumeUsage(containers)
+
+	expected := "map[db:[db] node1/data:[backup web] node2/data:[web]]"
+	if fmt.Sprint(usage) != expected {
+		t.Fatalf("expected usage %s; received %v", expected, usage)
+	}
+}
+
+func TestBuildVolumes(t *testing.T) {
+	volumes := buildVolumes([]*dockerclient.Volume{
+		{Name: "node1/logs", Driver: "local"},
+		{Name: "node1/data", Driver: "local"},
+	}, map[string][]string{"node1/data": {"web"}}, map[string]int64{"node1/logs": 2048})
+
+	if len(volumes) != 2 || volumes[0].Name != "node1/data" {
+		t.Fatalf("expected volumes sorted by name; received %v", volumes)
+	}
+
+	if volumes[0].Dangling || volumes[0].Size != -1 || volumes[0].Node != "node1" {
+		t.Fatalf("expected used volume with unknown size; received %+v", volumes[0])
+	}
+
+	if !volumes[1].Dangling || volumes[1].Size != 2048 || len(volumes[1].Containers) != 0 {
+		t.Fatalf("expected dangling volume with size; received %+v", volumes[1])
+	}
+}
+
+func TestCleanupVolumesUnconfirmed(t *testing.T) {
+	removed := []string{}
+	df := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "DELETE":
+			removed = append(removed, r.URL.Path)
+		case r.URL.Path == "/v1.15/volumes":
+			fmt.Fprint(w, `{"Volumes": [{"Name": "data", "Driver": "local"}, {"Name": "cache", "Driver": "local"}, {"Name": "logs", "Driver": "local"}]}`)
+		case r.URL.Path == "/v1.25/containers/json":
+			fmt.Fprint(w, `[{"Id": "1", "Names": ["/web"], "Mounts": [{"Name": "data"}]}]`)
+		case r.URL.Path == "/v1.25/system/df":
+			df++
+			fmt.Fprint(w, `{"Volumes": [{"Name": "cache", "UsageData": {"Size": 4096, "RefCount": 0}}, {"Name": "logs", "UsageData": {"Size": -1, "RefCount": 0}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := dockerclient.NewDockerClient(ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := DefaultManager{client: client}
+
+	cleanup, err := m.CleanupVolumes(false, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cleanup.Confirmed || fmt.Sprint(cleanup.Volumes) != "[cache logs]" || cleanup.Size != 4096 {
+		t.Fatalf("unexpected cleanup: %+v", cleanup)
+	}
+
+	if len(removed) != 0 {
+		t.Fatalf("expected no volumes to be removed without confirmation; removed %v", removed)
+	}
+
+	if err := m.RemoveVolume("data", "admin"); err != ErrVolumeInUse {
+		t.Fatalf("expected ErrVolumeInUse; received %v", err)
+	}
+
+	if _, err := m.Volume("missing", false); err != ErrVolumeDoesNotExist {
+		t.Fatalf("expected ErrVolumeDoesNotExist; received %v", err)
+	}
+
+	// only the cleanup needs the disk usage
+	if df != 1 {
+		t.Fatalf("expected disk usage to be requested once; received %d", df)
+	}
+}
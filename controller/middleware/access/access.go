@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/shipyard/shipyard"
 	"github.com/shipyard/shipyard/auth"
 	"github.com/shipyard/shipyard/controller/manager"
 )
@@ -59,7 +60,7 @@ func (a *AccessRequired) handleRequest(w http.ResponseWriter, r *http.Request) e
 				return err
 			}
 			// check role
-			valid = a.checkAccess(acct, r.URL.Path, r.Method)
+			valid = a.checkRequest(acct, r.URL.Path, r.Method)
 		}
 	} else { // only check access for users; not service keys
 		valid = true
@@ -118,6 +119,23 @@ func (a *AccessRequired) checkAccess(acct *auth.Account, path string, method str
 	return false
 }
 
+// checkRequest checks the access to the path of a request.  Docker api
+// paths are checked without their api version and also by their resource
+// (i.e. /exec/{id}/start as /containers).
+func (a *AccessRequired) checkRequest(acct *auth.Account, path string, method string) bool {
+	_, path = shipyard.SplitAPIVersion(path)
+	if a.checkAccess(acct, path, method) {
+		return true
+	}
+
+	resource := shipyard.ProxyResource(path)
+	if resource == "" || strings.HasPrefix(path, "/"+resource) {
+		return false
+	}
+
+	return a.checkAccess(acct, "/"+resource, method)
+}
+
 func (a *AccessRequired) HandlerFuncWithNext(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	err := a.handleRequest(w, r)
 	session, _ := a.manager.Store().Get(r, a.manager.StoreKey())
@@ -349,3 +349,226 @@ func TestAccessControlNodesRWRole(t *testing.T) {
 		t.Fatalf("expected denied access for %s %s", testMethod, testPath)
 	}
 }
+
+func TestAccessControlServicesRORole(t *testing.T) {
+	testAcct := &auth.Account{
+		Username: "testuser",
+		Roles:    []string{"services:ro"},
+	}
+
+	testPath := "/api/services/web/tasks"
+	testMethod := "GET"
+
+	if !accessRequired.checkAccess(testAcct, testPath, testMethod) {
+		t.Fatalf("expected valid access for %s %s", testMethod, testPath)
+	}
+
+	testPath = "/services/create"
+	testMethod = "POST"
+
+	if accessRequired.checkAccess(testAcct, testPath, testMethod) {
+		t.Fatalf("expected denied access for %s %s", testMethod, testPath)
+	}
+
+	testPath = "/api/secrets"
+	testMethod = "GET"
+
+	if accessRequired.checkAccess(testAcct, testPath, testMethod) {
+		t.Fatalf("expected denied access for %s %s", testMethod, testPath)
+	}
+}
+
+func TestAccessControlServicesRWRole(t *testing.T) {
+	testAcct := &auth.Account{
+		Username: "testuser",
+		Roles:    []string{"services:rw"},
+	}
+
+	testPath := "/services/create"
+	testMethod := "POST"
+
+	if !accessRequired.checkAccess(testAcct, testPath, testMethod) {
+		t.Fatalf("expected valid access for %s %s", testMethod, testPath)
+	}
+
+	testPath = "/api/services/web"
+	testMethod = "DELETE"
+
+	if !accessRequired.checkAccess(testAcct, testPath, testMethod) {
+		t.Fatalf("expected valid access for %s %s", testMethod, testPath)
+	}
+
+	testPath = "/secrets/create"
+	testMethod = "POST"
+
+	if accessRequired.checkAccess(testAcct, testPath, testMethod) {
+		t.Fatalf("expected denied access for %s %s", testMethod, testPath)
+	}
+}
+
+func TestAccessControlSecretsRWRole(t *testing.T) {
+	testAcct := &auth.Account{
+		Username: "testuser",
+		Roles:    []string{"secrets:rw"},
+	}
+
+	testPath := "/api/secrets"
+	testMethod := "POST"
+
+	if !accessRequired.checkAccess(testAcct, testPath, testMethod) {
+		t.Fatalf("expected valid access for %s %s", testMethod, testPath)
+	}
+
+	testPath = "/tasks"
+	testMethod = "GET"
+
+	if accessRequired.checkAccess(testAcct, testPath, testMethod) {
+		t.Fatalf("expected denied access for %s %s", testMethod, testPath)
+	}
+}
+
+func TestAccessControlTasksRORole(t *testing.T) {
+	testAcct := &auth.Account{
+		Username: "testuser",
+		Roles:    []string{"tasks:ro"},
+	}
+
+	testPath := "/tasks"
+	testMethod := "GET"
+
+	if !accessRequired.checkAccess(testAcct, testPath, testMethod) {
+		t.Fatalf("expected valid access for %s %s", testMethod, testPath)
+	}
+
+	testPath = "/api/services"
+	testMethod = "GET"
+
+	if accessRequired.checkAccess(testAcct, testPath, testMethod) {
+		t.Fatalf("expected denied access for %s %s", testMethod, testPath)
+	}
+}
+
+func TestAccessControlNetworksRORole(t *testing.T) {
+	testAcct := &auth.Account{
+		Username: "testuser",
+		Roles:    []string{"networks:ro"},
+	}
+
+	testPath := "/networks"
+	testMethod := "GET"
+
+	if !accessRequired.checkAccess(testAcct, testPath, testMethod) {
+		t.Fatalf("expected valid access for %s %s", testMethod, testPath)
+	}
+
+	testPath = "/networks/create"
+	testMethod = "POST"
+
+	if accessRequired.checkAccess(testAcct, testPath, testMethod) {
+		t.Fatalf("expected denied access for %s %s", testMethod, testPath)
+	}
+}
+
+func TestAccessControlVersionedPaths(t *testing.T) {
+	testAcct := &auth.Account{
+		Username: "testuser",
+		Roles:    []string{"containers:rw"},
+	}
+
+	testPath := "/v1.24/containers/json"
+	testMethod := "GET"
+
+	if !accessRequired.checkRequest(testAcct, testPath, testMethod) {
+		t.Fatalf("expected valid access for %s %s", testMethod, testPath)
+	}
+
+	testPath = "/v1.26/exec/abc/start"
+	testMethod = "POST"
+
+	if !accessRequired.checkRequest(testAcct, testPath, testMethod) {
+		t.Fatalf("expected valid access for %s %s", testMethod, testPath)
+	}
+
+	testPath = "/v1.26/images/create"
+	testMethod = "POST"
+
+	if accessRequired.checkRequest(testAcct, testPath, testMethod) {
+		t.Fatalf("expected denied access for %s %s", testMethod, testPath)
+	}
+
+	testPath = "/v1.30/unknown/endpoint"
+	testMethod = "GET"
+
+	if accessRequired.checkRequest(testAcct, testPath, testMethod) {
+		t.Fatalf("expected denied access for %s %s", testMethod, testPath)
+	}
+}
+
+func TestAccessControlVolumesRORole(t *testing.T) {
+	testAcct := &auth.Account{
+		Username: "testuser",
+		Roles:    []string{"volumes:ro"},
+	}
+
+	testPath := "/api/volumes"
+	testMethod := "GET"
+
+	if !accessRequired.checkAccess(testAcct, testPath, testMethod) {
+		t.Fatalf("expected valid access for %s %s", testMethod, testPath)
+	}
+
+	testPath = "/api/volumes/cleanup"
+	testMethod = "POST"
+
+	if accessRequired.checkAccess(testAcct, testPath, testMethod) {
+		t.Fatalf("expected denied access for %s %s", testMethod, testPath)
+	}
+}
+
+func TestAccessControlVolumesRWRole(t *testing.T) {
+	testAcct := &auth.Account{
+		Username: "testuser",
+		Roles:    []string{"volumes:rw"},
+	}
+
+	testPath := "/v1.24/volumes/data"
+	testMethod := "DELETE"
+
+	if !accessRequired.checkRequest(testAcct, testPath, testMethod) {
+		t.Fatalf("expected valid access for %s %s", testMethod, testPath)
+	}
+
+	testPath = "/api/volumes/cleanup"
+	testMethod = "POST"
+
+	if !accessRequired.checkAccess(testAcct, testPath, testMethod) {
+		t.Fatalf("expected valid access for %s %s", testMethod, testPath)
+	}
+
+	testPath = "/containers"
+	testMethod = "GET"
+
+	if accessRequired.checkAccess(testAcct, testPath, testMethod) {
+		t.Fatalf("expected denied access for %s %s", testMethod, testPath)
+	}
+}
+
+func TestAccessControlClusterInfo(t *testing.T) {
+	testAcct := &auth.Account{
+		Username: "testuser",
+		Roles:    []string{"nodes:ro"},
+	}
+
+	testPath := "/api/cluster/info"
+	testMethod := "GET"
+
+	if !accessRequired.checkAccess(testAcct, testPath, testMethod) {
+		t.Fatalf("expected valid access for %s %s", testMethod, testPath)
+	}
+
+	testAcct.Roles = []string{"containers:ro"}
+
+	if accessRequired.checkAccess(testAcct, testPath, testMethod) {
+		t.Fatalf("expected denied access for %s %s", testMethod, testPath)
+	}
+}
@@ -64,6 +64,9 @@ func (a *Auditor) HandlerFuncWithNext(w http.ResponseWriter, r *http.Request, ne
 		log.Errorf("audit path filter error: %s", err)
 	}
 
+	// docker api requests are audited without the api version
+	_, path = shipyard.SplitAPIVersion(path)
+
 	// check if excluded
 	for _, e := range a.excludes {
 		match, err := regexp.MatchString(e, path)
@@ -80,6 +83,9 @@ func (a *Auditor) HandlerFuncWithNext(w http.ResponseWriter, r *http.Request, ne
 	if user != "" && path != "" && !skipAudit {
 		tagParts := strings.Split(path, "/")
 		tag := tagParts[1]
+		if resource := shipyard.ProxyResource(path); resource != "" {
+			tag = resource
+		}
 
 		evt := &shipyard.Event{
 			Type:     "api",
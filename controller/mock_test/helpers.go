@@ -19,6 +19,20 @@ var (
 		Name: "test-registry",
 		Addr: "http://localhost:5000",
 	}
+	TestRegistryAuthConfig = &dockerclient.AuthConfig{
+		Username: "testuser",
+		Password: "testpass",
+	}
+	TestContainerHealth = &shipyard.ContainerHealth{
+		ID:     TestContainerId,
+		Name:   TestContainerName,
+		Status: shipyard.HealthHealthy,
+		Probe: &shipyard.HealthProbe{
+			Type: shipyard.HealthProbeHTTP,
+			Port: "8080",
+			Path: "/health",
+		},
+	}
 	TestRepository    = &registry.Repository{}
 	TestContainerInfo = &dockerclient.ContainerInfo{
 		Id:      TestContainerId,
@@ -31,6 +45,20 @@ var (
 		Name: "testnode",
 		Addr: "tcp://127.0.0.1:3375",
 	}
+	TestClusterInfo = &shipyard.ClusterInfo{
+		Cpus:           4,
+		Memory:         8589934592,
+		ContainerCount: 1,
+		EngineCount:    1,
+		ImageCount:     1,
+		ReservedCpus:   1,
+		ReservedMemory: 1073741824,
+		Version:        "swarm/1.2.5",
+		Nodes: []*shipyard.ClusterNodeInfo{
+			{Name: "testnode", Addr: "tcp://127.0.0.1:3375", Health: "up", Cpus: 4, Memory: 8589934592, ContainerCount: 1, ReservedCpus: 1, ReservedMemory: 1073741824},
+		},
+		History: []*shipyard.ClusterInfoSample{},
+	}
 	TestAccount = &auth.Account{
 		ID:       "0",
 		Username: "testuser",
@@ -51,11 +79,100 @@ var (
 		Image: "ehazlett/test",
 		Key:   "abcdefg",
 	}
+	TestRetentionPolicy = &shipyard.RetentionPolicy{
+		ID:       "0",
+		Name:     "test-policy",
+		Registry: "test-registry",
+		KeepLast: 5,
+		MaxAge:   "30d",
+	}
+	TestStorageReport = &shipyard.StorageReport{
+		Registry: "test-registry",
+		Size:     130,
+		Layers:   3,
+		Repositories: []*shipyard.RepositoryStorage{
+			{
+				Repository:    "library/app",
+				Size:          130,
+				ExclusiveSize: 130,
+				Tags: []*shipyard.TagStorage{
+					{Tag: "1.0", ImageID: "aaa", Size: 110, SharedSize: 100, ExclusiveSize: 10},
+					{Tag: "latest", ImageID: "bbb", Size: 120, SharedSize: 100, ExclusiveSize: 20},
+				},
+			},
+		},
+		Errors: []string{},
+	}
+	TestApplication = &shipyard.Application{
+		ID:         "0",
+		Name:       "test-app",
+		Definition: "web:\n  image: nginx\n",
+		Services:   []string{"web"},
+	}
+	TestRevision = &shipyard.Revision{
+		ID:         "0",
+		Kind:       shipyard.RevisionApplication,
+		Target:     "test-app",
+		Number:     1,
+		Action:     "deploy",
+		Definition: "web:\n  image: nginx\n",
+		Containers: []*shipyard.RevisionContainer{
+			{Name: "test-app_web_1", Service: "web", Image: "nginx"},
+		},
+	}
+	TestTemplate = &shipyard.Template{
+		ID:      "0",
+		Name:    "test-template",
+		Version: 1,
+		Variables: []*shipyard.TemplateVariable{
+			{Name: "Version", Type: shipyard.TemplateVariableString, Required: true},
+		},
+		Config: map[string]interface{}{"Image": "nginx:{{.Version}}"},
+		Owner:  "admin",
+		Teams:  []string{},
+	}
 	TestConsoleSession = &shipyard.ConsoleSession{
 		ID:          "0",
 		ContainerID: "abcdefg",
 		Token:       "1234567890",
 	}
+	TestService = &shipyard.SwarmService{
+		ID:      "service0",
+		Version: shipyard.SwarmVersion{Index: 10},
+		Spec: shipyard.SwarmServiceSpec{
+			Name: "test-service",
+		},
+	}
+	TestTask = &shipyard.SwarmTask{
+		ID:           "task0",
+		ServiceID:    "service0",
+		NodeID:       "node0",
+		Status:       shipyard.SwarmTaskStatus{State: "running"},
+		DesiredState: "running",
+	}
+	TestSecret = &shipyard.SwarmSecret{
+		ID:   "secret0",
+		Spec: shipyard.SwarmSecretSpec{Name: "test-secret"},
+	}
+	TestVolume = &shipyard.Volume{
+		Name:       "node1/data",
+		Node:       "node1",
+		Driver:     "local",
+		Containers: []string{TestContainerName},
+		Size:       -1,
+	}
+	TestNetwork = &dockerclient.NetworkResource{
+		ID:     "network0",
+		Name:   "test-network",
+		Driver: "overlay",
+		IPAM: dockerclient.IPAM{
+			Driver: "default",
+			Config: []dockerclient.IPAMConfig{{Subnet: "10.0.0.0/24", Gateway: "10.0.0.1"}},
+		},
+		Containers: map[string]dockerclient.EndpointResource{
+			TestContainerId: {Name: TestContainerName, IPv4Address: "10.0.0.2/24"},
+		},
+	}
 )
 
 func getTestContainerInfo(id string, name string, image string) *dockerclient.ContainerInfo {
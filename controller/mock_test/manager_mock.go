@@ -1,6 +1,10 @@
 package mock_test
 
 import (
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/gorilla/sessions"
 	"github.com/samalba/dockerclient"
 	"github.com/shipyard/shipyard"
@@ -145,6 +149,47 @@ func (m MockManager) Registry(name string) (*shipyard.Registry, error) {
 	return TestRegistry, nil
 }
 
+func (m MockManager) RegistryAuthConfig(username, image string) (*dockerclient.AuthConfig, error) {
+	if strings.HasPrefix(image, "localhost:5000/") {
+		return TestRegistryAuthConfig, nil
+	}
+
+	return nil, nil
+}
+
+func (m MockManager) DeleteTag(registry *shipyard.Registry, repo, tag string) error {
+	return nil
+}
+
+func (m MockManager) RetentionPolicies() ([]*shipyard.RetentionPolicy, error) {
+	return []*shipyard.RetentionPolicy{
+		TestRetentionPolicy,
+	}, nil
+}
+
+func (m MockManager) RetentionPolicy(id string) (*shipyard.RetentionPolicy, error) {
+	return TestRetentionPolicy, nil
+}
+
+func (m MockManager) SaveRetentionPolicy(policy *shipyard.RetentionPolicy) error {
+	return nil
+}
+
+func (m MockManager) RemoveRetentionPolicy(policy *shipyard.RetentionPolicy) error {
+	return nil
+}
+
+func (m MockManager) ApplyRetentionPolicy(policy *shipyard.RetentionPolicy, dryRun bool) (*shipyard.RetentionReport, error) {
+	return &shipyard.RetentionReport{
+		PolicyID: policy.ID,
+		Registry: policy.Registry,
+		DryRun:   dryRun,
+		Deleted: []*shipyard.RetentionTag{
+			{Repository: "library/app", Tag: "0.1", Reason: "older than 30d"},
+		},
+	}, nil
+}
+
 func (m MockManager) RemoveRegistry(registry *shipyard.Registry) error {
 	return nil
 }
@@ -165,14 +210,420 @@ func (m MockManager) Repository(name string) (*registry.Repository, error) {
 	return TestRepository, nil
 }
 
-func (m MockManager) DeleteRepository(name string) error {
+func (m MockManager) RepositoryUsage(registry *shipyard.Registry, repo string) (*shipyard.RepositoryUsage, error) {
+	usage := &shipyard.RepositoryUsage{
+		Registry:   registry.Name,
+		Repository: repo,
+		Tags:       TestRepository.Tags,
+		Containers: []dockerclient.Container{},
+	}
+
+	if repo == TestContainerImage {
+		usage.Containers = append(usage.Containers, dockerclient.Container{
+			Id:    TestContainerId,
+			Image: TestContainerImage,
+		})
+	}
+
+	return usage, nil
+}
+
+func (m MockManager) DeleteRepository(registry *shipyard.Registry, repo, username string, force bool) (*shipyard.RepositoryDeletion, error) {
+	usage, err := m.RepositoryUsage(registry, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(usage.Containers) > 0 && !force {
+		return nil, manager.ErrRepositoryInUse
+	}
+
+	return &shipyard.RepositoryDeletion{
+		Registry:   registry.Name,
+		Repository: repo,
+		Tags:       usage.Tags,
+		Forced:     force,
+		Username:   username,
+	}, nil
+}
+
+func (m MockManager) PromoteImage(promotion *shipyard.Promotion, username string) (*shipyard.Promotion, error) {
+	if promotion.SourceRegistry != TestRegistry.Name || promotion.TargetRegistry != TestRegistry.Name {
+		return nil, manager.ErrRegistryDoesNotExist
+	}
+
+	p := *promotion
+	p.ImageID = "0123456789abcdef"
+	p.CopiedLayers = []string{p.ImageID}
+	p.SkippedLayers = []string{}
+	p.Username = username
+
+	return &p, nil
+}
+
+func (m MockManager) DeployRepository(registry *shipyard.Registry, repo string, deployment *shipyard.RepositoryDeployment, username string) (*shipyard.RepositoryDeployment, error) {
+	d := *deployment
+	if d.Tag == "" {
+		d.Tag = "latest"
+	}
+
+	if strings.ContainsAny(d.Tag, " /") {
+		return nil, &manager.DeploymentError{Err: errors.New("invalid tag: " + d.Tag)}
+	}
+
+	d.Registry = registry.Name
+	d.Repository = repo
+	d.Image = "localhost:5000/" + repo + ":" + d.Tag
+	d.ContainerID = TestContainerId
+	d.Username = username
+
+	return &d, nil
+}
+
+func (m MockManager) SearchImages(query, username string, timeout time.Duration) (*shipyard.ImageSearch, error) {
+	return &shipyard.ImageSearch{
+		Query: query,
+		Results: []*shipyard.ImageSearchResult{
+			{
+				Registry:   TestRegistry.Name,
+				Image:      "localhost:5000/library/" + query,
+				Repository: "library/" + query,
+				Tags:       []registry.Tag{{ID: "0123456789abcdef", Name: "latest"}},
+				Score:      4,
+			},
+		},
+		Errors: map[string]string{
+			"offline-registry": "timed out after " + timeout.String(),
+		},
+	}, nil
+}
+
+func (m MockManager) StorageReport(registry *shipyard.Registry) (*shipyard.StorageReport, error) {
+	return TestStorageReport, nil
+}
+
+func (m MockManager) RepositoryStorage(registry *shipyard.Registry, repo string) (*shipyard.RepositoryStorage, error) {
+	if !strings.Contains(repo, "/") {
+		repo = "library/" + repo
+	}
+
+	for _, rs := range TestStorageReport.Repositories {
+		if rs.Repository == repo {
+			return rs, nil
+		}
+	}
+
+	return nil, manager.ErrRepositoryDoesNotExist
+}
+
+func (m MockManager) StorageHistory(registry *shipyard.Registry, since time.Time) ([]*shipyard.StorageSnapshot, error) {
+	return []*shipyard.StorageSnapshot{
+		{
+			Registry: registry.Name,
+			Size:     TestStorageReport.Size,
+			Layers:   TestStorageReport.Layers,
+		},
+	}, nil
+}
+
+func (m MockManager) Applications() ([]*shipyard.Application, error) {
+	return []*shipyard.Application{TestApplication}, nil
+}
+
+func (m MockManager) Application(name string) (*shipyard.Application, error) {
+	if name != TestApplication.Name {
+		return nil, manager.ErrApplicationDoesNotExist
+	}
+
+	return TestApplication, nil
+}
+
+func (m MockManager) CreateApplication(app *shipyard.Application, username string) error {
+	if app.Name == TestApplication.Name {
+		return manager.ErrApplicationExists
+	}
+
 	return nil
 }
 
+func (m MockManager) StartApplication(app *shipyard.Application, username string) manager.ApplicationResult {
+	return manager.ApplicationResult{Containers: []string{TestContainerId}, Errors: []string{}}
+}
+
+func (m MockManager) StopApplication(app *shipyard.Application, username string) manager.ApplicationResult {
+	return manager.ApplicationResult{Containers: []string{TestContainerId}, Errors: []string{}}
+}
+
+func (m MockManager) RestartApplication(app *shipyard.Application, username string) manager.ApplicationResult {
+	return manager.ApplicationResult{
+		Containers: []string{TestContainerId},
+		Errors:     []string{TestContainerName + ": restart failed"},
+	}
+}
+
+func (m MockManager) RedeployApplication(app *shipyard.Application, definition, username string) error {
+	if app.ID == "" {
+		return manager.ErrApplicationNotDeployed
+	}
+
+	return nil
+}
+
+func (m MockManager) RemoveApplication(app *shipyard.Application, username string) manager.ApplicationResult {
+	return manager.ApplicationResult{Containers: []string{TestContainerId}, Errors: []string{}}
+}
+
+func (m MockManager) ApplicationLogs(app *shipyard.Application, tail int) ([]*shipyard.ApplicationLog, error) {
+	return []*shipyard.ApplicationLog{
+		{Container: TestContainerName, Service: "web", Stream: "stdout", Message: "started"},
+	}, nil
+}
+
+func (m MockManager) ApplicationStats(app *shipyard.Application) (*shipyard.ApplicationStats, error) {
+	return &shipyard.ApplicationStats{
+		Application: app.Name,
+		CPUPercent:  1.5,
+		MemoryUsage: 1024,
+		Containers: []*shipyard.ContainerStats{
+			{Container: TestContainerName, Service: "web", CPUPercent: 1.5, MemoryUsage: 1024},
+		},
+		Errors: []string{},
+	}, nil
+}
+
 func (m MockManager) Node(name string) (*shipyard.Node, error) {
 	return TestNode, nil
 }
 
+func (m MockManager) ClusterInfo() (*shipyard.ClusterInfo, error) {
+	return TestClusterInfo, nil
+}
+
+func (m MockManager) MaintenanceNodes() ([]*shipyard.NodeMaintenance, error) {
+	return []*shipyard.NodeMaintenance{
+		{Node: TestNode.Name, Reason: "patching"},
+	}, nil
+}
+
+func (m MockManager) SetNodeMaintenance(node, reason, username string) error {
+	if node != TestNode.Name {
+		return manager.ErrNodeDoesNotExist
+	}
+
+	return nil
+}
+
+func (m MockManager) ClearNodeMaintenance(node, username string) error {
+	return nil
+}
+
+func (m MockManager) PlaceContainer(config *dockerclient.ContainerConfig) (bool, error) {
+	labels := map[string]string{}
+	for k, v := range config.Labels {
+		labels[k] = v
+	}
+	labels["com.docker.swarm.constraints"] = `["node!=/^` + TestNode.Name + `$/"]`
+	config.Labels = labels
+
+	return true, nil
+}
+
+func (m MockManager) DrainNode(node, username string, progress func(*shipyard.DrainProgress)) (*shipyard.DrainResult, error) {
+	if node != TestNode.Name {
+		return nil, manager.ErrNodeDoesNotExist
+	}
+
+	result := &shipyard.DrainResult{
+		Node:    node,
+		Moved:   []string{TestContainerName},
+		Failed:  []string{},
+		Skipped: []string{},
+		Errors:  []string{},
+	}
+
+	progress(&shipyard.DrainProgress{Node: node, Stage: "moved", Container: TestContainerName, Moved: 1, Total: 1})
+	progress(&shipyard.DrainProgress{Node: node, Stage: "complete", Moved: 1, Total: 1, Result: result})
+
+	return result, nil
+}
+
+func (m MockManager) Services() ([]*shipyard.SwarmService, error) {
+	return []*shipyard.SwarmService{TestService}, nil
+}
+
+func (m MockManager) Service(id string) (*shipyard.SwarmService, error) {
+	if id != TestService.ID && id != TestService.Spec.Name {
+		return nil, manager.ErrServiceDoesNotExist
+	}
+
+	return TestService, nil
+}
+
+func (m MockManager) CreateService(spec *shipyard.SwarmServiceSpec, username string) (string, error) {
+	if spec.Name == TestService.Spec.Name {
+		return "", &manager.SwarmError{StatusCode: 409, Message: "name conflicts with an existing object"}
+	}
+
+	return "service1", nil
+}
+
+func (m MockManager) UpdateService(id string, version uint64, spec *shipyard.SwarmServiceSpec, username string) error {
+	if id != TestService.ID {
+		return manager.ErrServiceDoesNotExist
+	}
+
+	return nil
+}
+
+func (m MockManager) RemoveService(id, username string) error {
+	if id != TestService.ID {
+		return manager.ErrServiceDoesNotExist
+	}
+
+	return nil
+}
+
+func (m MockManager) Tasks(service string) ([]*shipyard.SwarmTask, error) {
+	return []*shipyard.SwarmTask{TestTask}, nil
+}
+
+func (m MockManager) Task(id string) (*shipyard.SwarmTask, error) {
+	if id != TestTask.ID {
+		return nil, manager.ErrTaskDoesNotExist
+	}
+
+	return TestTask, nil
+}
+
+func (m MockManager) SwarmNodes() ([]*shipyard.SwarmNode, error) {
+	return []*shipyard.SwarmNode{
+		{ID: "node0", Description: shipyard.SwarmNodeDescription{Hostname: TestNode.Name}},
+	}, nil
+}
+
+func (m MockManager) Secrets() ([]*shipyard.SwarmSecret, error) {
+	return []*shipyard.SwarmSecret{TestSecret}, nil
+}
+
+func (m MockManager) Secret(id string) (*shipyard.SwarmSecret, error) {
+	if id != TestSecret.ID {
+		return nil, manager.ErrSecretDoesNotExist
+	}
+
+	return TestSecret, nil
+}
+
+func (m MockManager) CreateSecret(spec *shipyard.SwarmSecretSpec, username string) (string, error) {
+	return "secret1", nil
+}
+
+func (m MockManager) RemoveSecret(id, username string) error {
+	if id != TestSecret.ID {
+		return manager.ErrSecretDoesNotExist
+	}
+
+	return nil
+}
+
+func (m MockManager) Networks() ([]*dockerclient.NetworkResource, error) {
+	return []*dockerclient.NetworkResource{TestNetwork}, nil
+}
+
+func (m MockManager) Network(id string) (*dockerclient.NetworkResource, error) {
+	if id != TestNetwork.ID && id != TestNetwork.Name {
+		return nil, manager.ErrNetworkDoesNotExist
+	}
+
+	return TestNetwork, nil
+}
+
+func (m MockManager) AdmitNetwork(config *dockerclient.NetworkCreate, username string) error {
+	// the test cluster is a classic swarm
+	if config.Driver == "" {
+		config.Driver = "overlay"
+	}
+
+	if config.Driver == "overlay" && !config.Internal {
+		return manager.ErrNetworkNotAdmitted
+	}
+
+	return nil
+}
+
+func (m MockManager) CreateNetwork(config *dockerclient.NetworkCreate, username string) (*dockerclient.NetworkResource, error) {
+	if err := m.AdmitNetwork(config, username); err != nil {
+		return nil, err
+	}
+
+	return &dockerclient.NetworkResource{ID: "network1", Name: config.Name, Driver: config.Driver, IPAM: config.IPAM}, nil
+}
+
+func (m MockManager) RemoveNetwork(id, username string) error {
+	if id == TestNetwork.ID || id == TestNetwork.Name {
+		return manager.ErrNetworkInUse
+	}
+
+	return manager.ErrNetworkDoesNotExist
+}
+
+func (m MockManager) ConnectNetwork(id, container, username string) error {
+	if _, err := m.Network(id); err != nil {
+		return err
+	}
+
+	if container == "" {
+		return manager.ErrNetworkContainerRequired
+	}
+
+	return nil
+}
+
+func (m MockManager) DisconnectNetwork(id, container string, force bool, username string) error {
+	return m.ConnectNetwork(id, container, username)
+}
+
+func (m MockManager) Volumes(usage bool) ([]*shipyard.Volume, error) {
+	return []*shipyard.Volume{
+		TestVolume,
+		{Name: "node1/cache", Node: "node1", Driver: "local", Containers: []string{}, Dangling: true, Size: 1024},
+	}, nil
+}
+
+func (m MockManager) Volume(name string, usage bool) (*shipyard.Volume, error) {
+	if name != TestVolume.Name {
+		return nil, manager.ErrVolumeDoesNotExist
+	}
+
+	if usage {
+		v := *TestVolume
+		v.Size = 1024
+		return &v, nil
+	}
+
+	return TestVolume, nil
+}
+
+func (m MockManager) CreateVolume(request *dockerclient.VolumeCreateRequest, username string) (*shipyard.Volume, error) {
+	return &shipyard.Volume{Name: request.Name, Driver: "local", Containers: []string{}, Dangling: true, Size: -1}, nil
+}
+
+func (m MockManager) RemoveVolume(name, username string) error {
+	if name == TestVolume.Name {
+		return manager.ErrVolumeInUse
+	}
+
+	return manager.ErrVolumeDoesNotExist
+}
+
+func (m MockManager) CleanupVolumes(confirm bool, username string) (*shipyard.VolumeCleanup, error) {
+	return &shipyard.VolumeCleanup{
+		Confirmed: confirm,
+		Volumes:   []string{"node1/cache"},
+		Size:      1024,
+		Errors:    []string{},
+	}, nil
+}
+
 func (m MockManager) CreateConsoleSession(c *shipyard.ConsoleSession) error {
 	return nil
 }
@@ -193,6 +644,192 @@ func (m MockManager) GetAuthenticator() auth.Authenticator {
 	return nil
 }
 
-func (m MockManager) ScaleContainer(id string, numInstances int) manager.ScaleResult {
+func (m MockManager) ScaleContainer(id string, numInstances int, ports manager.PortStrategy, username string) manager.ScaleResult {
 	return manager.ScaleResult{Scaled: []string{"9c3c7dd2199a95cce29950b612ecf918ae278a42e53e10f6cccb752b6fbcd8b3"}, Errors: []string{"500 Internal Server Error: no resources available to schedule container"}}
 }
+
+func (m MockManager) ScaleContainerReplicas(id string, replicas int, ports manager.PortStrategy, username string) manager.GroupScaleResult {
+	return m.ScaleGroup(TestContainerName, replicas, ports, username)
+}
+
+func (m MockManager) HealthStatuses() ([]*shipyard.ContainerHealth, error) {
+	return []*shipyard.ContainerHealth{
+		TestContainerHealth,
+	}, nil
+}
+
+func (m MockManager) ContainerHealth(id string) (*shipyard.ContainerHealth, error) {
+	if id != TestContainerId && id != TestContainerName {
+		return nil, manager.ErrContainerNotMonitored
+	}
+
+	return TestContainerHealth, nil
+}
+
+func (m MockManager) ScaleGroup(group string, replicas int, ports manager.PortStrategy, username string) manager.GroupScaleResult {
+	result := manager.GroupScaleResult{
+		Group:   group,
+		Created: []string{},
+		Removed: []string{},
+		Errors:  []string{},
+		Nodes:   map[string]int{},
+	}
+
+	if group != TestContainerName {
+		result.Errors = append(result.Errors, manager.ErrGroupDoesNotExist.Error())
+		return result
+	}
+
+	result.Replicas = replicas
+	if replicas > 0 {
+		result.Nodes[TestNode.Name] = replicas
+	}
+
+	return result
+}
+
+func (m MockManager) UpdateApplication(app *shipyard.Application, update *shipyard.RollingUpdate, username string, progress func(*shipyard.UpdateProgress)) (*shipyard.UpdateResult, error) {
+	return m.UpdateGroup(app.Name, update, username, progress)
+}
+
+func (m MockManager) UpdateGroup(group string, update *shipyard.RollingUpdate, username string, progress func(*shipyard.UpdateProgress)) (*shipyard.UpdateResult, error) {
+	if group != TestContainerName && group != TestApplication.Name {
+		return nil, manager.ErrGroupDoesNotExist
+	}
+
+	if update.BatchSize < 0 {
+		return nil, errors.New("batch size must not be negative")
+	}
+
+	result := &shipyard.UpdateResult{
+		Target:   group,
+		Image:    update.Image,
+		Replaced: []string{TestContainerId},
+		Failed:   []string{},
+		Errors:   []string{},
+	}
+
+	progress(&shipyard.UpdateProgress{Target: group, Stage: "start", Total: 1})
+	progress(&shipyard.UpdateProgress{Target: group, Stage: "replaced", Container: TestContainerName, Replaced: 1, Total: 1})
+	progress(&shipyard.UpdateProgress{Target: group, Stage: "complete", Replaced: 1, Total: 1, Result: result})
+
+	return result, nil
+}
+
+func (m MockManager) Revisions(kind, target string) ([]*shipyard.Revision, error) {
+	if kind != TestRevision.Kind || target != TestRevision.Target {
+		return []*shipyard.Revision{}, nil
+	}
+
+	return []*shipyard.Revision{TestRevision}, nil
+}
+
+func (m MockManager) Revision(kind, target string, number int) (*shipyard.Revision, error) {
+	if kind != TestRevision.Kind || target != TestRevision.Target || number != TestRevision.Number {
+		return nil, manager.ErrRevisionDoesNotExist
+	}
+
+	return TestRevision, nil
+}
+
+func (m MockManager) DiffRevisions(kind, target string, from, to int) (*shipyard.RevisionDiff, error) {
+	// only a single revision exists
+	return nil, manager.ErrRevisionDoesNotExist
+}
+
+func (m MockManager) RollbackApplication(app *shipyard.Application, number int, username string) (manager.ApplicationResult, error) {
+	rev, err := m.Revision(shipyard.RevisionApplication, app.Name, number)
+	if err != nil {
+		return manager.ApplicationResult{}, err
+	}
+
+	result := manager.ApplicationResult{Containers: []string{}, Errors: []string{}}
+	for _, c := range rev.Containers {
+		result.Containers = append(result.Containers, c.Name)
+	}
+
+	return result, nil
+}
+
+func (m MockManager) ReconcileApplication(app *shipyard.Application, username string) (*shipyard.ApplicationDrift, error) {
+	if app.ReconcilePaused {
+		return nil, manager.ErrReconcilePaused
+	}
+
+	return &shipyard.ApplicationDrift{
+		Application: app.Name,
+		Revision:    TestRevision.Number,
+		Missing:     []string{"test-app_web_1"},
+		Extra:       []string{},
+		Created:     []string{"test-app_web_1"},
+		Removed:     []string{},
+		Errors:      []string{},
+	}, nil
+}
+
+func (m MockManager) SetReconcilePaused(app *shipyard.Application, paused bool, username string) error {
+	if app.ID == "" {
+		return manager.ErrApplicationNotDeployed
+	}
+
+	return nil
+}
+
+func (m MockManager) Templates(username string) ([]*shipyard.Template, error) {
+	return []*shipyard.Template{TestTemplate}, nil
+}
+
+func (m MockManager) Template(name string, version int, username string) (*shipyard.Template, error) {
+	if name != TestTemplate.Name || (version != 0 && version != TestTemplate.Version) {
+		return nil, manager.ErrTemplateDoesNotExist
+	}
+
+	return TestTemplate, nil
+}
+
+func (m MockManager) TemplateVersions(name, username string) ([]*shipyard.Template, error) {
+	t, err := m.Template(name, 0, username)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*shipyard.Template{t}, nil
+}
+
+func (m MockManager) SaveTemplate(t *shipyard.Template, username string) error {
+	if t.Name == "" {
+		return errors.New("invalid template name")
+	}
+
+	if t.Name == TestTemplate.Name && username != TestTemplate.Owner {
+		return manager.ErrTemplateAccessDenied
+	}
+
+	return nil
+}
+
+func (m MockManager) RemoveTemplate(name, username string) error {
+	_, err := m.Template(name, 0, username)
+	return err
+}
+
+func (m MockManager) RenderTemplate(name string, deployment *shipyard.TemplateDeployment, username string) (*dockerclient.ContainerConfig, error) {
+	if _, err := m.Template(name, deployment.Version, username); err != nil {
+		return nil, err
+	}
+
+	version, ok := deployment.Variables["Version"].(string)
+	if !ok {
+		return nil, &manager.TemplateError{Err: errors.New("variable Version is required")}
+	}
+
+	return &dockerclient.ContainerConfig{Image: "nginx:" + version}, nil
+}
+
+func (m MockManager) DeployTemplate(name string, deployment *shipyard.TemplateDeployment, username string) (manager.ApplicationResult, error) {
+	if _, err := m.RenderTemplate(name, deployment, username); err != nil {
+		return manager.ApplicationResult{}, err
+	}
+
+	return manager.ApplicationResult{Containers: []string{TestContainerId}, Errors: []string{}}, nil
+}
@@ -34,7 +34,7 @@
             },
             listRepositories: function(name) {
                 var promise = $http
-                    .get('/api/registries/'+name+'/repositories')
+                    .get('/api/registries/'+name+'/repositories', {params: {expand: 1}})
                     .then(function(response) {
                         return response.data;
                     });
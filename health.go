@@ -0,0 +1,66 @@
+package shipyard
+
+import (
+	"time"
+)
+
+const (
+	// HealthProbeHTTP expects a 2xx or 3xx response to a GET of the path
+	HealthProbeHTTP = "http"
+	// HealthProbeTCP expects the port to accept connections
+	HealthProbeTCP = "tcp"
+	// HealthProbeExec expects the command to exit with 0 in the container
+	HealthProbeExec = "exec"
+
+	// HealthActionRestart restarts an unhealthy container
+	HealthActionRestart = "restart"
+	// HealthActionReplace recreates an unhealthy container from its config
+	HealthActionReplace = "replace"
+
+	HealthStarting  = "starting"
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+
+	// labels configuring the health probe of a container
+	HealthTypeLabel      = "com.shipyard.health.type"
+	HealthPortLabel      = "com.shipyard.health.port"
+	HealthPathLabel      = "com.shipyard.health.path"
+	HealthCommandLabel   = "com.shipyard.health.command"
+	HealthIntervalLabel  = "com.shipyard.health.interval"
+	HealthThresholdLabel = "com.shipyard.health.threshold"
+	HealthActionLabel    = "com.shipyard.health.action"
+)
+
+type (
+	// HealthProbe checks a container.  Port is the container port (i.e.
+	// "8080" or "8080/tcp") and is reached through its published host port
+	// or the container address.  Command is run with "sh -c" inside the
+	// container by exec probes.  Interval (in seconds), Threshold and Action
+	// are only used by the health monitor: after Threshold consecutive
+	// failures the container is unhealthy and the action is taken.
+	HealthProbe struct {
+		Type      string `json:"type,omitempty"`
+		Port      string `json:"port,omitempty"`
+		Path      string `json:"path,omitempty"`
+		Command   string `json:"command,omitempty"`
+		Interval  int    `json:"interval,omitempty"`
+		Threshold int    `json:"threshold,omitempty"`
+		Action    string `json:"action,omitempty"`
+	}
+
+	// ContainerHealth is the last known health of a monitored container.
+	// Failures counts the consecutive failed probes and Actions the
+	// restarts or replacements triggered by them.  Since is the time of
+	// the last status change.
+	ContainerHealth struct {
+		ID        string       `json:"id,omitempty"`
+		Name      string       `json:"name,omitempty"`
+		Status    string       `json:"status,omitempty"`
+		Probe     *HealthProbe `json:"probe,omitempty"`
+		Failures  int          `json:"failures"`
+		Actions   int          `json:"actions"`
+		LastError string       `json:"last_error,omitempty"`
+		LastCheck time.Time    `json:"last_check,omitempty"`
+		Since     time.Time    `json:"since,omitempty"`
+	}
+)
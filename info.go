@@ -1,14 +1,47 @@
 package shipyard
 
+import (
+	"time"
+)
+
 type (
+	// ClusterInfo is the capacity of the cluster.  Memory is in bytes.
+	// Nodes is the breakdown per node and History the recent totals
+	// recorded by the controller.
 	ClusterInfo struct {
-		Cpus           float64 `json:"cpus,omitempty"`
-		Memory         float64 `json:"memory,omitempty"`
-		ContainerCount int     `json:"container_count,omitempty"`
-		EngineCount    int     `json:"engine_count,omitempty"`
-		ImageCount     int     `json:"image_count,omitempty"`
-		ReservedCpus   float64 `json:"reserved_cpus,omitempty"`
-		ReservedMemory float64 `json:"reserved_memory,omitempty"`
-		Version        string  `json:"version,omitempty"`
+		Cpus           float64              `json:"cpus"`
+		Memory         float64              `json:"memory"`
+		ContainerCount int                  `json:"container_count"`
+		EngineCount    int                  `json:"engine_count"`
+		ImageCount     int                  `json:"image_count"`
+		ReservedCpus   float64              `json:"reserved_cpus"`
+		ReservedMemory float64              `json:"reserved_memory"`
+		Version        string               `json:"version,omitempty"`
+		Nodes          []*ClusterNodeInfo   `json:"nodes,omitempty"`
+		History        []*ClusterInfoSample `json:"history,omitempty"`
+	}
+
+	// ClusterNodeInfo is the capacity of a node of the cluster
+	ClusterNodeInfo struct {
+		Name           string  `json:"name,omitempty"`
+		Addr           string  `json:"addr,omitempty"`
+		Status         string  `json:"status,omitempty"`
+		Health         string  `json:"health,omitempty"`
+		Cpus           float64 `json:"cpus"`
+		Memory         float64 `json:"memory"`
+		ContainerCount int     `json:"container_count"`
+		ReservedCpus   float64 `json:"reserved_cpus"`
+		ReservedMemory float64 `json:"reserved_memory"`
+	}
+
+	// ClusterInfoSample is the capacity of the cluster at a point in time
+	ClusterInfoSample struct {
+		Time           time.Time `json:"time"`
+		Cpus           float64   `json:"cpus"`
+		Memory         float64   `json:"memory"`
+		ContainerCount int       `json:"container_count"`
+		EngineCount    int       `json:"engine_count"`
+		ReservedCpus   float64   `json:"reserved_cpus"`
+		ReservedMemory float64   `json:"reserved_memory"`
 	}
 )
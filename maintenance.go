@@ -0,0 +1,41 @@
+package shipyard
+
+import (
+	"time"
+)
+
+type (
+	// NodeMaintenance marks a node as being in maintenance.  No new
+	// containers are placed on the node until maintenance ends.
+	NodeMaintenance struct {
+		ID       string    `json:"id,omitempty" gorethink:"id,omitempty"`
+		Node     string    `json:"node,omitempty" gorethink:"node,omitempty"`
+		Reason   string    `json:"reason,omitempty" gorethink:"reason,omitempty"`
+		Username string    `json:"username,omitempty" gorethink:"username,omitempty"`
+		Started  time.Time `json:"started,omitempty" gorethink:"started,omitempty"`
+	}
+
+	// DrainProgress reports a step of draining a node.  The final step
+	// includes the result.
+	DrainProgress struct {
+		Node      string       `json:"node,omitempty"`
+		Stage     string       `json:"stage,omitempty"`
+		Container string       `json:"container,omitempty"`
+		Message   string       `json:"message,omitempty"`
+		Moved     int          `json:"moved"`
+		Failed    int          `json:"failed"`
+		Total     int          `json:"total"`
+		Time      time.Time    `json:"time,omitempty"`
+		Result    *DrainResult `json:"result,omitempty"`
+	}
+
+	// DrainResult lists the containers moved off a node.  Containers not
+	// managed by Shipyard are skipped and left on the node.
+	DrainResult struct {
+		Node    string   `json:"node,omitempty"`
+		Moved   []string `json:"moved"`
+		Failed  []string `json:"failed"`
+		Skipped []string `json:"skipped"`
+		Errors  []string `json:"errors"`
+	}
+)
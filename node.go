@@ -1,12 +1,43 @@
 package shipyard
 
+import (
+	"time"
+)
+
+// Node is an engine of the cluster.  The reserved CPUs and memory are
+// parsed from the swarm strings into numbers (memory in bytes).  Role and
+// Availability are only set for nodes of a swarm mode cluster.  Health,
+// ResponseTime (in milliseconds), Uptime (percentage of successful pings in
+// the recorded history) and LastSeen are set by the node health monitor.
 type Node struct {
-	ID             string   `json:"id,omitempty" gorethink:"id,omitempty"`
-	Name           string   `json:"name,omitempty" gorethink:"name,omitempty"`
-	Addr           string   `json:"addr,omitempty" gorethink:"addr,omitempty"`
-	Containers     string   `json:"containers,omitempty"`
-	ReservedCPUs   string   `json:"reserved_cpus,omitempty"`
-	ReservedMemory string   `json:"reserved_memory,omitempty"`
-	Labels         []string `json:"labels,omitempty"`
-	ResponseTime   float64  `json:"response_time" gorethink:"response_time,omitempty"`
+	ID             string              `json:"id,omitempty" gorethink:"id,omitempty"`
+	Name           string              `json:"name,omitempty" gorethink:"name,omitempty"`
+	Addr           string              `json:"addr,omitempty" gorethink:"addr,omitempty"`
+	Containers     string              `json:"containers,omitempty"`
+	ReservedCPUs   string              `json:"reserved_cpus,omitempty"`
+	ReservedMemory string              `json:"reserved_memory,omitempty"`
+	Labels         []string            `json:"labels,omitempty"`
+	Status         string              `json:"status,omitempty"`
+	Role           string              `json:"role,omitempty"`
+	Availability   string              `json:"availability,omitempty"`
+	ServerVersion  string              `json:"server_version,omitempty"`
+	Error          string              `json:"error,omitempty"`
+	UpdatedAt      time.Time           `json:"updated_at,omitempty"`
+	CPUsReserved   float64             `json:"cpus_reserved"`
+	CPUsTotal      float64             `json:"cpus_total"`
+	MemoryReserved int64               `json:"memory_reserved"`
+	MemoryTotal    int64               `json:"memory_total"`
+	ResponseTime   float64             `json:"response_time" gorethink:"response_time,omitempty"`
+	Health         string              `json:"health,omitempty"`
+	Uptime         float64             `json:"uptime"`
+	LastSeen       time.Time           `json:"last_seen,omitempty"`
+	HealthHistory  []*NodeHealthSample `json:"health_history,omitempty"`
+}
+
+// NodeHealthSample is the result of pinging a node
+type NodeHealthSample struct {
+	Time         time.Time `json:"time"`
+	Health       string    `json:"health"`
+	ResponseTime float64   `json:"response_time"`
+	Error        string    `json:"error,omitempty"`
 }
@@ -0,0 +1,94 @@
+package shipyard
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// resources of the docker api used for access control and audit
+	ResourceContainers = "containers"
+	ResourceImages     = "images"
+	ResourceVolumes    = "volumes"
+	ResourceNetworks   = "networks"
+	ResourceServices   = "services"
+	ResourceTasks      = "tasks"
+	ResourceNodes      = "nodes"
+	ResourceSecrets    = "secrets"
+	ResourceConfigs    = "configs"
+	ResourceSwarm      = "swarm"
+	ResourcePlugins    = "plugins"
+	ResourceSystem     = "system"
+
+	// ProxyPolicyAllow forwards docker api endpoints of unknown resources
+	ProxyPolicyAllow = "allow"
+	// ProxyPolicyDeny rejects docker api endpoints of unknown resources
+	ProxyPolicyDeny = "deny"
+)
+
+var (
+	apiVersionRegexp = regexp.MustCompile(`^/v([0-9]+(\.[0-9]+)?)(/|$)`)
+
+	// proxyResources maps the first path element of docker api endpoints
+	// to their resource
+	proxyResources = map[string]string{
+		"containers":   ResourceContainers,
+		"exec":         ResourceContainers,
+		"commit":       ResourceContainers,
+		"images":       ResourceImages,
+		"build":        ResourceImages,
+		"distribution": ResourceImages,
+		"volumes":      ResourceVolumes,
+		"networks":     ResourceNetworks,
+		"services":     ResourceServices,
+		"tasks":        ResourceTasks,
+		"nodes":        ResourceNodes,
+		"secrets":      ResourceSecrets,
+		"configs":      ResourceConfigs,
+		"swarm":        ResourceSwarm,
+		"plugins":      ResourcePlugins,
+		"_ping":        ResourceSystem,
+		"auth":         ResourceSystem,
+		"events":       ResourceSystem,
+		"info":         ResourceSystem,
+		"version":      ResourceSystem,
+		"system":       ResourceSystem,
+		"session":      ResourceSystem,
+	}
+)
+
+// SplitAPIVersion returns the docker api version of the path (i.e. "1.24"
+// for "/v1.24/containers/json") and the path without it.  The version is
+// empty for paths without one.
+func SplitAPIVersion(path string) (string, string) {
+	m := apiVersionRegexp.FindStringSubmatch(path)
+	if m == nil {
+		return "", path
+	}
+
+	rest := path[len("/v"+m[1]):]
+	if rest == "" {
+		rest = "/"
+	}
+
+	return m[1], rest
+}
+
+// ProxyResource returns the resource of the docker api path without its
+// version.  It is empty for unknown endpoints.
+func ProxyResource(path string) string {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+
+	return proxyResources[parts[0]]
+}
+
+// IsProxyPath reports whether the path is a docker api endpoint.  Paths
+// with an api version are docker api endpoints even if the resource is
+// unknown.
+func IsProxyPath(path string) bool {
+	if apiVersionRegexp.MatchString(path) {
+		return true
+	}
+
+	return ProxyResource(path) != ""
+}
@@ -1,16 +1,111 @@
 package shipyard
 
 import (
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/samalba/dockerclient"
 	registry "github.com/shipyard/shipyard/registry/v1"
 )
 
+type (
+	// RepositoryUsage lists the tags of a repository and the running
+	// containers that would break if the repository was deleted
+	RepositoryUsage struct {
+		Registry   string                   `json:"registry,omitempty"`
+		Repository string                   `json:"repository,omitempty"`
+		Tags       []registry.Tag           `json:"tags"`
+		Containers []dockerclient.Container `json:"containers"`
+	}
+
+	// RepositoryDeletion records the tags and image ids removed with a
+	// repository
+	RepositoryDeletion struct {
+		Registry   string         `json:"registry,omitempty"`
+		Repository string         `json:"repository,omitempty"`
+		Tags       []registry.Tag `json:"tags"`
+		Containers []string       `json:"containers,omitempty"`
+		Forced     bool           `json:"forced"`
+		Username   string         `json:"username,omitempty"`
+		Time       time.Time      `json:"time,omitempty"`
+	}
+
+	// Promotion copies a tagged image from one registry to another.  The
+	// target repository and tag default to the source values.
+	Promotion struct {
+		SourceRegistry   string    `json:"source_registry,omitempty"`
+		SourceRepository string    `json:"source_repository,omitempty"`
+		SourceTag        string    `json:"source_tag,omitempty"`
+		TargetRegistry   string    `json:"target_registry,omitempty"`
+		TargetRepository string    `json:"target_repository,omitempty"`
+		TargetTag        string    `json:"target_tag,omitempty"`
+		ImageID          string    `json:"image_id,omitempty"`
+		CopiedLayers     []string  `json:"copied_layers"`
+		SkippedLayers    []string  `json:"skipped_layers"`
+		Username         string    `json:"username,omitempty"`
+		Time             time.Time `json:"time,omitempty"`
+	}
+
+	// RepositoryDeployment creates a container from a tagged image of a
+	// registry repository.  The tag defaults to "latest" and ports use the
+	// compose format ([[ip:]host_port:]container_port[/protocol]).
+	RepositoryDeployment struct {
+		Tag             string    `json:"tag,omitempty"`
+		Name            string    `json:"name,omitempty"`
+		Env             []string  `json:"env,omitempty"`
+		Memory          int64     `json:"memory,omitempty"`
+		CpuShares       int64     `json:"cpu_shares,omitempty"`
+		Ports           []string  `json:"ports,omitempty"`
+		PublishAllPorts bool      `json:"publish_all_ports,omitempty"`
+		Registry        string    `json:"registry,omitempty"`
+		Repository      string    `json:"repository,omitempty"`
+		Image           string    `json:"image,omitempty"`
+		ContainerID     string    `json:"container_id,omitempty"`
+		Username        string    `json:"username,omitempty"`
+		Time            time.Time `json:"time,omitempty"`
+	}
+
+	// ImageSearchResult is a repository matching an image search
+	ImageSearchResult struct {
+		Registry    string         `json:"registry,omitempty"`
+		Image       string         `json:"image,omitempty"`
+		Repository  string         `json:"repository,omitempty"`
+		Description string         `json:"description,omitempty"`
+		Tags        []registry.Tag `json:"tags"`
+		Score       int            `json:"score"`
+	}
+
+	// ImageSearch holds the merged results of searching all registries.
+	// Registries which failed or timed out are listed in Errors.
+	ImageSearch struct {
+		Query   string               `json:"query"`
+		Results []*ImageSearchResult `json:"results"`
+		Errors  map[string]string    `json:"errors"`
+	}
+)
+
 type Registry struct {
 	ID             string                   `json:"id,omitempty" gorethink:"id,omitempty"`
 	Name           string                   `json:"name,omitempty" gorethink:"name,omitempty"`
 	Addr           string                   `json:"addr,omitempty", gorethink:"addr,omitempty"`
+	Username       string                   `json:"username,omitempty" gorethink:"username,omitempty"`
+	Password       string                   `json:"password,omitempty" gorethink:"password,omitempty"`
+	Email          string                   `json:"email,omitempty" gorethink:"email,omitempty"`
+	Roles          []string                 `json:"roles,omitempty" gorethink:"roles,omitempty"`
 	registryClient *registry.RegistryClient `json:"-" gorethink:"-"`
 }
 
+// MarshalJSON omits the stored password so that it is never returned by
+// the api.  The password is still read when a registry is added.
+func (r Registry) MarshalJSON() ([]byte, error) {
+	type plainRegistry Registry
+	reg := plainRegistry(r)
+	reg.Password = ""
+
+	return json.Marshal(reg)
+}
+
 func NewRegistry(id, name, addr string) (*Registry, error) {
 	rClient, err := registry.NewRegistryClient(addr, nil)
 	if err != nil {
@@ -25,19 +120,107 @@ func NewRegistry(id, name, addr string) (*Registry, error) {
 	}, nil
 }
 
-func (r *Registry) Repositories() ([]*registry.Repository, error) {
-	res, err := r.registryClient.Search("", 1, 100)
-	if err != nil {
-		return nil, err
+// SetCredentials stores the registry credentials and uses them for
+// subsequent registry api requests
+func (r *Registry) SetCredentials(username, password, email string) {
+	r.Username = username
+	r.Password = password
+	r.Email = email
+	r.registryClient.SetBasicAuth(username, password)
+}
+
+// Host returns the registry host as used in image names (i.e. "host:5000")
+func (r *Registry) Host() string {
+	u, err := url.Parse(r.Addr)
+	if err != nil || u.Host == "" {
+		return r.Addr
 	}
 
-	return res.Results, nil
+	return u.Host
+}
+
+// HasCredentials reports whether credentials are stored for the registry
+func (r *Registry) HasCredentials() bool {
+	return r.Username != ""
+}
+
+// AuthConfig returns the stored credentials for use with the Docker engine
+func (r *Registry) AuthConfig() *dockerclient.AuthConfig {
+	return &dockerclient.AuthConfig{
+		Username: r.Username,
+		Password: r.Password,
+		Email:    r.Email,
+	}
+}
+
+// CanUseCredentials reports whether an account with the specified roles
+// may use the stored credentials.  Registries without roles allow any account
+// and registries with roles deny requests without roles.
+func (r *Registry) CanUseCredentials(roles []string) bool {
+	if len(r.Roles) == 0 {
+		return true
+	}
+
+	for _, role := range roles {
+		if role == "admin" {
+			return true
+		}
+
+		for _, allowed := range r.Roles {
+			if role == allowed {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// SetDeadline cancels registry requests which do not complete before the
+// deadline
+func (r *Registry) SetDeadline(deadline time.Time) {
+	r.registryClient.SetDeadline(deadline)
+}
+
+// Repositories returns a page of repositories matching the query.  Layers
+// are only fetched when expand is set.
+func (r *Registry) Repositories(query string, page, perPage int, expand bool) (*registry.SearchResult, error) {
+	if expand {
+		return r.registryClient.Search(query, page, perPage)
+	}
+
+	return r.registryClient.List(query, page, perPage)
 }
 
 func (r *Registry) Repository(name string) (*registry.Repository, error) {
 	return r.registryClient.Repository(name)
 }
 
+// Layer returns the metadata of the image
+func (r *Registry) Layer(id string) (*registry.Layer, error) {
+	return r.registryClient.Layer(id)
+}
+
+// Ancestry returns the ids of the image and all of its parents
+func (r *Registry) Ancestry(id string) ([]string, error) {
+	return r.registryClient.Ancestry(id)
+}
+
+// Tags returns the tags of the repository with their creation time
+func (r *Registry) Tags(repo string) ([]registry.Tag, error) {
+	return r.registryClient.ListTags(repo)
+}
+
+func (r *Registry) DeleteTag(repo, tag string) error {
+	return r.registryClient.DeleteTag(repo, tag)
+}
+
 func (r *Registry) DeleteRepository(name string) error {
 	return r.registryClient.DeleteRepository(name)
 }
+
+// CopyImage copies srcRepo:srcTag from the src registry to dstRepo:dstTag in
+// this registry.  Layers already present in this registry are not copied.
+func (r *Registry) CopyImage(src *Registry, srcRepo, srcTag, dstRepo, dstTag string) (*registry.CopyResult, error) {
+	return r.registryClient.Copy(src.registryClient, srcRepo, srcTag, dstRepo, dstTag)
+}
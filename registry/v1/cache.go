@@ -0,0 +1,96 @@
+package v1
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLayerCacheSize = 4096
+	defaultLayerCacheTTL  = 10 * time.Minute
+)
+
+var (
+	// v1 image ids are random and not content addressed so a registry may
+	// accept a push which replaces the metadata or ancestry of an id.
+	// Entries expire after the ttl to pick up such changes.  Entries are
+	// keyed by registry and user (see RegistryClient.cacheKey).
+	layers    = newCache(defaultLayerCacheSize, defaultLayerCacheTTL)
+	ancestors = newCache(defaultLayerCacheSize, defaultLayerCacheTTL)
+)
+
+type (
+	cacheEntry struct {
+		key     string
+		value   interface{}
+		expires time.Time
+	}
+
+	// cache is a size bounded least recently used cache whose entries
+	// expire after the ttl
+	cache struct {
+		mu      sync.Mutex
+		size    int
+		ttl     time.Duration
+		entries map[string]*list.Element
+		order   *list.List
+	}
+)
+
+func newCache(size int, ttl time.Duration) *cache {
+	return &cache{
+		size:    size,
+		ttl:     ttl,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+func (c *cache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(e.Value.(*cacheEntry).expires) {
+		c.order.Remove(e)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(e)
+	return e.Value.(*cacheEntry).value, true
+}
+
+func (c *cache) Add(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := time.Now().Add(c.ttl)
+
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		e.Value.(*cacheEntry).value = value
+		e.Value.(*cacheEntry).expires = expires
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value, expires: expires})
+
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (c *cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
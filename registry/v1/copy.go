@@ -0,0 +1,176 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	checksumHeader = "X-Docker-Checksum-Payload"
+)
+
+type (
+	// CopyResult lists the images transferred by Copy.  Images already
+	// present in the target registry are skipped.
+	CopyResult struct {
+		ImageID string   `json:"image_id,omitempty"`
+		Copied  []string `json:"copied"`
+		Skipped []string `json:"skipped"`
+	}
+)
+
+// ImageExists reports whether the registry has the image including its
+// layer.  The json is uploaded before the layer so an image whose layer
+// upload failed only has the json and must be uploaded again.
+func (client *RegistryClient) ImageExists(id string) (bool, error) {
+	for _, kind := range []string{"json", "layer"} {
+		resp, err := client.doStreamRequest("HEAD", fmt.Sprintf("/images/%s/%s", id, kind), nil, nil)
+		if err != nil {
+			if err == ErrNotFound {
+				return false, nil
+			}
+			return false, err
+		}
+		resp.Body.Close()
+	}
+
+	return true, nil
+}
+
+// imageJSON returns the raw image json and the layer checksum
+func (client *RegistryClient) imageJSON(id string) ([]byte, string, error) {
+	resp, err := client.doStreamRequest("GET", fmt.Sprintf("/images/%s/json", id), nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	checksum := resp.Header.Get(checksumHeader)
+	if checksum == "" {
+		// older registries use the legacy header name
+		checksum = resp.Header.Get("X-Docker-Payload-Checksum")
+	}
+
+	return data, checksum, nil
+}
+
+// copyImage uploads the json, layer and checksum of the image from src.
+// The registry only accepts the layer of an image after its json.
+func (client *RegistryClient) copyImage(src *RegistryClient, id string) error {
+	data, checksum, err := src.imageJSON(id)
+	if err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("/images/%s/json", id)
+	if _, err := client.doRequest("PUT", uri, data, nil); err != nil {
+		return err
+	}
+
+	layer, err := src.doStreamRequest("GET", fmt.Sprintf("/images/%s/layer", id), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer layer.Body.Close()
+
+	resp, err := client.doStreamRequest("PUT", fmt.Sprintf("/images/%s/layer", id), layer.Body, map[string]string{
+		"Content-Type": "application/octet-stream",
+	})
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if checksum != "" {
+		uri := fmt.Sprintf("/images/%s/checksum", id)
+		if _, err := client.doRequest("PUT", uri, nil, map[string]string{checksumHeader: checksum}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetTag points the tag of the repository at the image
+func (client *RegistryClient) SetTag(repo, tag, id string) error {
+	r := parseRepo(repo)
+	data, err := json.Marshal(id)
+	if err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("/repositories/%s/%s/tags/%s", r.Namespace, r.Repository, tag)
+	if _, err := client.doRequest("PUT", uri, data, nil); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Copy transfers srcRepo:srcTag from the src registry to dstRepo:dstTag in
+// this registry without using a Docker engine.  Images are uploaded parents
+// first and images the registry already has are skipped.
+func (client *RegistryClient) Copy(src *RegistryClient, srcRepo, srcTag, dstRepo, dstTag string) (*CopyResult, error) {
+	tags, err := src.Tags(srcRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	id := ""
+	for _, t := range tags {
+		if t.Name == srcTag {
+			id = t.ID
+			break
+		}
+	}
+
+	if id == "" {
+		return nil, fmt.Errorf("tag %s not found in %s", srcTag, srcRepo)
+	}
+
+	ancestry, err := src.Ancestry(id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CopyResult{
+		ImageID: id,
+		Copied:  []string{},
+		Skipped: []string{},
+	}
+
+	// the ancestry starts with the image itself; upload the base image first
+	for i := len(ancestry) - 1; i >= 0; i-- {
+		imageID := ancestry[i]
+
+		exists, err := client.ImageExists(imageID)
+		if err != nil {
+			return nil, err
+		}
+
+		if exists {
+			result.Skipped = append(result.Skipped, imageID)
+			continue
+		}
+
+		if err := client.copyImage(src, imageID); err != nil {
+			return nil, fmt.Errorf("error copying image %s: %s", imageID, err)
+		}
+
+		result.Copied = append(result.Copied, imageID)
+	}
+
+	if err := client.SetTag(dstRepo, dstTag, id); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,198 @@
+package v1
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryRegistry is a v1 registry accepting pushes
+type memoryRegistry struct {
+	sync.Mutex
+	server    *httptest.Server
+	images    map[string]string
+	layers    map[string]string
+	checksums map[string]string
+	ancestry  map[string]string
+	tags      map[string]string
+	uploads   []string
+}
+
+func newMemoryRegistry() *memoryRegistry {
+	mr := &memoryRegistry{
+		images:    map[string]string{},
+		layers:    map[string]string{},
+		checksums: map[string]string{},
+		ancestry:  map[string]string{},
+		tags:      map[string]string{},
+	}
+
+	mr.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mr.Lock()
+		defer mr.Unlock()
+
+		parts := strings.Split(r.URL.Path, "/")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/images/"):
+			id, kind := parts[3], parts[4]
+			switch r.Method {
+			case "GET", "HEAD":
+				var v string
+				var ok bool
+				switch kind {
+				case "json":
+					v, ok = mr.images[id]
+					w.Header().Set(checksumHeader, mr.checksums[id])
+				case "layer":
+					v, ok = mr.layers[id]
+				case "ancestry":
+					v, ok = mr.ancestry[id]
+				}
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				fmt.Fprint(w, v)
+			case "PUT":
+				data, _ := ioutil.ReadAll(r.Body)
+				switch kind {
+				case "json":
+					mr.images[id] = string(data)
+					mr.uploads = append(mr.uploads, id)
+				case "layer":
+					mr.layers[id] = string(data)
+				case "checksum":
+					mr.checksums[id] = r.Header.Get(checksumHeader)
+				}
+			}
+		case strings.HasPrefix(r.URL.Path, "/v1/repositories/"):
+			repo := parts[3] + "/" + parts[4]
+			if r.Method == "PUT" {
+				data, _ := ioutil.ReadAll(r.Body)
+				mr.tags[repo+":"+parts[6]] = strings.Trim(string(data), `"`)
+				return
+			}
+			tags := []string{}
+			for k, v := range mr.tags {
+				if strings.HasPrefix(k, repo+":") {
+					tags = append(tags, fmt.Sprintf("%q: %q", strings.TrimPrefix(k, repo+":"), v))
+				}
+			}
+			fmt.Fprintf(w, "{%s}", strings.Join(tags, ","))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	return mr
+}
+
+func (mr *memoryRegistry) addImage(id, parent string, ancestry string) {
+	mr.images[id] = fmt.Sprintf(`{"id": %q, "parent": %q}`, id, parent)
+	mr.layers[id] = "layer-" + id
+	mr.checksums[id] = "sha256:" + id
+	mr.ancestry[id] = ancestry
+}
+
+func TestCopy(t *testing.T) {
+	layers = newCache(defaultLayerCacheSize, defaultLayerCacheTTL)
+	ancestors = newCache(defaultLayerCacheSize, defaultLayerCacheTTL)
+
+	src := newMemoryRegistry()
+	defer src.server.Close()
+	src.addImage("base", "", `["base"]`)
+	src.addImage("app", "base", `["app", "base"]`)
+	src.tags["library/app:1.0"] = "app"
+
+	dst := newMemoryRegistry()
+	defer dst.server.Close()
+	dst.addImage("base", "", `["base"]`)
+
+	srcClient, err := NewRegistryClient(src.server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dstClient, err := NewRegistryClient(dst.server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := dstClient.Copy(srcClient, "app", "1.0", "prod/app", "stable")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.ImageID != "app" {
+		t.Fatalf("expected image app; received %s", res.ImageID)
+	}
+
+	if len(res.Copied) != 1 || res.Copied[0] != "app" {
+		t.Fatalf("expected only app to be copied; received %v", res.Copied)
+	}
+
+	if len(res.Skipped) != 1 || res.Skipped[0] != "base" {
+		t.Fatalf("expected base to be skipped; received %v", res.Skipped)
+	}
+
+	if len(dst.uploads) != 1 {
+		t.Fatalf("expected 1 upload; received %v", dst.uploads)
+	}
+
+	if dst.layers["app"] != "layer-app" {
+		t.Fatalf("expected layer to be copied; received %q", dst.layers["app"])
+	}
+
+	if dst.checksums["app"] != "sha256:app" {
+		t.Fatalf("expected checksum to be copied; received %q", dst.checksums["app"])
+	}
+
+	if dst.tags["prod/app:stable"] != "app" {
+		t.Fatalf("expected prod/app:stable to be tagged; received %q", dst.tags["prod/app:stable"])
+	}
+}
+
+func TestCopyUnknownTag(t *testing.T) {
+	src := newMemoryRegistry()
+	defer src.server.Close()
+	dst := newMemoryRegistry()
+	defer dst.server.Close()
+
+	srcClient, _ := NewRegistryClient(src.server.URL, nil)
+	dstClient, _ := NewRegistryClient(dst.server.URL, nil)
+
+	if _, err := dstClient.Copy(srcClient, "app", "missing", "app", "missing"); err == nil {
+		t.Fatal("expected error for unknown tag")
+	}
+}
+
+func TestCopyIncompleteImage(t *testing.T) {
+	layers = newCache(defaultLayerCacheSize, defaultLayerCacheTTL)
+	ancestors = newCache(defaultLayerCacheSize, defaultLayerCacheTTL)
+
+	src := newMemoryRegistry()
+	defer src.server.Close()
+	src.addImage("app", "", `["app"]`)
+	src.tags["library/app:1.0"] = "app"
+
+	// a previous copy failed after uploading the json
+	dst := newMemoryRegistry()
+	defer dst.server.Close()
+	dst.images["app"] = src.images["app"]
+
+	srcClient, _ := NewRegistryClient(src.server.URL, nil)
+	dstClient, _ := NewRegistryClient(dst.server.URL, nil)
+
+	res, err := dstClient.Copy(srcClient, "app", "1.0", "app", "1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Copied) != 1 || dst.layers["app"] != "layer-app" {
+		t.Fatalf("expected the image without layer to be copied again; copied %v layer %q", res.Copied, dst.layers["app"])
+	}
+}
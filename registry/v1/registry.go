@@ -6,24 +6,34 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
 	"path"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
 var (
-	ErrNotFound        = errors.New("Not found")
-	defaultHTTPTimeout = 30 * time.Second
+	ErrNotFound         = errors.New("Not found")
+	ErrDeadlineExceeded = errors.New("registry request deadline exceeded")
+	defaultHTTPTimeout  = 30 * time.Second
+	// maximum number of concurrent requests per client
+	defaultConcurrency = 8
 )
 
 type RegistryClient struct {
 	URL        *url.URL
 	tlsConfig  *tls.Config
 	httpClient *http.Client
+	username   string
+	password   string
+	requests   chan struct{}
+	deadline   time.Time
 }
 
 type Repo struct {
@@ -68,55 +78,112 @@ func NewRegistryClient(registryUrl string, tlsConfig *tls.Config) (*RegistryClie
 		URL:        u,
 		httpClient: httpClient,
 		tlsConfig:  tlsConfig,
+		requests:   make(chan struct{}, defaultConcurrency),
 	}, nil
 }
 
+// SetBasicAuth configures the credentials sent with every registry request
+func (client *RegistryClient) SetBasicAuth(username, password string) {
+	client.username = username
+	client.password = password
+}
+
+// SetDeadline fails requests which do not complete before the deadline.
+// Requests in progress are cancelled when it passes.
+func (client *RegistryClient) SetDeadline(deadline time.Time) {
+	client.deadline = deadline
+}
+
 func (client *RegistryClient) doRequest(method string, path string, body []byte, headers map[string]string) ([]byte, error) {
-	b := bytes.NewBuffer(body)
+	client.requests <- struct{}{}
+	defer func() { <-client.requests }()
+
+	resp, err := client.doStreamRequest(method, path, bytes.NewBuffer(body), headers)
+	if err != nil {
+		return nil, err
+	}
 
-	req, err := http.NewRequest(method, client.URL.String()+"/v1"+path, b)
+	defer resp.Body.Close()
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// doStreamRequest returns the response of a successful request.  The caller
+// must close the response body.
+func (client *RegistryClient) doStreamRequest(method string, path string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequest(method, client.URL.String()+"/v1"+path, body)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Add("Content-Type", "application/json")
+	if client.username != "" {
+		req.SetBasicAuth(client.username, client.password)
+	}
 	if headers != nil {
 		for header, value := range headers {
-			req.Header.Add(header, value)
+			req.Header.Set(header, value)
 		}
 	}
 
-	resp, err := client.httpClient.Do(req)
-	if err != nil {
-		if !strings.Contains(err.Error(), "connection refused") && client.tlsConfig == nil {
-			return nil, fmt.Errorf("%v. Are you trying to connect to a TLS-enabled daemon without TLS?", err)
+	httpClient := client.httpClient
+	if !client.deadline.IsZero() {
+		timeout := client.deadline.Sub(time.Now())
+		if timeout <= 0 {
+			return nil, ErrDeadlineExceeded
 		}
-		return nil, err
-	}
 
-	defer resp.Body.Close()
+		httpClient = &http.Client{
+			Transport: client.httpClient.Transport,
+			Timeout:   timeout,
+		}
+	}
 
-	data, err := ioutil.ReadAll(resp.Body)
+	resp, err := httpClient.Do(req)
 	if err != nil {
+		if !strings.Contains(err.Error(), "connection refused") && client.tlsConfig == nil {
+			return nil, fmt.Errorf("%v. Are you trying to connect to a TLS-enabled daemon without TLS?", err)
+		}
 		return nil, err
 	}
 
 	if resp.StatusCode == 404 {
+		resp.Body.Close()
 		return nil, ErrNotFound
 	}
 
 	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
 		return nil, Error{StatusCode: resp.StatusCode, Status: resp.Status, msg: string(data)}
 	}
 
-	return data, nil
+	return resp, nil
 }
 
+// Search returns the repositories matching the query including their tags
+// and layers
 func (client *RegistryClient) Search(query string, page int, numResults int) (*SearchResult, error) {
+	return client.search(query, page, numResults, true)
+}
+
+// List returns the repositories matching the query including their tags.
+// Layers are not expanded which makes it much cheaper than Search.
+func (client *RegistryClient) List(query string, page int, numResults int) (*SearchResult, error) {
+	return client.search(query, page, numResults, false)
+}
+
+func (client *RegistryClient) search(query string, page int, numResults int, expand bool) (*SearchResult, error) {
 	if numResults < 1 {
 		numResults = 100
 	}
-	uri := fmt.Sprintf("/search?q=%s&n=%d&page=%d", query, numResults, page)
+	if page < 1 {
+		page = 1
+	}
+	uri := fmt.Sprintf("/search?q=%s&n=%d&page=%d", url.QueryEscape(query), numResults, page)
 	data, err := client.doRequest("GET", uri, nil, nil)
 	if err != nil {
 		return nil, err
@@ -128,14 +195,18 @@ func (client *RegistryClient) Search(query string, page int, numResults int) (*S
 	}
 
 	// convert the simple results to rich Repository results
-	repos := []*Repository{}
-	for _, repo := range res.Results {
-		r, err := client.Repository(repo.Name)
+	repos := make([]*Repository, len(res.Results))
+	if err := ForEach(len(res.Results), func(i int) error {
+		r, err := client.repository(res.Results[i].Name, expand)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		repos = append(repos, r)
+		r.Description = res.Results[i].Description
+		repos[i] = r
+		return nil
+	}); err != nil {
+		return nil, err
 	}
 
 	res.Results = repos
@@ -163,7 +234,21 @@ func (client *RegistryClient) DeleteTag(repo string, tag string) error {
 	return nil
 }
 
+// cacheKey returns the key of the image id in the layer caches.  Cached
+// entries are only shared by clients of the same registry and user so that
+// images are not returned to users without access to them.
+func (client *RegistryClient) cacheKey(id string) string {
+	return client.URL.String() + "|" + client.username + "|" + id
+}
+
+// Layer returns the metadata for the image id
 func (client *RegistryClient) Layer(id string) (*Layer, error) {
+	key := client.cacheKey(id)
+	if l, ok := layers.Get(key); ok {
+		layer := l.(Layer)
+		return &layer, nil
+	}
+
 	uri := fmt.Sprintf("/images/%s/json", id)
 	data, err := client.doRequest("GET", uri, nil, nil)
 	if err != nil {
@@ -175,10 +260,36 @@ func (client *RegistryClient) Layer(id string) (*Layer, error) {
 		return nil, err
 	}
 
+	layers.Add(key, *layer)
+
 	return layer, nil
 }
 
-func (client *RegistryClient) Repository(name string) (*Repository, error) {
+// Ancestry returns the ids of the image and all of its parents
+func (client *RegistryClient) Ancestry(id string) ([]string, error) {
+	key := client.cacheKey(id)
+	if a, ok := ancestors.Get(key); ok {
+		return append([]string{}, a.([]string)...), nil
+	}
+
+	uri := fmt.Sprintf("/images/%s/ancestry", id)
+	data, err := client.doRequest("GET", uri, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ancestry := []string{}
+	if err := json.Unmarshal(data, &ancestry); err != nil {
+		return nil, err
+	}
+
+	ancestors.Add(key, append([]string{}, ancestry...))
+
+	return ancestry, nil
+}
+
+// Tags returns the tags of the repository sorted by name
+func (client *RegistryClient) Tags(name string) ([]Tag, error) {
 	r := parseRepo(name)
 	uri := fmt.Sprintf("/repositories/%s/%s/tags", r.Namespace, r.Repository)
 	repoTags := map[string]string{}
@@ -192,74 +303,168 @@ func (client *RegistryClient) Repository(name string) (*Repository, error) {
 		return nil, err
 	}
 
-	layers := []Layer{}
 	tags := []Tag{}
-	size := int64(0)
-
 	for n, id := range repoTags {
-		uri := fmt.Sprintf("/images/%s/json", id)
-		layer := &Layer{}
+		tags = append(tags, Tag{
+			ID:   id,
+			Name: n,
+		})
+	}
+
+	sort.Sort(tagsByName(tags))
+
+	return tags, nil
+}
+
+// ListTags returns the tags of the repository including the creation time
+// of the tagged images
+func (client *RegistryClient) ListTags(name string) ([]Tag, error) {
+	tags, err := client.Tags(name)
+	if err != nil {
+		return nil, err
+	}
 
-		data, err := client.doRequest("GET", uri, nil, nil)
+	if err := ForEach(len(tags), func(i int) error {
+		layer, err := client.Layer(tags[i].ID)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		if err := json.Unmarshal(data, &layer); err != nil {
-			return nil, err
-		}
+		tags[i].Created = layer.Created
+		return nil
+	}); err != nil {
+		return nil, err
+	}
 
-		uri = fmt.Sprintf("/images/%s/ancestry", id)
+	return tags, nil
+}
+
+// Repository returns the repository including its tags and layers
+func (client *RegistryClient) Repository(name string) (*Repository, error) {
+	return client.repository(name, true)
+}
 
-		ancestry := []string{}
+func (client *RegistryClient) repository(name string, expand bool) (*Repository, error) {
+	r := parseRepo(name)
 
-		data, err = client.doRequest("GET", uri, nil, nil)
+	tags, err := client.Tags(name)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &Repository{
+		Name:       path.Join(r.Namespace, r.Repository),
+		Namespace:  r.Namespace,
+		Repository: r.Repository,
+		Tags:       tags,
+	}
+
+	if !expand {
+		return repo, nil
+	}
+
+	tagLayers := make([]*Layer, len(tags))
+	if err := ForEach(len(tags), func(i int) error {
+		layer, err := client.Layer(tags[i].ID)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		if err = json.Unmarshal(data, &ancestry); err != nil {
-			return nil, err
+		ancestry, err := client.Ancestry(tags[i].ID)
+		if err != nil {
+			return err
 		}
 
-		tag := &Tag{
-			ID:   id,
-			Name: n,
+		layer.Ancestry = ancestry
+		tagLayers[i] = layer
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	// tags usually share most of their ancestors so only fetch each once
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, l := range tagLayers {
+		for _, id := range l.Ancestry {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
 		}
+	}
 
-		tags = append(tags, *tag)
-		layer.Ancestry = ancestry
+	ancestorLayers := make([]*Layer, len(ids))
+	if err := ForEach(len(ids), func(i int) error {
+		l, err := client.Layer(ids[i])
+		if err != nil {
+			return err
+		}
 
-		layers = append(layers, *layer)
+		ancestorLayers[i] = l
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	layerByID := map[string]*Layer{}
+	for _, l := range ancestorLayers {
+		layerByID[l.ID] = l
+	}
+
+	repoLayers := []Layer{}
+	for _, layer := range tagLayers {
+		repoLayers = append(repoLayers, *layer)
 
 		// parse ancestor layers
-		for _, i := range ancestry {
-			uri = fmt.Sprintf("/images/%s/json", i)
-			l := &Layer{}
+		for _, id := range layer.Ancestry {
+			repoLayers = append(repoLayers, *layerByID[id])
+		}
+	}
 
-			data, err = client.doRequest("GET", uri, nil, nil)
-			if err != nil {
-				return nil, err
-			}
+	// layers shared by several tags are only stored once
+	size := int64(0)
+	for _, l := range ancestorLayers {
+		size += l.Size
+	}
+
+	repo.Layers = repoLayers
+	repo.Size = size
+
+	return repo, nil
+}
+
+// ForEach calls fn for every index below n and returns the first error.
+// At most defaultConcurrency calls run at the same time.
+func ForEach(n int, fn func(i int) error) error {
+	workers := defaultConcurrency
+	if n < workers {
+		workers = n
+	}
 
-			if err = json.Unmarshal(data, &l); err != nil {
-				return nil, err
+	var wg sync.WaitGroup
+	indexes := make(chan int)
+	errs := make(chan error, n)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := fn(i); err != nil {
+					errs <- err
+				}
 			}
-			size += l.Size
-			layers = append(layers, *l)
-		}
+		}()
 	}
-	var repoSize int64
-	if int64(len(tags)) > 0 {
-		repoSize = int64(size) / int64(len(tags))
+
+	for i := 0; i < n; i++ {
+		indexes <- i
 	}
+	close(indexes)
 
-	return &Repository{
-		Name:       path.Join(r.Namespace, r.Repository),
-		Namespace:  r.Namespace,
-		Repository: r.Repository,
-		Tags:       tags,
-		Layers:     layers,
-		Size:       repoSize,
-	}, nil
+	wg.Wait()
+	close(errs)
+
+	return <-errs
 }
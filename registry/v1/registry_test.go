@@ -0,0 +1,269 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testRegistry struct {
+	server        *httptest.Server
+	layerRequests int64
+	query         string
+}
+
+// newTestRegistry returns a v1 registry serving the repository "library/app"
+// with the tags "1.0" and "latest" which share the base layer
+func newTestRegistry(t *testing.T) *testRegistry {
+	tr := &testRegistry{}
+	ancestry := map[string][]string{
+		"aaa": {"aaa", "base"},
+		"bbb": {"bbb", "base"},
+	}
+	sizes := map[string]int64{
+		"aaa":  10,
+		"bbb":  20,
+		"base": 100,
+	}
+
+	tr.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		switch {
+		case path == "/v1/search":
+			tr.query = r.URL.RawQuery
+			fmt.Fprint(w, `{"num_results": 1, "num_pages": 1, "page": 1, "results": [{"name": "library/app", "description": "test app"}]}`)
+		case path == "/v1/repositories/library/app/tags":
+			fmt.Fprint(w, `{"latest": "bbb", "1.0": "aaa"}`)
+		case strings.HasSuffix(path, "/ancestry"):
+			id := strings.Split(path, "/")[3]
+			json.NewEncoder(w).Encode(ancestry[id])
+		case strings.HasSuffix(path, "/json"):
+			atomic.AddInt64(&tr.layerRequests, 1)
+			id := strings.Split(path, "/")[3]
+			json.NewEncoder(w).Encode(Layer{ID: id, Size: sizes[id]})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	return tr
+}
+
+func TestRepository(t *testing.T) {
+	tr := newTestRegistry(t)
+	defer tr.server.Close()
+
+	layers = newCache(defaultLayerCacheSize, defaultLayerCacheTTL)
+	ancestors = newCache(defaultLayerCacheSize, defaultLayerCacheTTL)
+
+	client, err := NewRegistryClient(tr.server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := client.Repository("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(repo.Tags) != 2 {
+		t.Fatalf("expected 2 tags; received %d", len(repo.Tags))
+	}
+
+	if repo.Tags[0].Name != "1.0" {
+		t.Fatalf("expected tags sorted by name; received %q first", repo.Tags[0].Name)
+	}
+
+	// two tag layers each followed by their two ancestors
+	if len(repo.Layers) != 6 {
+		t.Fatalf("expected 6 layers; received %d", len(repo.Layers))
+	}
+
+	// base is shared by both tags and only counted once
+	if repo.Size != 130 {
+		t.Fatalf("expected size 130; received %d", repo.Size)
+	}
+
+	// 3 unique images
+	if n := atomic.LoadInt64(&tr.layerRequests); n != 3 {
+		t.Fatalf("expected 3 layer requests; received %d", n)
+	}
+
+	if _, err := client.Repository("app"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := atomic.LoadInt64(&tr.layerRequests); n != 3 {
+		t.Fatalf("expected cached layers to be used; received %d layer requests", n)
+	}
+}
+
+func TestLayerCacheKeyedByUser(t *testing.T) {
+	tr := newTestRegistry(t)
+	defer tr.server.Close()
+
+	layers = newCache(defaultLayerCacheSize, defaultLayerCacheTTL)
+	ancestors = newCache(defaultLayerCacheSize, defaultLayerCacheTTL)
+
+	for _, username := range []string{"alice", "bob", "alice"} {
+		client, err := NewRegistryClient(tr.server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.SetBasicAuth(username, "secret")
+
+		if _, err := client.Layer("base"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := atomic.LoadInt64(&tr.layerRequests); n != 2 {
+		t.Fatalf("expected one layer request per user; received %d", n)
+	}
+}
+
+func TestForEachBounded(t *testing.T) {
+	var running, max int64
+	err := ForEach(100, func(i int) error {
+		n := atomic.AddInt64(&running, 1)
+		defer atomic.AddInt64(&running, -1)
+
+		for {
+			m := atomic.LoadInt64(&max)
+			if n <= m || atomic.CompareAndSwapInt64(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+
+		if i == 50 {
+			return fmt.Errorf("failed %d", i)
+		}
+		return nil
+	})
+
+	if err == nil || err.Error() != "failed 50" {
+		t.Fatalf("expected error of the failed call; received %v", err)
+	}
+
+	if max > int64(defaultConcurrency) {
+		t.Fatalf("expected at most %d concurrent calls; received %d", defaultConcurrency, max)
+	}
+}
+
+func TestListDoesNotExpandLayers(t *testing.T) {
+	tr := newTestRegistry(t)
+	defer tr.server.Close()
+
+	client, err := NewRegistryClient(tr.server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.List("app", 2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tr.query != "q=app&n=10&page=2" {
+		t.Fatalf("expected paginated search query; received %q", tr.query)
+	}
+
+	if len(res.Results) != 1 {
+		t.Fatalf("expected 1 repository; received %d", len(res.Results))
+	}
+
+	repo := res.Results[0]
+	if repo.Description != "test app" {
+		t.Fatalf("expected description from search; received %q", repo.Description)
+	}
+
+	if len(repo.Tags) != 2 {
+		t.Fatalf("expected 2 tags; received %d", len(repo.Tags))
+	}
+
+	if len(repo.Layers) != 0 {
+		t.Fatalf("expected no layers; received %d", len(repo.Layers))
+	}
+
+	if n := atomic.LoadInt64(&tr.layerRequests); n != 0 {
+		t.Fatalf("expected no layer requests; received %d", n)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	c := newCache(2, time.Minute)
+	c.Add("a", 1)
+	c.Add("b", 2)
+
+	// touch a so b is the least recently used
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a in cache")
+	}
+
+	c.Add("c", 3)
+
+	if c.Len() != 2 {
+		t.Fatalf("expected 2 entries; received %d", c.Len())
+	}
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected b to be evicted")
+	}
+
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a in cache")
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	c := newCache(2, 10*time.Millisecond)
+	c.Add("a", 1)
+
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a in cache")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expected a to expire")
+	}
+
+	if c.Len() != 0 {
+		t.Fatalf("expected expired entry to be removed; received %d entries", c.Len())
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	client, err := NewRegistryClient(ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetDeadline(time.Now().Add(10 * time.Millisecond))
+
+	start := time.Now()
+	if _, err := client.Tags("app"); err == nil {
+		t.Fatal("expected error after the deadline")
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the request to be cancelled at the deadline; took %s", elapsed)
+	}
+
+	if _, err := client.Tags("app"); err != ErrDeadlineExceeded {
+		t.Fatalf("expected ErrDeadlineExceeded; received %v", err)
+	}
+}
@@ -8,8 +8,9 @@ import (
 
 type (
 	Tag struct {
-		ID   string
-		Name string
+		ID      string
+		Name    string
+		Created *time.Time `json:",omitempty"`
 	}
 
 	ContainerConfig struct {
@@ -41,3 +42,9 @@ type (
 		Size        int64   `json:"size,omitempty"`
 	}
 )
+
+type tagsByName []Tag
+
+func (t tagsByName) Len() int           { return len(t) }
+func (t tagsByName) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t tagsByName) Less(i, j int) bool { return t[i].Name < t[j].Name }
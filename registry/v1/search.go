@@ -3,6 +3,9 @@ package v1
 type (
 	SearchResult struct {
 		NumberOfResults int           `json:"num_results,omitempty"`
+		NumberOfPages   int           `json:"num_pages,omitempty"`
+		Page            int           `json:"page,omitempty"`
+		PageSize        int           `json:"page_size,omitempty"`
 		Query           string        `json:"string,omitempty"`
 		Results         []*Repository `json:"results,omitempty"`
 	}
@@ -0,0 +1,48 @@
+package shipyard
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRegistryPasswordNotEncoded(t *testing.T) {
+	data, err := json.Marshal(&Registry{Name: "local", Username: "user", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(data), "secret") {
+		t.Fatalf("expected the password to be omitted; received %s", data)
+	}
+
+	reg := &Registry{}
+	if err := json.Unmarshal([]byte(`{"name": "local", "password": "secret"}`), reg); err != nil {
+		t.Fatal(err)
+	}
+
+	if reg.Password != "secret" {
+		t.Fatalf("expected the password to be read; received %q", reg.Password)
+	}
+}
+
+func TestRegistryCanUseCredentials(t *testing.T) {
+	open := &Registry{Name: "open"}
+	restricted := &Registry{Name: "restricted", Roles: []string{"registries:ro"}}
+
+	if !open.CanUseCredentials(nil) {
+		t.Fatal("expected registry without roles to allow requests without roles")
+	}
+
+	if restricted.CanUseCredentials(nil) {
+		t.Fatal("expected restricted registry to deny requests without roles")
+	}
+
+	if !restricted.CanUseCredentials([]string{"registries:ro"}) || !restricted.CanUseCredentials([]string{"admin"}) {
+		t.Fatal("expected restricted registry to allow matching roles and admins")
+	}
+
+	if restricted.CanUseCredentials([]string{"containers:rw"}) {
+		t.Fatal("expected restricted registry to deny other roles")
+	}
+}
@@ -0,0 +1,45 @@
+package shipyard
+
+import (
+	"time"
+)
+
+type (
+	// RetentionPolicy removes old tags from the repositories of a registry.
+	// The newest KeepLast tags are always kept.  Of the remaining tags
+	// those older than MaxAge are removed or all of them if MaxAge is
+	// empty.  Tags matching KeepPattern and tags used by running
+	// containers are never removed.
+	RetentionPolicy struct {
+		ID          string    `json:"id,omitempty" gorethink:"id,omitempty"`
+		Name        string    `json:"name,omitempty" gorethink:"name,omitempty"`
+		Registry    string    `json:"registry,omitempty" gorethink:"registry,omitempty"`
+		Repository  string    `json:"repository,omitempty" gorethink:"repository,omitempty"`
+		KeepLast    int       `json:"keep_last,omitempty" gorethink:"keep_last,omitempty"`
+		MaxAge      string    `json:"max_age,omitempty" gorethink:"max_age,omitempty"`
+		KeepPattern string    `json:"keep_pattern,omitempty" gorethink:"keep_pattern,omitempty"`
+		Schedule    string    `json:"schedule,omitempty" gorethink:"schedule,omitempty"`
+		LastRun     time.Time `json:"last_run,omitempty" gorethink:"last_run,omitempty"`
+	}
+
+	RetentionTag struct {
+		Repository string     `json:"repository,omitempty"`
+		Tag        string     `json:"tag,omitempty"`
+		ImageID    string     `json:"image_id,omitempty"`
+		Created    *time.Time `json:"created,omitempty"`
+		Reason     string     `json:"reason,omitempty"`
+	}
+
+	// RetentionReport lists the tags deleted by a retention policy.
+	// ReclaimedSize only counts layers no remaining tag uses.
+	RetentionReport struct {
+		PolicyID      string          `json:"policy_id,omitempty"`
+		Registry      string          `json:"registry,omitempty"`
+		DryRun        bool            `json:"dry_run"`
+		Time          time.Time       `json:"time,omitempty"`
+		Deleted       []*RetentionTag `json:"deleted"`
+		Kept          []*RetentionTag `json:"kept"`
+		Errors        []string        `json:"errors"`
+		ReclaimedSize int64           `json:"reclaimed_size"`
+	}
+)
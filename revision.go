@@ -0,0 +1,61 @@
+package shipyard
+
+import (
+	"time"
+
+	"github.com/samalba/dockerclient"
+)
+
+const (
+	// RevisionApplication is the kind of revisions of applications
+	RevisionApplication = "application"
+	// RevisionGroup is the kind of revisions of container groups
+	RevisionGroup = "group"
+)
+
+type (
+	// Revision records the containers of an application or group after a
+	// change made through the controller.  Revisions are numbered per
+	// target starting at 1.
+	Revision struct {
+		ID         string               `json:"id,omitempty" gorethink:"id,omitempty"`
+		Kind       string               `json:"kind,omitempty" gorethink:"kind,omitempty"`
+		Target     string               `json:"target,omitempty" gorethink:"target,omitempty"`
+		Number     int                  `json:"number" gorethink:"number"`
+		Action     string               `json:"action,omitempty" gorethink:"action,omitempty"`
+		Definition string               `json:"definition,omitempty" gorethink:"definition,omitempty"`
+		Containers []*RevisionContainer `json:"containers" gorethink:"containers"`
+		Username   string               `json:"username,omitempty" gorethink:"username,omitempty"`
+		Time       time.Time            `json:"time,omitempty" gorethink:"time,omitempty"`
+	}
+
+	// RevisionContainer is the configuration of a container in a revision.
+	// ImageDigest is the id of the image the container was created from.
+	RevisionContainer struct {
+		Name        string                        `json:"name,omitempty" gorethink:"name,omitempty"`
+		Service     string                        `json:"service,omitempty" gorethink:"service,omitempty"`
+		Node        string                        `json:"node,omitempty" gorethink:"node,omitempty"`
+		Image       string                        `json:"image,omitempty" gorethink:"image,omitempty"`
+		ImageDigest string                        `json:"image_digest,omitempty" gorethink:"image_digest,omitempty"`
+		Config      *dockerclient.ContainerConfig `json:"config,omitempty" gorethink:"config,omitempty"`
+		HostConfig  *dockerclient.HostConfig      `json:"host_config,omitempty" gorethink:"host_config,omitempty"`
+	}
+
+	// RevisionChange is a difference between two revisions.  Changes of
+	// the definition have no container.
+	RevisionChange struct {
+		Container string `json:"container,omitempty"`
+		Field     string `json:"field,omitempty"`
+		From      string `json:"from"`
+		To        string `json:"to"`
+	}
+
+	// RevisionDiff lists the changes from one revision to another
+	RevisionDiff struct {
+		Kind    string            `json:"kind,omitempty"`
+		Target  string            `json:"target,omitempty"`
+		From    int               `json:"from"`
+		To      int               `json:"to"`
+		Changes []*RevisionChange `json:"changes"`
+	}
+)
@@ -0,0 +1,50 @@
+package shipyard
+
+import (
+	"time"
+)
+
+type (
+	// TagStorage is the size of a tagged image.  Exclusive bytes are used
+	// by no other tag in the registry and would be reclaimed by deleting
+	// the tag.
+	TagStorage struct {
+		Tag           string `json:"tag,omitempty"`
+		ImageID       string `json:"image_id,omitempty"`
+		Size          int64  `json:"size"`
+		SharedSize    int64  `json:"shared_size"`
+		ExclusiveSize int64  `json:"exclusive_size"`
+	}
+
+	// RepositoryStorage is the size of the unique layers of a repository.
+	// Exclusive bytes are used by no other repository in the registry.
+	RepositoryStorage struct {
+		Repository    string        `json:"repository,omitempty"`
+		Size          int64         `json:"size"`
+		SharedSize    int64         `json:"shared_size"`
+		ExclusiveSize int64         `json:"exclusive_size"`
+		Tags          []*TagStorage `json:"tags"`
+	}
+
+	// StorageReport is the storage used by a registry counting every layer
+	// once.  Growth is the change since the previous snapshot.
+	StorageReport struct {
+		Registry     string               `json:"registry,omitempty"`
+		Time         time.Time            `json:"time,omitempty"`
+		Size         int64                `json:"size"`
+		Layers       int                  `json:"layers"`
+		Growth       int64                `json:"growth"`
+		Repositories []*RepositoryStorage `json:"repositories"`
+		Errors       []string             `json:"errors"`
+	}
+
+	// StorageSnapshot records the storage used by a registry over time
+	StorageSnapshot struct {
+		ID           string           `json:"id,omitempty" gorethink:"id,omitempty"`
+		Registry     string           `json:"registry,omitempty" gorethink:"registry,omitempty"`
+		Time         time.Time        `json:"time,omitempty" gorethink:"time,omitempty"`
+		Size         int64            `json:"size" gorethink:"size"`
+		Layers       int              `json:"layers" gorethink:"layers"`
+		Repositories map[string]int64 `json:"repositories,omitempty" gorethink:"repositories,omitempty"`
+	}
+)
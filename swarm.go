@@ -0,0 +1,131 @@
+package shipyard
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SwarmAPIVersion is the engine api version used for swarm mode requests
+const SwarmAPIVersion = "v1.25"
+
+// The swarm mode types keep the field names of the engine api so that
+// they can be passed to and from the engine unchanged.  Parts of the specs
+// Shipyard does not look into are kept as raw json.
+type (
+	SwarmVersion struct {
+		Index uint64
+	}
+
+	SwarmReplicatedService struct {
+		Replicas *uint64 `json:",omitempty"`
+	}
+
+	SwarmServiceMode struct {
+		Replicated *SwarmReplicatedService `json:",omitempty"`
+		Global     *struct{}               `json:",omitempty"`
+	}
+
+	SwarmServiceSpec struct {
+		Name         string
+		Labels       map[string]string `json:",omitempty"`
+		TaskTemplate json.RawMessage   `json:",omitempty"`
+		Mode         SwarmServiceMode
+		UpdateConfig json.RawMessage `json:",omitempty"`
+		Networks     json.RawMessage `json:",omitempty"`
+		EndpointSpec json.RawMessage `json:",omitempty"`
+	}
+
+	SwarmService struct {
+		ID        string
+		Version   SwarmVersion
+		CreatedAt time.Time
+		UpdatedAt time.Time
+		Spec      SwarmServiceSpec
+		Endpoint  json.RawMessage `json:",omitempty"`
+	}
+
+	SwarmTaskStatus struct {
+		Timestamp       time.Time
+		State           string
+		Message         string
+		Err             string          `json:",omitempty"`
+		ContainerStatus json.RawMessage `json:",omitempty"`
+	}
+
+	SwarmTask struct {
+		ID           string
+		Version      SwarmVersion
+		CreatedAt    time.Time
+		UpdatedAt    time.Time
+		Name         string            `json:",omitempty"`
+		Labels       map[string]string `json:",omitempty"`
+		Spec         json.RawMessage   `json:",omitempty"`
+		ServiceID    string
+		Slot         int    `json:",omitempty"`
+		NodeID       string `json:",omitempty"`
+		Status       SwarmTaskStatus
+		DesiredState string
+	}
+
+	SwarmNodeSpec struct {
+		Name         string            `json:",omitempty"`
+		Labels       map[string]string `json:",omitempty"`
+		Role         string
+		Availability string
+	}
+
+	SwarmNodeDescription struct {
+		Hostname string
+		Platform struct {
+			Architecture string
+			OS           string
+		}
+		Resources struct {
+			NanoCPUs    int64
+			MemoryBytes int64
+		}
+		Engine struct {
+			EngineVersion string
+			Labels        map[string]string `json:",omitempty"`
+		}
+	}
+
+	SwarmNodeStatus struct {
+		State   string
+		Message string `json:",omitempty"`
+		Addr    string `json:",omitempty"`
+	}
+
+	SwarmManagerStatus struct {
+		Leader       bool `json:",omitempty"`
+		Reachability string
+		Addr         string
+	}
+
+	SwarmNode struct {
+		ID            string
+		Version       SwarmVersion
+		CreatedAt     time.Time
+		UpdatedAt     time.Time
+		Spec          SwarmNodeSpec
+		Description   SwarmNodeDescription
+		Status        SwarmNodeStatus
+		ManagerStatus *SwarmManagerStatus `json:",omitempty"`
+	}
+
+	// SwarmSecretSpec is the definition of a secret.  Data is only sent
+	// when creating the secret; the engine never returns it.
+	SwarmSecretSpec struct {
+		Name   string
+		Labels map[string]string `json:",omitempty"`
+		Data   []byte            `json:",omitempty"`
+	}
+
+	SwarmSecret struct {
+		ID        string
+		Version   SwarmVersion
+		CreatedAt time.Time
+		UpdatedAt time.Time
+		Spec      SwarmSecretSpec
+	}
+)
@@ -0,0 +1,62 @@
+package shipyard
+
+import (
+	"time"
+)
+
+const (
+	// TemplateLabel is set on containers deployed from a template to the
+	// template name
+	TemplateLabel = "com.shipyard.template"
+	// TemplateVersionLabel is set to the template version
+	TemplateVersionLabel = "com.shipyard.template.version"
+
+	TemplateVariableString = "string"
+	TemplateVariableInt    = "int"
+	TemplateVariableBool   = "bool"
+	TemplateVariableList   = "list"
+	TemplateVariableMap    = "map"
+)
+
+type (
+	// TemplateVariable is an input of a template.  Its value is used as
+	// {{.Name}} in the template.
+	TemplateVariable struct {
+		Name        string      `json:"name,omitempty" gorethink:"name,omitempty"`
+		Type        string      `json:"type,omitempty" gorethink:"type,omitempty"`
+		Description string      `json:"description,omitempty" gorethink:"description,omitempty"`
+		Required    bool        `json:"required" gorethink:"required"`
+		Default     interface{} `json:"default,omitempty" gorethink:"default,omitempty"`
+	}
+
+	// Template is a blueprint of a container config and host config.
+	// String values may use the variables with text/template syntax.  A
+	// value consisting of a single variable is replaced by the typed value
+	// and list or map variables are expanded into string lists (i.e. Env).
+	// Saving a template creates a new version.  Teams lists the account
+	// roles the template is shared with.  The health probe is added to the
+	// labels of the rendered config.
+	Template struct {
+		ID          string                 `json:"id,omitempty" gorethink:"id,omitempty"`
+		Name        string                 `json:"name,omitempty" gorethink:"name,omitempty"`
+		Version     int                    `json:"version" gorethink:"version"`
+		Description string                 `json:"description,omitempty" gorethink:"description,omitempty"`
+		Variables   []*TemplateVariable    `json:"variables" gorethink:"variables"`
+		Config      map[string]interface{} `json:"config,omitempty" gorethink:"config,omitempty"`
+		HostConfig  map[string]interface{} `json:"host_config,omitempty" gorethink:"host_config,omitempty"`
+		HealthProbe *HealthProbe           `json:"health_probe,omitempty" gorethink:"health_probe,omitempty"`
+		Owner       string                 `json:"owner,omitempty" gorethink:"owner,omitempty"`
+		Teams       []string               `json:"teams" gorethink:"teams"`
+		Created     time.Time              `json:"created,omitempty" gorethink:"created,omitempty"`
+	}
+
+	// TemplateDeployment deploys Count containers from a template version.
+	// A zero version selects the latest version.  Containers are named
+	// after Name if it is set.
+	TemplateDeployment struct {
+		Version   int                    `json:"version,omitempty"`
+		Name      string                 `json:"name,omitempty"`
+		Count     int                    `json:"count,omitempty"`
+		Variables map[string]interface{} `json:"variables,omitempty"`
+	}
+)
@@ -0,0 +1,46 @@
+package shipyard
+
+import (
+	"time"
+)
+
+type (
+	// RollingUpdate replaces the containers of an application or group in
+	// batches.  An empty image pulls and recreates the current image.
+	// Delay and Timeout are in seconds.  The update is rolled back once
+	// FailureThreshold containers failed to come up.
+	RollingUpdate struct {
+		Image            string       `json:"image,omitempty"`
+		Service          string       `json:"service,omitempty"`
+		BatchSize        int          `json:"batch_size,omitempty"`
+		Delay            int          `json:"delay,omitempty"`
+		Timeout          int          `json:"timeout,omitempty"`
+		FailureThreshold int          `json:"failure_threshold,omitempty"`
+		HealthProbe      *HealthProbe `json:"health_probe,omitempty"`
+	}
+
+	// UpdateProgress reports a step of a rolling update.  The final step
+	// includes the result.
+	UpdateProgress struct {
+		Target    string        `json:"target,omitempty"`
+		Stage     string        `json:"stage,omitempty"`
+		Container string        `json:"container,omitempty"`
+		Message   string        `json:"message,omitempty"`
+		Replaced  int           `json:"replaced"`
+		Failures  int           `json:"failures"`
+		Total     int           `json:"total"`
+		Time      time.Time     `json:"time,omitempty"`
+		Result    *UpdateResult `json:"result,omitempty"`
+	}
+
+	// UpdateResult is the outcome of a rolling update.  Replaced lists the
+	// new containers and Failed the containers which could not be replaced.
+	UpdateResult struct {
+		Target     string   `json:"target,omitempty"`
+		Image      string   `json:"image,omitempty"`
+		Replaced   []string `json:"replaced"`
+		Failed     []string `json:"failed"`
+		RolledBack bool     `json:"rolled_back"`
+		Errors     []string `json:"errors"`
+	}
+)
@@ -0,0 +1,29 @@
+package shipyard
+
+type (
+	// Volume is a volume of the cluster and the names of the containers
+	// using it.  Node is only set for volumes listed by swarm.  Dangling
+	// volumes are not used by any container.  Size is the disk usage in
+	// bytes or -1 if it was not requested or the driver does not report
+	// it.
+	Volume struct {
+		Name       string            `json:"name,omitempty"`
+		Node       string            `json:"node,omitempty"`
+		Driver     string            `json:"driver,omitempty"`
+		Mountpoint string            `json:"mountpoint,omitempty"`
+		Labels     map[string]string `json:"labels,omitempty"`
+		Containers []string          `json:"containers"`
+		Dangling   bool              `json:"dangling"`
+		Size       int64             `json:"size"`
+	}
+
+	// VolumeCleanup lists the dangling volumes removed by a cleanup.  An
+	// unconfirmed cleanup only lists the volumes it would remove.  Size is
+	// the reclaimed disk space of the volumes with a known size.
+	VolumeCleanup struct {
+		Confirmed bool     `json:"confirmed"`
+		Volumes   []string `json:"volumes"`
+		Size      int64    `json:"size"`
+		Errors    []string `json:"errors"`
+	}
+)